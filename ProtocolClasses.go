@@ -2,6 +2,8 @@
 // RegisterDataClass
 package protocol
 
+//go:generate go run ./cmd/goprotocol-gen -pkg= ProtocolClasses.go
+
 const (
 	classID_SvrBase = 1000000
 	SM_BASE         = 3000000
@@ -14,6 +16,8 @@ const (
 )
 
 // 地图本身
+//
+//goprotocol:generate
 type TMapInfo struct {
 	Idx          int32
 	Name         string
@@ -32,6 +36,8 @@ type TMapInfo struct {
 }
 
 // 地图上的插槽
+//
+//goprotocol:generate
 type TSlotData struct {
 	Idx         int32
 	SlotType    uint16
@@ -41,8 +47,8 @@ type TSlotData struct {
 }
 
 func RegisterProtocolClasses() {
-	RegisterDataClass(ClassID_MapInfo, (*TMapInfo)(nil))
-	RegisterDataClass(ClassID_SlotData, (*TSlotData)(nil))
+	MustRegisterDataClass(ClassID_MapInfo, (*TMapInfo)(nil))
+	MustRegisterDataClass(ClassID_SlotData, (*TSlotData)(nil))
 }
 
 func init() {
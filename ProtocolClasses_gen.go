@@ -0,0 +1,123 @@
+// Code generated by goprotocol-gen. DO NOT EDIT.
+
+package protocol
+
+// MarshalProtocol 实现 IProtocolMarshaler
+func (m *TMapInfo) MarshalProtocol(w *ProtocolWritter) error {
+	if m == nil {
+		w.WriteEmptyHeader()
+		return nil
+	}
+	head, err := w.BeginStruct(m)
+	if err != nil {
+		return err
+	}
+	w.WriteInt32(m.Idx)
+	if err := w.WriteString(m.Name); err != nil {
+		return err
+	}
+	w.WriteUint16(m.RefreshPoint)
+	w.WriteArrayLen(len(m.SlotList))
+	for i0 := range m.SlotList {
+		if err := m.SlotList[i0].MarshalProtocol(w); err != nil {
+			return err
+		}
+	}
+	w.WriteInt32(m.MaxCount)
+	w.WriteInt32(m.MaxLineUpCount)
+	w.WriteInt32(m.MaxClerk)
+	w.WriteInt32(m.MaxCook)
+	w.WriteInt32(m.CookExp)
+	w.WriteInt32(m.OrderExp)
+	w.WriteInt32(m.DeliveryExp)
+	w.WriteInt32(m.PointRefreshTime)
+	return w.EndStruct(&head)
+}
+
+// UnmarshalProtocol 实现 IProtocolUnmarshaler
+func (m *TMapInfo) UnmarshalProtocol(r *ProtocolReader) error {
+	*m = TMapInfo{}
+	head := r.BeginStruct(m)
+	if r.More(&head) {
+		m.Idx = r.ReadInt32()
+	}
+	if r.More(&head) {
+		m.Name = r.ReadString()
+	}
+	if r.More(&head) {
+		m.RefreshPoint = r.ReadUint16()
+	}
+	if r.More(&head) {
+		if n0 := r.ReadArrayLen(6); n0 > 0 {
+			m.SlotList = make([]TSlotData, n0)
+			for i0 := range m.SlotList {
+				r.ReadStruct(&m.SlotList[i0])
+			}
+		}
+	}
+	if r.More(&head) {
+		m.MaxCount = r.ReadInt32()
+	}
+	if r.More(&head) {
+		m.MaxLineUpCount = r.ReadInt32()
+	}
+	if r.More(&head) {
+		m.MaxClerk = r.ReadInt32()
+	}
+	if r.More(&head) {
+		m.MaxCook = r.ReadInt32()
+	}
+	if r.More(&head) {
+		m.CookExp = r.ReadInt32()
+	}
+	if r.More(&head) {
+		m.OrderExp = r.ReadInt32()
+	}
+	if r.More(&head) {
+		m.DeliveryExp = r.ReadInt32()
+	}
+	if r.More(&head) {
+		m.PointRefreshTime = r.ReadInt32()
+	}
+	return r.EndStruct(&head)
+}
+
+// MarshalProtocol 实现 IProtocolMarshaler
+func (m *TSlotData) MarshalProtocol(w *ProtocolWritter) error {
+	if m == nil {
+		w.WriteEmptyHeader()
+		return nil
+	}
+	head, err := w.BeginStruct(m)
+	if err != nil {
+		return err
+	}
+	w.WriteInt32(m.Idx)
+	w.WriteUint16(m.SlotType)
+	w.WriteBool(m.BoSit)
+	w.WriteInt32(m.PlaceItemId)
+	w.WriteInt32(m.SitPersonId)
+	return w.EndStruct(&head)
+}
+
+// UnmarshalProtocol 实现 IProtocolUnmarshaler
+func (m *TSlotData) UnmarshalProtocol(r *ProtocolReader) error {
+	*m = TSlotData{}
+	head := r.BeginStruct(m)
+	if r.More(&head) {
+		m.Idx = r.ReadInt32()
+	}
+	if r.More(&head) {
+		m.SlotType = r.ReadUint16()
+	}
+	if r.More(&head) {
+		m.BoSit = r.ReadBool()
+	}
+	if r.More(&head) {
+		m.PlaceItemId = r.ReadInt32()
+	}
+	if r.More(&head) {
+		m.SitPersonId = r.ReadInt32()
+	}
+	return r.EndStruct(&head)
+}
@@ -0,0 +1,63 @@
+// goprotocol-compat 检查两个协议结构描述之间的修改是否兼容,有不兼容的修改时返回 1
+// 结构描述由 protocol.ExportSchema 导出,一般将发布版本的导出结果保存下来,在 CI 中与当前版本比较
+//
+// 用法:
+//
+//	goprotocol-compat old.schema new.schema
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	goprotocol "github.com/raochq/goprotocol"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: goprotocol-compat old.schema new.schema\n")
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	os.Exit(run(flag.Arg(0), flag.Arg(1), os.Stdout))
+}
+
+// run 输出所有不兼容的修改,返回进程的退出码
+func run(oldFile, newFile string, out io.Writer) int {
+	old, err := parseFile(oldFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goprotocol-compat: %v\n", err)
+		return 2
+	}
+	cur, err := parseFile(newFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goprotocol-compat: %v\n", err)
+		return 2
+	}
+	violations := goprotocol.CheckCompatibility(old, cur)
+	for _, v := range violations {
+		fmt.Fprintln(out, v)
+	}
+	if len(violations) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func parseFile(file string) (*goprotocol.TSchema, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := goprotocol.ParseSchema(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return s, nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	var out bytes.Buffer
+	if code := run("testdata/old.schema", "testdata/new.schema", &out); code != 1 {
+		t.Errorf("run() = %d, want 1", code)
+	}
+	want := `class TMapInfo(1000012) field 2 RefreshPoint: type changed from uint16 to int16
+class TMapInfo(1000012) field 4 Points: type changed from []int32 to [10]int32, data will be lost
+class TMapInfo(1000012) field 5 Cells: type changed from [8]uint8 to [4]uint8, data will be lost
+class TMapInfo(1000012) field 7 visible: renamed to Visible, private and public members can't be swapped
+class TRemoved(1000013): class removed
+`
+	if out.String() != want {
+		t.Errorf("run() output:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	out.Reset()
+	if code := run("testdata/old.schema", "testdata/old.schema", &out); code != 0 || out.Len() != 0 {
+		t.Errorf("run() = %d, output %q, want 0", code, out.String())
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// generator 输出一个文件的生成代码
+type generator struct {
+	buf       bytes.Buffer
+	q         string          // protocol 包的限定符
+	generated map[string]bool // 本次生成了方法的结构体,可以直接调用生成的方法
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// generate 为 file 中带注释的结构体生成代码,没有需要生成的结构体时返回 nil
+func generate(file string, pkgPath string) ([]byte, error) {
+	pkg, f, err := parsePackage(file, pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	specs := annotated(f)
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	g := &generator{generated: make(map[string]bool)}
+	structs := make([]*structInfo, 0, len(specs))
+	for _, spec := range specs {
+		info, err := pkg.structInfo(spec)
+		if err != nil {
+			return nil, err
+		}
+		structs = append(structs, info)
+		g.generated[info.name] = true
+	}
+
+	if pkgPath != "" {
+		g.q = "goprotocol."
+		if pkg.runtime != "" {
+			g.q = pkg.runtime + "."
+		}
+	}
+	for _, info := range structs {
+		if err := g.marshal(info); err != nil {
+			return nil, err
+		}
+		if err := g.unmarshal(info); err != nil {
+			return nil, err
+		}
+	}
+	body := g.buf.String()
+	g.buf.Reset()
+	g.printf("// Code generated by goprotocol-gen. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", pkg.name)
+	var imports []string
+	if pkgPath != "" && pkg.runtime == "" {
+		imports = append(imports, "goprotocol "+strconv.Quote(pkgPath))
+	}
+	for _, imp := range f.Imports { // 只保留生成代码中用到的包
+		name := importName(imp, pkgPath)
+		if name != "" && regexp.MustCompile(`\b`+regexp.QuoteMeta(name)+`\.`).MatchString(body) {
+			if imp.Name != nil {
+				imports = append(imports, imp.Name.Name+" "+imp.Path.Value)
+			} else {
+				imports = append(imports, imp.Path.Value)
+			}
+		}
+	}
+	if len(imports) > 0 {
+		g.printf("import (\n%s\n)\n\n", strings.Join(imports, "\n"))
+	}
+	g.buf.WriteString(body)
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %v\n%s", err, g.buf.Bytes())
+	}
+	return src, nil
+}
+
+func (g *generator) marshal(info *structInfo) error {
+	g.printf("// MarshalProtocol 实现 %sIProtocolMarshaler\n", g.q)
+	g.printf("func (m *%s) MarshalProtocol(w *%sProtocolWritter) error {\n", info.name, g.q)
+	g.printf("if m == nil {\nw.WriteEmptyHeader()\nreturn nil\n}\n")
+	g.printf("head, err := w.BeginStruct(m)\nif err != nil {\nreturn err\n}\n")
+	for _, field := range info.fields {
+		if field.name == "_" { // 占位成员写入零值
+			g.printf("{\nvar blank %s\n", field.typ.expr)
+			g.write("blank", field.typ, 0)
+			g.printf("}\n")
+			continue
+		}
+		g.write("m."+field.name, field.typ, 0)
+	}
+	g.printf("return w.EndStruct(&head)\n}\n\n")
+	return nil
+}
+
+// write 输出写入 expr 的代码, depth 用于生成不冲突的局部变量名
+func (g *generator) write(expr string, t *typeInfo, depth int) {
+	switch t.kind {
+	case kindPod:
+		if t.named() {
+			expr = t.basic + "(" + expr + ")"
+		}
+		g.printf("w.Write%s(%s)\n", t.method, expr)
+	case kindString:
+		if t.named() {
+			expr = "string(" + expr + ")"
+		}
+		method := "WriteString"
+		if t.long {
+			method = "WriteLongString"
+		}
+		g.printf("if err := w.%s(%s); err != nil {\nreturn err\n}\n", method, expr)
+	case kindStruct:
+		if g.generated[t.expr] {
+			g.printf("if err := %s.MarshalProtocol(w); err != nil {\nreturn err\n}\n", expr)
+		} else {
+			g.printf("if err := w.WriteAny(&%s); err != nil {\nreturn err\n}\n", expr)
+		}
+	case kindPtr:
+		if g.generated[t.elem.expr] {
+			g.printf("if err := %s.MarshalProtocol(w); err != nil {\nreturn err\n}\n", expr)
+		} else {
+			g.printf("if %s == nil {\nw.WriteEmptyHeader()\n} else if err := w.WriteAny(%s); err != nil {\nreturn err\n}\n", expr, expr)
+		}
+	case kindInterface:
+		g.printf("if err := w.WriteAny(%s); err != nil {\nreturn err\n}\n", expr)
+	case kindSlice, kindArray:
+		if t.blob() {
+			if t.kind == kindArray {
+				expr += "[:]"
+			}
+			g.printf("if err := w.WriteBlob(%s, %d); err != nil {\nreturn err\n}\n", expr, t.max)
+			return
+		}
+		i := fmt.Sprintf("i%d", depth)
+		g.printf("w.WriteArrayLen(len(%s))\n", expr)
+		g.printf("for %s := range %s {\n", i, expr)
+		g.write(expr+"["+i+"]", t.elem, depth+1)
+		g.printf("}\n")
+	case kindMap:
+		e := fmt.Sprintf("e%d", depth)
+		g.printf("w.WriteArrayLen(len(%s))\n", expr)
+		sorted := "SortedMapEntries"
+		if t.key.basic == "bool" {
+			sorted = "SortedBoolMapEntries"
+		}
+		g.printf("for _, %s := range %s%s(%s) {\n", e, g.q, sorted, expr)
+		g.write(e+".Key", t.key, depth+1)
+		g.write(e+".Val", t.elem, depth+1)
+		g.printf("}\n")
+	}
+}
+
+func (g *generator) unmarshal(info *structInfo) error {
+	g.printf("// UnmarshalProtocol 实现 %sIProtocolUnmarshaler\n", g.q)
+	g.printf("func (m *%s) UnmarshalProtocol(r *%sProtocolReader) error {\n", info.name, g.q)
+	g.printf("*m = %s{}\n", info.name)
+	g.printf("head := r.BeginStruct(m)\n")
+	for _, field := range info.fields {
+		g.printf("if r.More(&head) {\n")
+		if field.name == "_" {
+			g.printf("var blank %s\n", field.typ.expr)
+			g.read("blank", field.typ, 0)
+			g.printf("_ = blank\n")
+		} else {
+			g.read("m."+field.name, field.typ, 0)
+		}
+		g.printf("}\n")
+	}
+	g.printf("return r.EndStruct(&head)\n}\n\n")
+	return nil
+}
+
+// read 输出读取到 target 的代码, target 必须可以寻址
+func (g *generator) read(target string, t *typeInfo, depth int) {
+	switch t.kind {
+	case kindPod:
+		if t.named() {
+			g.printf("%s = %s(r.Read%s())\n", target, t.expr, t.method)
+		} else {
+			g.printf("%s = r.Read%s()\n", target, t.method)
+		}
+	case kindString:
+		call := "r.ReadString()"
+		if t.long {
+			call = "r.ReadLongString()"
+		}
+		if t.named() {
+			g.printf("%s = %s(%s)\n", target, t.expr, call)
+		} else {
+			g.printf("%s = %s\n", target, call)
+		}
+	case kindStruct:
+		g.printf("r.ReadStruct(&%s)\n", target)
+	case kindPtr:
+		g.printf("if !r.ReadNil() {\n%s = new(%s)\nr.ReadStruct(%s)\n}\n", target, t.elem.expr, target)
+	case kindInterface:
+		g.printf("%s = r.ReadAny()\n", target)
+	case kindSlice:
+		if t.blob() { // []byte 可以直接赋值给以 []byte 为基础类型的自定义类型
+			g.printf("%s = r.ReadBlob(%d)\n", target, t.max)
+			return
+		}
+		n, i := fmt.Sprintf("n%d", depth), fmt.Sprintf("i%d", depth)
+		g.printf("if %s := r.ReadArrayLen(%d); %s > 0 {\n", n, t.elem.minSize(), n)
+		g.printf("%s = make(%s, %s)\n", target, t.expr, n)
+		g.printf("for %s := range %s {\n", i, target)
+		g.read(target+"["+i+"]", t.elem, depth+1)
+		g.printf("}\n}\n")
+	case kindArray: // 数据中的元素个数可能与数组长度不一致,多余的元素读取后丢弃
+		if t.blob() {
+			g.printf("r.ReadBlobInto(%s[:])\n", target)
+			return
+		}
+		n, i, tmp := fmt.Sprintf("n%d", depth), fmt.Sprintf("i%d", depth), fmt.Sprintf("tmp%d", depth)
+		g.printf("%s := r.ReadArrayLen(%d)\n", n, t.elem.minSize())
+		g.printf("for %s := 0; %s < %s; %s++ {\n", i, i, n, i)
+		g.printf("if %s < len(%s) {\n", i, target)
+		g.read(target+"["+i+"]", t.elem, depth+1)
+		g.printf("} else {\nvar %s %s\n", tmp, t.elem.expr)
+		g.read(tmp, t.elem, depth+1)
+		g.printf("_ = %s\n}\n}\n", tmp)
+	case kindMap:
+		n, i, k, v := fmt.Sprintf("n%d", depth), fmt.Sprintf("i%d", depth), fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth)
+		g.printf("if %s := r.ReadArrayLen(1); %s > 0 {\n", n, n) // 与反射方式一样按每个元素至少一个字节检查
+		g.printf("%s = make(%s, %s)\n", target, t.expr, n)
+		g.printf("for %s := 0; %s < %s; %s++ {\n", i, i, n, i)
+		g.printf("var %s %s\nvar %s %s\n", k, t.key.expr, v, t.elem.expr)
+		g.read(k, t.key, depth+1)
+		g.read(v, t.elem, depth+1)
+		g.printf("%s[%s] = %s\n}\n}\n", target, k, v)
+	}
+}
@@ -0,0 +1,59 @@
+// goprotocol-gen 为带有 //goprotocol:generate 注释的结构体生成 MarshalProtocol/UnmarshalProtocol 方法
+// 生成的代码不使用反射和 unsafe 偏移,输出与 protocol.Marshal 完全一致
+//
+// 用法:
+//
+//	//go:generate goprotocol-gen ProtocolClasses.go
+//
+//	//goprotocol:generate
+//	type TMapInfo struct { ... }
+//
+// 每个输入文件 xxx.go 生成 xxx_gen.go,结构体仍然需要调用 RegisterDataClass 注册
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const annotation = "//goprotocol:generate"
+
+func main() {
+	pkgPath := flag.String("pkg", "github.com/raochq/goprotocol", "import path of the protocol package, empty when generating inside it")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: goprotocol-gen [flags] file.go...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	files := flag.Args()
+	if len(files) == 0 {
+		if gofile := os.Getenv("GOFILE"); gofile != "" {
+			files = []string{gofile}
+		} else {
+			flag.Usage()
+			os.Exit(2)
+		}
+	}
+	for _, file := range files {
+		src, err := generate(file, *pkgPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "goprotocol-gen: %v\n", err)
+			os.Exit(1)
+		}
+		if src == nil {
+			continue
+		}
+		if err := os.WriteFile(outputName(file), src, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "goprotocol-gen: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// outputName xxx.go 对应的生成文件 xxx_gen.go
+func outputName(file string) string {
+	return strings.TrimSuffix(file, filepath.Ext(file)) + "_gen.go"
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		pkgPath string
+		golden  string
+	}{
+		{"protocol", "../../ProtocolClasses.go", "", "../../ProtocolClasses_gen.go"},
+		{"kinds", "testdata/kinds/kinds.go", "github.com/raochq/goprotocol", "testdata/kinds/kinds_gen.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := generate(tt.file, tt.pkgPath)
+			if err != nil {
+				t.Fatalf("generate() error = %v", err)
+			}
+			want, err := os.ReadFile(tt.golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("generate() is out of date with %s, run go generate\n%s", tt.golden, got)
+			}
+		})
+	}
+}
+
+func TestGenerateUnsupported(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+	}{
+		{"chan", "C chan int"},
+		{"long", "N int32 `protocol:\",long\"`"},
+		{"max", "N []int32 `protocol:\",max=4\"`"},
+		{"slice of map", "M [][]map[int32]int32"},
+		{"map of map", "M map[int32]map[int32]int32"},
+		{"error", "E error"},
+		{"non-empty interface", "S interface{ String() string }"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := []byte("package p\n\n//goprotocol:generate\ntype T struct {\n\t" + tt.field + "\n}\n")
+			dir := t.TempDir()
+			if err := os.WriteFile(dir+"/p.go", src, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := generate(dir+"/p.go", ""); err == nil {
+				t.Errorf("generate() expected error for %s", tt.field)
+			}
+		})
+	}
+}
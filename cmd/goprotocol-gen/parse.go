@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type kind int
+
+const (
+	kindPod kind = iota
+	kindString
+	kindStruct
+	kindPtr
+	kindInterface
+	kindSlice
+	kindArray
+	kindMap
+)
+
+// typeInfo 成员类型在协议中的编码方式
+type typeInfo struct {
+	kind   kind
+	expr   string    // Go 源码中的类型
+	basic  string    // 数值和字符串的基础类型,如 int32
+	method string    // 数值类型读写方法的后缀,如 Int32
+	long   bool      // 字符串使用 uint32 长度,由成员的 protocol tag 的 long 指定
+	max    int       // []byte 的最大字节数,由成员的 protocol tag 的 max 指定
+	elem   *typeInfo // 数组、切片和 map 的元素类型,指针指向的类型
+	key    *typeInfo // map 的 key 类型
+}
+
+// named 是否是以数值或字符串为基础类型的自定义类型,读写时需要类型转换
+func (t *typeInfo) named() bool {
+	return t.expr != t.basic
+}
+
+// minSize 元素编码后最少占用的字节数,与 protocol 包中反射方式解码时的检查一致
+func (t *typeInfo) minSize() int {
+	switch t.kind {
+	case kindPod:
+		return podSizes[t.method]
+	case kindString:
+		if t.long {
+			return 4
+		}
+		return 2
+	case kindSlice, kindArray:
+		return 4
+	}
+	return 6 // 空数据头
+}
+
+// podSizes 数值类型编码后的字节数, int、uint、uintptr 固定为 8
+var podSizes = map[string]int{
+	"Bool": 1, "Int8": 1, "Uint8": 1, "Int16": 2, "Uint16": 2, "Int32": 4, "Uint32": 4, "Float32": 4,
+	"Int64": 8, "Uint64": 8, "Float64": 8, "Int": 8, "Uint": 8, "Uintptr": 8,
+}
+
+var podMethods = map[string]string{
+	"bool":    "Bool",
+	"int8":    "Int8",
+	"uint8":   "Uint8",
+	"byte":    "Uint8",
+	"int16":   "Int16",
+	"uint16":  "Uint16",
+	"int32":   "Int32",
+	"rune":    "Int32",
+	"uint32":  "Uint32",
+	"int64":   "Int64",
+	"uint64":  "Uint64",
+	"int":     "Int",
+	"uint":    "Uint",
+	"uintptr": "Uintptr",
+	"float32": "Float32",
+	"float64": "Float64",
+}
+
+// structField 结构体的一个成员
+type structField struct {
+	name string // 匿名成员为类型名, _ 为占位成员
+	typ  *typeInfo
+}
+
+type structInfo struct {
+	name   string
+	fields []structField
+}
+
+// pkgInfo 包中所有文件声明的类型,用于解析成员类型
+type pkgInfo struct {
+	fset    *token.FileSet
+	name    string
+	types   map[string]*ast.TypeSpec
+	runtime string // 目标文件中 protocol 包的导入名
+}
+
+// parsePackage 解析 file 所在目录中的所有非测试文件
+func parsePackage(file string, pkgPath string) (*pkgInfo, *ast.File, error) {
+	fset := token.NewFileSet()
+	dir := filepath.Dir(file)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkg := &pkgInfo{fset: fset, types: make(map[string]*ast.TypeSpec)}
+	var target *ast.File
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		isTarget := filepath.Clean(path) == filepath.Clean(file)
+		if !isTarget && strings.HasSuffix(name, "_gen.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, nil, err
+		}
+		if isTarget {
+			target = f
+			pkg.name = f.Name.Name
+			pkg.runtime = runtimeName(f, pkgPath)
+		}
+		for _, decl := range f.Decls {
+			if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.TYPE {
+				for _, spec := range gd.Specs {
+					ts := spec.(*ast.TypeSpec)
+					pkg.types[ts.Name.Name] = ts
+				}
+			}
+		}
+	}
+	if target == nil {
+		return nil, nil, fmt.Errorf("%s: file not found", file)
+	}
+	return pkg, target, nil
+}
+
+// runtimeName 返回 f 中导入 pkgPath 使用的包名,没有导入时返回空
+func runtimeName(f *ast.File, pkgPath string) string {
+	for _, imp := range f.Imports {
+		if path, _ := strconv.Unquote(imp.Path.Value); path == pkgPath && pkgPath != "" {
+			return importName(imp, pkgPath)
+		}
+	}
+	return ""
+}
+
+// importName 导入的包使用的包名,未显式命名时按惯例取路径的最后一段
+func importName(imp *ast.ImportSpec, pkgPath string) string {
+	if imp.Name != nil {
+		if imp.Name.Name == "_" || imp.Name.Name == "." {
+			return ""
+		}
+		return imp.Name.Name
+	}
+	path, _ := strconv.Unquote(imp.Path.Value)
+	if path == pkgPath { // protocol 包的包名与路径不一致
+		return "protocol"
+	}
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// annotated 返回文件中带有 //goprotocol:generate 注释的结构体
+func annotated(f *ast.File) []*ast.TypeSpec {
+	var specs []*ast.TypeSpec
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if _, ok := ts.Type.(*ast.StructType); !ok {
+				continue
+			}
+			if hasAnnotation(ts.Doc) || (len(gd.Specs) == 1 && hasAnnotation(gd.Doc)) {
+				specs = append(specs, ts)
+			}
+		}
+	}
+	return specs
+}
+
+func hasAnnotation(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == annotation {
+			return true
+		}
+	}
+	return false
+}
+
+// structInfo 按声明顺序展开结构体的成员,与 reflect 的 NumField 顺序一致
+func (p *pkgInfo) structInfo(ts *ast.TypeSpec) (*structInfo, error) {
+	st := ts.Type.(*ast.StructType)
+	info := &structInfo{name: ts.Name.Name}
+	for _, field := range st.Fields.List {
+		tag := protocolTag(field)
+		if tag == "-" {
+			continue
+		}
+		typ, err := p.resolve(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %v", p.fset.Position(field.Pos()), ts.Name.Name, err)
+		}
+		if _, ok := tagOption(tag, "long"); ok && !typ.setLong() {
+			return nil, fmt.Errorf("%s: %s: option long requires a string member", p.fset.Position(field.Pos()), ts.Name.Name)
+		}
+		if v, ok := tagOption(tag, "max"); ok {
+			if typ.kind != kindSlice || !typ.blob() {
+				return nil, fmt.Errorf("%s: %s: option max requires a []byte member", p.fset.Position(field.Pos()), ts.Name.Name)
+			}
+			if typ.max, err = strconv.Atoi(v); err != nil || typ.max < 0 {
+				return nil, fmt.Errorf("%s: %s: bad option max=%s", p.fset.Position(field.Pos()), ts.Name.Name, v)
+			}
+		}
+		if len(field.Names) == 0 { // 匿名成员
+			info.fields = append(info.fields, structField{name: embeddedName(field.Type), typ: typ})
+			continue
+		}
+		for _, name := range field.Names {
+			info.fields = append(info.fields, structField{name: name.Name, typ: typ})
+		}
+	}
+	return info, nil
+}
+
+// protocolTag 成员的 protocol tag, `protocol:"-"` 表示不序列化该成员
+func protocolTag(field *ast.Field) string {
+	if field.Tag == nil {
+		return ""
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return reflect.StructTag(tag).Get("protocol")
+}
+
+// tagOption 返回 tag 的名字之后的选项 key 或 key=value 中的 value
+func tagOption(tag, key string) (string, bool) {
+	opts := strings.Split(tag, ",")
+	for _, opt := range opts[1:] {
+		if k, v, _ := strings.Cut(opt, "="); k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// blob 是否是 []byte 或 [N]byte,整块读写
+func (t *typeInfo) blob() bool {
+	return (t.kind == kindSlice || t.kind == kindArray) && t.elem.kind == kindPod && t.elem.expr == "uint8"
+}
+
+// setLong 把成员中的字符串标记为长字符串,没有字符串时返回 false
+func (t *typeInfo) setLong() bool {
+	switch t.kind {
+	case kindString:
+		t.long = true
+		return true
+	case kindSlice, kindArray:
+		return t.elem.setLong()
+	case kindMap:
+		key, elem := t.key.setLong(), t.elem.setLong()
+		return key || elem
+	}
+	return false
+}
+
+func embeddedName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// resolve 解析成员类型的编码方式
+func (p *pkgInfo) resolve(expr ast.Expr) (*typeInfo, error) {
+	return p.resolveNamed(expr, p.exprString(expr), 0)
+}
+
+func (p *pkgInfo) resolveNamed(expr ast.Expr, name string, depth int) (*typeInfo, error) {
+	if depth > 16 {
+		return nil, fmt.Errorf("type %s: too many indirections", name)
+	}
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if method, ok := podMethods[e.Name]; ok {
+			basic := e.Name
+			if name == basic {
+				switch basic { // 别名的基础类型
+				case "byte":
+					basic = "uint8"
+				case "rune":
+					basic = "int32"
+				}
+				name = basic
+			}
+			return &typeInfo{kind: kindPod, expr: name, basic: basic, method: method}, nil
+		}
+		switch e.Name {
+		case "string":
+			return &typeInfo{kind: kindString, expr: name, basic: "string"}, nil
+		case "IMsg":
+			if p.runtime == "" && p.name == "protocol" { // 在 protocol 包中生成
+				return &typeInfo{kind: kindInterface, expr: name}, nil
+			}
+		case "any":
+			return &typeInfo{kind: kindInterface, expr: name}, nil
+		}
+		ts, ok := p.types[e.Name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported type %s", e.Name)
+		}
+		if _, ok := ts.Type.(*ast.StructType); ok {
+			return &typeInfo{kind: kindStruct, expr: name}, nil
+		}
+		return p.resolveNamed(ts.Type, name, depth+1)
+	case *ast.SelectorExpr: // 其他包中的类型,除 protocol.IMsg 外只支持已注册的结构体
+		if x, ok := e.X.(*ast.Ident); ok && x.Name == p.runtime && e.Sel.Name == "IMsg" {
+			return &typeInfo{kind: kindInterface, expr: name}, nil
+		}
+		return &typeInfo{kind: kindStruct, expr: name}, nil
+	case *ast.StarExpr:
+		elem, err := p.resolve(e.X)
+		if err != nil {
+			return nil, err
+		}
+		if elem.kind != kindStruct {
+			return nil, fmt.Errorf("pointer member %s must point to a registered struct", name)
+		}
+		return &typeInfo{kind: kindPtr, expr: name, elem: elem}, nil
+	case *ast.InterfaceType:
+		if len(e.Methods.List) != 0 { // 与 Register 一致,只支持空接口
+			return nil, fmt.Errorf("unsupported non-empty interface %s", name)
+		}
+		return &typeInfo{kind: kindInterface, expr: name}, nil
+	case *ast.ArrayType:
+		elem, err := p.resolve(e.Elt)
+		if err != nil {
+			return nil, err
+		}
+		if elem.kind == kindMap { // 数组和切片可以嵌套,map 不能作为元素
+			return nil, fmt.Errorf("unsupported element type of %s", name)
+		}
+		if e.Len == nil {
+			return &typeInfo{kind: kindSlice, expr: name, elem: elem}, nil
+		}
+		return &typeInfo{kind: kindArray, expr: name, elem: elem}, nil
+	case *ast.MapType:
+		key, err := p.resolve(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		if key.kind != kindPod && key.kind != kindString {
+			return nil, fmt.Errorf("unsupported key type of %s", name)
+		}
+		elem, err := p.resolve(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		if elem.kind == kindMap {
+			return nil, fmt.Errorf("unsupported value type of %s", name)
+		}
+		return &typeInfo{kind: kindMap, expr: name, key: key, elem: elem}, nil
+	case *ast.ParenExpr:
+		return p.resolveNamed(e.X, name, depth)
+	}
+	return nil, fmt.Errorf("unsupported type %s", name)
+}
+
+// exprString 类型表达式的源码
+func (p *pkgInfo) exprString(expr ast.Expr) string {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, p.fset, expr)
+	return buf.String()
+}
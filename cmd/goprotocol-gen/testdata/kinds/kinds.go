@@ -0,0 +1,51 @@
+package kinds
+
+import protocol "github.com/raochq/goprotocol"
+
+type ItemId int32
+type Name string
+type Blob []byte
+
+// TAllKinds 覆盖生成器支持的所有成员类型
+//
+//goprotocol:generate
+type TAllKinds struct {
+	A      int
+	B, C   bool
+	D      uint16
+	E      float64
+	Id     ItemId
+	Name   Name
+	Bytes  []byte
+	Arr    [3]int64
+	Strs   []string
+	Slots  []protocol.TSlotData
+	PSlots [2]*protocol.TSlotData
+	Items  []interface{}
+	Ptr    *TInner
+	Inner  TInner
+	Any    interface{}
+	Msg    protocol.IMsg
+	ById   map[ItemId]TInner
+	ByName map[string]*TInner
+	Flags  map[uint8]interface{}
+	Log    Name             `protocol:",long"`
+	Docs   map[int32]string `protocol:"docs,long"`
+	Image  Blob             `protocol:",max=1024"`
+	Key    [16]byte
+	Grid   [][]int32
+	Matrix [4][4]float32
+	Chunks [][]byte
+	Tags   map[int32][]string
+	Toggle map[bool]Name
+	_      int32
+	TInner
+	Cache chan int `protocol:"-"`
+	Level int32    `protocol:"level,since=2"`
+}
+
+//goprotocol:generate
+type TInner struct {
+	X int32
+	Y string
+}
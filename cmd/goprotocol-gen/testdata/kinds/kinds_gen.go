@@ -0,0 +1,439 @@
+// Code generated by goprotocol-gen. DO NOT EDIT.
+
+package kinds
+
+import (
+	protocol "github.com/raochq/goprotocol"
+)
+
+// MarshalProtocol 实现 protocol.IProtocolMarshaler
+func (m *TAllKinds) MarshalProtocol(w *protocol.ProtocolWritter) error {
+	if m == nil {
+		w.WriteEmptyHeader()
+		return nil
+	}
+	head, err := w.BeginStruct(m)
+	if err != nil {
+		return err
+	}
+	w.WriteInt(m.A)
+	w.WriteBool(m.B)
+	w.WriteBool(m.C)
+	w.WriteUint16(m.D)
+	w.WriteFloat64(m.E)
+	w.WriteInt32(int32(m.Id))
+	if err := w.WriteString(string(m.Name)); err != nil {
+		return err
+	}
+	if err := w.WriteBlob(m.Bytes, 0); err != nil {
+		return err
+	}
+	w.WriteArrayLen(len(m.Arr))
+	for i0 := range m.Arr {
+		w.WriteInt64(m.Arr[i0])
+	}
+	w.WriteArrayLen(len(m.Strs))
+	for i0 := range m.Strs {
+		if err := w.WriteString(m.Strs[i0]); err != nil {
+			return err
+		}
+	}
+	w.WriteArrayLen(len(m.Slots))
+	for i0 := range m.Slots {
+		if err := w.WriteAny(&m.Slots[i0]); err != nil {
+			return err
+		}
+	}
+	w.WriteArrayLen(len(m.PSlots))
+	for i0 := range m.PSlots {
+		if m.PSlots[i0] == nil {
+			w.WriteEmptyHeader()
+		} else if err := w.WriteAny(m.PSlots[i0]); err != nil {
+			return err
+		}
+	}
+	w.WriteArrayLen(len(m.Items))
+	for i0 := range m.Items {
+		if err := w.WriteAny(m.Items[i0]); err != nil {
+			return err
+		}
+	}
+	if err := m.Ptr.MarshalProtocol(w); err != nil {
+		return err
+	}
+	if err := m.Inner.MarshalProtocol(w); err != nil {
+		return err
+	}
+	if err := w.WriteAny(m.Any); err != nil {
+		return err
+	}
+	if err := w.WriteAny(m.Msg); err != nil {
+		return err
+	}
+	w.WriteArrayLen(len(m.ById))
+	for _, e0 := range protocol.SortedMapEntries(m.ById) {
+		w.WriteInt32(int32(e0.Key))
+		if err := e0.Val.MarshalProtocol(w); err != nil {
+			return err
+		}
+	}
+	w.WriteArrayLen(len(m.ByName))
+	for _, e0 := range protocol.SortedMapEntries(m.ByName) {
+		if err := w.WriteString(e0.Key); err != nil {
+			return err
+		}
+		if err := e0.Val.MarshalProtocol(w); err != nil {
+			return err
+		}
+	}
+	w.WriteArrayLen(len(m.Flags))
+	for _, e0 := range protocol.SortedMapEntries(m.Flags) {
+		w.WriteUint8(e0.Key)
+		if err := w.WriteAny(e0.Val); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteLongString(string(m.Log)); err != nil {
+		return err
+	}
+	w.WriteArrayLen(len(m.Docs))
+	for _, e0 := range protocol.SortedMapEntries(m.Docs) {
+		w.WriteInt32(e0.Key)
+		if err := w.WriteLongString(e0.Val); err != nil {
+			return err
+		}
+	}
+	if err := w.WriteBlob(m.Image, 1024); err != nil {
+		return err
+	}
+	if err := w.WriteBlob(m.Key[:], 0); err != nil {
+		return err
+	}
+	w.WriteArrayLen(len(m.Grid))
+	for i0 := range m.Grid {
+		w.WriteArrayLen(len(m.Grid[i0]))
+		for i1 := range m.Grid[i0] {
+			w.WriteInt32(m.Grid[i0][i1])
+		}
+	}
+	w.WriteArrayLen(len(m.Matrix))
+	for i0 := range m.Matrix {
+		w.WriteArrayLen(len(m.Matrix[i0]))
+		for i1 := range m.Matrix[i0] {
+			w.WriteFloat32(m.Matrix[i0][i1])
+		}
+	}
+	w.WriteArrayLen(len(m.Chunks))
+	for i0 := range m.Chunks {
+		if err := w.WriteBlob(m.Chunks[i0], 0); err != nil {
+			return err
+		}
+	}
+	w.WriteArrayLen(len(m.Tags))
+	for _, e0 := range protocol.SortedMapEntries(m.Tags) {
+		w.WriteInt32(e0.Key)
+		w.WriteArrayLen(len(e0.Val))
+		for i1 := range e0.Val {
+			if err := w.WriteString(e0.Val[i1]); err != nil {
+				return err
+			}
+		}
+	}
+	w.WriteArrayLen(len(m.Toggle))
+	for _, e0 := range protocol.SortedBoolMapEntries(m.Toggle) {
+		w.WriteBool(e0.Key)
+		if err := w.WriteString(string(e0.Val)); err != nil {
+			return err
+		}
+	}
+	{
+		var blank int32
+		w.WriteInt32(blank)
+	}
+	if err := m.TInner.MarshalProtocol(w); err != nil {
+		return err
+	}
+	w.WriteInt32(m.Level)
+	return w.EndStruct(&head)
+}
+
+// UnmarshalProtocol 实现 protocol.IProtocolUnmarshaler
+func (m *TAllKinds) UnmarshalProtocol(r *protocol.ProtocolReader) error {
+	*m = TAllKinds{}
+	head := r.BeginStruct(m)
+	if r.More(&head) {
+		m.A = r.ReadInt()
+	}
+	if r.More(&head) {
+		m.B = r.ReadBool()
+	}
+	if r.More(&head) {
+		m.C = r.ReadBool()
+	}
+	if r.More(&head) {
+		m.D = r.ReadUint16()
+	}
+	if r.More(&head) {
+		m.E = r.ReadFloat64()
+	}
+	if r.More(&head) {
+		m.Id = ItemId(r.ReadInt32())
+	}
+	if r.More(&head) {
+		m.Name = Name(r.ReadString())
+	}
+	if r.More(&head) {
+		m.Bytes = r.ReadBlob(0)
+	}
+	if r.More(&head) {
+		n0 := r.ReadArrayLen(8)
+		for i0 := 0; i0 < n0; i0++ {
+			if i0 < len(m.Arr) {
+				m.Arr[i0] = r.ReadInt64()
+			} else {
+				var tmp0 int64
+				tmp0 = r.ReadInt64()
+				_ = tmp0
+			}
+		}
+	}
+	if r.More(&head) {
+		if n0 := r.ReadArrayLen(2); n0 > 0 {
+			m.Strs = make([]string, n0)
+			for i0 := range m.Strs {
+				m.Strs[i0] = r.ReadString()
+			}
+		}
+	}
+	if r.More(&head) {
+		if n0 := r.ReadArrayLen(6); n0 > 0 {
+			m.Slots = make([]protocol.TSlotData, n0)
+			for i0 := range m.Slots {
+				r.ReadStruct(&m.Slots[i0])
+			}
+		}
+	}
+	if r.More(&head) {
+		n0 := r.ReadArrayLen(6)
+		for i0 := 0; i0 < n0; i0++ {
+			if i0 < len(m.PSlots) {
+				if !r.ReadNil() {
+					m.PSlots[i0] = new(protocol.TSlotData)
+					r.ReadStruct(m.PSlots[i0])
+				}
+			} else {
+				var tmp0 *protocol.TSlotData
+				if !r.ReadNil() {
+					tmp0 = new(protocol.TSlotData)
+					r.ReadStruct(tmp0)
+				}
+				_ = tmp0
+			}
+		}
+	}
+	if r.More(&head) {
+		if n0 := r.ReadArrayLen(6); n0 > 0 {
+			m.Items = make([]interface{}, n0)
+			for i0 := range m.Items {
+				m.Items[i0] = r.ReadAny()
+			}
+		}
+	}
+	if r.More(&head) {
+		if !r.ReadNil() {
+			m.Ptr = new(TInner)
+			r.ReadStruct(m.Ptr)
+		}
+	}
+	if r.More(&head) {
+		r.ReadStruct(&m.Inner)
+	}
+	if r.More(&head) {
+		m.Any = r.ReadAny()
+	}
+	if r.More(&head) {
+		m.Msg = r.ReadAny()
+	}
+	if r.More(&head) {
+		if n0 := r.ReadArrayLen(1); n0 > 0 {
+			m.ById = make(map[ItemId]TInner, n0)
+			for i0 := 0; i0 < n0; i0++ {
+				var k0 ItemId
+				var v0 TInner
+				k0 = ItemId(r.ReadInt32())
+				r.ReadStruct(&v0)
+				m.ById[k0] = v0
+			}
+		}
+	}
+	if r.More(&head) {
+		if n0 := r.ReadArrayLen(1); n0 > 0 {
+			m.ByName = make(map[string]*TInner, n0)
+			for i0 := 0; i0 < n0; i0++ {
+				var k0 string
+				var v0 *TInner
+				k0 = r.ReadString()
+				if !r.ReadNil() {
+					v0 = new(TInner)
+					r.ReadStruct(v0)
+				}
+				m.ByName[k0] = v0
+			}
+		}
+	}
+	if r.More(&head) {
+		if n0 := r.ReadArrayLen(1); n0 > 0 {
+			m.Flags = make(map[uint8]interface{}, n0)
+			for i0 := 0; i0 < n0; i0++ {
+				var k0 uint8
+				var v0 interface{}
+				k0 = r.ReadUint8()
+				v0 = r.ReadAny()
+				m.Flags[k0] = v0
+			}
+		}
+	}
+	if r.More(&head) {
+		m.Log = Name(r.ReadLongString())
+	}
+	if r.More(&head) {
+		if n0 := r.ReadArrayLen(1); n0 > 0 {
+			m.Docs = make(map[int32]string, n0)
+			for i0 := 0; i0 < n0; i0++ {
+				var k0 int32
+				var v0 string
+				k0 = r.ReadInt32()
+				v0 = r.ReadLongString()
+				m.Docs[k0] = v0
+			}
+		}
+	}
+	if r.More(&head) {
+		m.Image = r.ReadBlob(1024)
+	}
+	if r.More(&head) {
+		r.ReadBlobInto(m.Key[:])
+	}
+	if r.More(&head) {
+		if n0 := r.ReadArrayLen(4); n0 > 0 {
+			m.Grid = make([][]int32, n0)
+			for i0 := range m.Grid {
+				if n1 := r.ReadArrayLen(4); n1 > 0 {
+					m.Grid[i0] = make([]int32, n1)
+					for i1 := range m.Grid[i0] {
+						m.Grid[i0][i1] = r.ReadInt32()
+					}
+				}
+			}
+		}
+	}
+	if r.More(&head) {
+		n0 := r.ReadArrayLen(4)
+		for i0 := 0; i0 < n0; i0++ {
+			if i0 < len(m.Matrix) {
+				n1 := r.ReadArrayLen(4)
+				for i1 := 0; i1 < n1; i1++ {
+					if i1 < len(m.Matrix[i0]) {
+						m.Matrix[i0][i1] = r.ReadFloat32()
+					} else {
+						var tmp1 float32
+						tmp1 = r.ReadFloat32()
+						_ = tmp1
+					}
+				}
+			} else {
+				var tmp0 [4]float32
+				n1 := r.ReadArrayLen(4)
+				for i1 := 0; i1 < n1; i1++ {
+					if i1 < len(tmp0) {
+						tmp0[i1] = r.ReadFloat32()
+					} else {
+						var tmp1 float32
+						tmp1 = r.ReadFloat32()
+						_ = tmp1
+					}
+				}
+				_ = tmp0
+			}
+		}
+	}
+	if r.More(&head) {
+		if n0 := r.ReadArrayLen(4); n0 > 0 {
+			m.Chunks = make([][]byte, n0)
+			for i0 := range m.Chunks {
+				m.Chunks[i0] = r.ReadBlob(0)
+			}
+		}
+	}
+	if r.More(&head) {
+		if n0 := r.ReadArrayLen(1); n0 > 0 {
+			m.Tags = make(map[int32][]string, n0)
+			for i0 := 0; i0 < n0; i0++ {
+				var k0 int32
+				var v0 []string
+				k0 = r.ReadInt32()
+				if n1 := r.ReadArrayLen(2); n1 > 0 {
+					v0 = make([]string, n1)
+					for i1 := range v0 {
+						v0[i1] = r.ReadString()
+					}
+				}
+				m.Tags[k0] = v0
+			}
+		}
+	}
+	if r.More(&head) {
+		if n0 := r.ReadArrayLen(1); n0 > 0 {
+			m.Toggle = make(map[bool]Name, n0)
+			for i0 := 0; i0 < n0; i0++ {
+				var k0 bool
+				var v0 Name
+				k0 = r.ReadBool()
+				v0 = Name(r.ReadString())
+				m.Toggle[k0] = v0
+			}
+		}
+	}
+	if r.More(&head) {
+		var blank int32
+		blank = r.ReadInt32()
+		_ = blank
+	}
+	if r.More(&head) {
+		r.ReadStruct(&m.TInner)
+	}
+	if r.More(&head) {
+		m.Level = r.ReadInt32()
+	}
+	return r.EndStruct(&head)
+}
+
+// MarshalProtocol 实现 protocol.IProtocolMarshaler
+func (m *TInner) MarshalProtocol(w *protocol.ProtocolWritter) error {
+	if m == nil {
+		w.WriteEmptyHeader()
+		return nil
+	}
+	head, err := w.BeginStruct(m)
+	if err != nil {
+		return err
+	}
+	w.WriteInt32(m.X)
+	if err := w.WriteString(m.Y); err != nil {
+		return err
+	}
+	return w.EndStruct(&head)
+}
+
+// UnmarshalProtocol 实现 protocol.IProtocolUnmarshaler
+func (m *TInner) UnmarshalProtocol(r *protocol.ProtocolReader) error {
+	*m = TInner{}
+	head := r.BeginStruct(m)
+	if r.More(&head) {
+		m.X = r.ReadInt32()
+	}
+	if r.More(&head) {
+		m.Y = r.ReadString()
+	}
+	return r.EndStruct(&head)
+}
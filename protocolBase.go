@@ -1,18 +1,24 @@
 // 协议序列化和反序列的实现
 // 作者 饶长泉
-// 考虑到兼容各个平台,数值类型不使用变长类型 int 和uint
-// string必须小于65536
-// 考虑到其他平台的支持情况,目前只支持一维数组和切片
-// 会导所有成员的数据
+// 考虑到兼容各个平台,int、uint、uintptr 固定按 64 位读写,也可以通过 Registry.SetIntMode 禁止使用
+// 数值固定按小端存储,大端平台上读写时逐个转换字节序
+// string必须小于65536,更长的字符串需要在成员的 protocol tag 中指定 long
+// 数组和切片可以嵌套,如 [][]int32、[4][4]float32,每一层都带有元素个数
+// 会导出所有成员的数据,可以用 tag `protocol:"-"` 跳过成员,`protocol:"name,since=N"` 指定结构描述中的名字和成员加入的版本
 // 允许将结构体中的数组改为切片,若将切片成员改为数组,或将原数组长度改小.可以正常解析,但会丢失数据
 // 一旦数据结构确定,只能在最后追加成员,不允许删除成员,允许修改成员名字,但不允许私有成员和公有成员互相转换
 // 不支持成员为unsafe.Pointer,*interface{}对象
-// 不支持interface成员中存放结构体外的类型
+// map的key只支持数值类型和string,value支持的类型与切片元素相同
+// interface成员中只能存放已注册的结构体和内置类型(数值、字符串、[]byte 和数值、字符串切片)
 // 切片或者数值中存放的是指针时,只允许存放指向已注册的结构体
 package protocol
 
 import (
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"unsafe"
 )
 
@@ -23,19 +29,30 @@ const (
 		    0x6D98 = 0110 1101   1001 1000
 		    最后两位用来标识DataLength长度(bit 1)和ClassId长度(bit 0)
 	*/
-	cSignFlagMask uint16 = 0xFFFC
-	cSignFlag     uint16 = 0x6D98 // 序列化 Sign
-	stringLenSize        = 2
-	arrayLenSize         = 4
+	cSignFlagMask     uint16 = 0xFFFC
+	cSignFlag         uint16 = 0x6D98 // 序列化 Sign
+	stringLenSize            = 2
+	longStringLenSize        = 4 // 带 long tag 的成员中的字符串长度使用 uint32
+	arrayLenSize             = 4
 )
 
 type TRegRttiData struct {
 	ClassId   uint32
-	BigData   bool
+	BigData   bool // Deprecated: 不再使用,写入时按 Size 计算出的长度选择数据长度字段
 	rType     reflect.Type
 	FieldData []TRegFieldOffsetData
+
+	marshaler   bool         // 实现了 IProtocolMarshaler
+	unmarshaler bool         // 实现了 IProtocolUnmarshaler
+	sizeHint    atomic.Int64 // 上次编码的长度,用于 Marshal 预分配缓冲区
+	bodySize    int          // 所有成员都是定长时为成员序列化后的长度,否则为 -1
 }
 type TRegFieldOffsetData struct {
+	Name          string // 成员名,可以由 protocol tag 指定
+	Exported      bool   // Go 的成员是否公有,与 protocol tag 指定的成员名无关
+	Since         int    // 成员加入协议的版本,由 protocol tag 的 since 指定
+	LongString    bool   // 成员中的字符串使用 uint32 长度,由 protocol tag 的 long 指定
+	MaxLen        int    // []byte 成员的最大字节数,由 protocol tag 的 max 指定,0 表示不限制
 	rType         reflect.Type
 	Kind          reflect.Kind
 	typeHash      uintptr
@@ -44,20 +61,42 @@ type TRegFieldOffsetData struct {
 	podSize       int          // 最终处理完毕后，当上一个字段有效时，此字段为合并值，否则为自身的大小
 	arraySize     int          // 数组和切片类型的元素类型大小
 	arrayLen      uint32       // 数组类型元素长度
-	arrayKind     reflect.Kind // 数组和切片类型的元素类型
-	arrayType     reflect.Type // 数组和切片类型的元素类型
+	arrayKind     reflect.Kind // 数组和切片类型的元素类型,map类型的value类型
+	arrayType     reflect.Type // 数组和切片类型的元素类型,map类型的value类型
+	mapKeyKind    reflect.Kind // map类型的key类型
+	mapKeyType    reflect.Type // map类型的key类型
+	blob          bool         // []byte 或 [N]byte 成员,整块读写,解码时可以引用输入数据
 
 }
 
+// G_ClassId G_DataClass 是默认注册表使用的 map,直接访问时没有加锁,只能在注册完成后读取
 var G_ClassId = make(map[uint32]*TRegRttiData)
 var G_DataClass = make(map[uintptr]*TRegRttiData)
 
 type IMsg interface{}
 
+// ErrClassMismatch 数据中的 classId 与目标对象注册的 classId 不一致
+type ErrClassMismatch struct {
+	Want uint32 // 目标对象注册的 classId
+	Got  uint32 // 数据头中的 classId
+}
+
+func (e *ErrClassMismatch) Error() string {
+	return fmt.Sprintf("classId mismatch: want %d, got %d", e.Want, e.Got)
+}
+
+// isPod 是否是可以直接按内存读写、合并的数值类型
 func (this *TRegFieldOffsetData) isPod() bool {
-	return isPod(this.Kind)
+	return isMemPod(this.Kind)
 }
 
+// stringLenSize 成员中字符串长度字段的字节数
+func (this *TRegFieldOffsetData) stringLenSize() int {
+	if this.LongString {
+		return longStringLenSize
+	}
+	return stringLenSize
+}
 func isPod(kd reflect.Kind) bool {
 	return kd >= reflect.Bool && kd <= reflect.Float64
 }
@@ -69,57 +108,70 @@ func GetClassId(msg IMsg) uint32 {
 }
 
 func GetRegRttiDataFromObj(msg IMsg) (*TRegRttiData, bool) {
-	tp := reflect.TypeOf(msg)
-	if tp.Kind() == reflect.Ptr {
-		tp = tp.Elem()
-	}
-	if rtti, ok := G_DataClass[uintptr(PtrOf(tp))]; ok {
-		return rtti, true
-	}
-	return nil, false
+	return defaultRegistry.FromObj(msg)
 }
 func GetRegRttiDataFromType(tp reflect.Type) (*TRegRttiData, bool) {
-	if rtti, ok := G_DataClass[uintptr(PtrOf(tp))]; ok {
-		return rtti, true
-	}
-	return nil, false
+	return defaultRegistry.FromType(tp)
 }
 func GetRegRttiDataByClassId(classid uint32) (dat *TRegRttiData, ok bool) {
-	dat, ok = G_ClassId[classid]
-	return
+	return defaultRegistry.ByClassId(classid)
 }
 func GetDataClass(classid uint32) reflect.Type {
-	if rtti, ok := G_ClassId[classid]; ok {
+	if rtti, ok := defaultRegistry.ByClassId(classid); ok {
 		return rtti.rType
 	}
 	return nil
 }
 
-// RegisterDataClass 注册函数
-func RegisterDataClass(msgid uint32, msg IMsg) {
-	// 只允许注册一次
-	if _, ok := G_ClassId[msgid]; ok {
-		return
-	}
-	tp := reflect.TypeOf(msg)
-	rType := uintptr(PtrOf(tp)) // 类型hash
-	pType := rType              // 对应指针hash
-	if tp.Kind() == reflect.Ptr {
-		tp = tp.Elem()
-		rType = uintptr(PtrOf(tp))
-	} else {
-		pType = uintptr(PtrOf(reflect.New(tp).Type()))
-	}
-	rtti := &TRegRttiData{ClassId: msgid, rType: tp, BigData: false}
-	rtti.FieldData = make([]TRegFieldOffsetData, tp.NumField())
+// RegisterDataClass 注册函数,注册到默认注册表
+func RegisterDataClass(msgid uint32, msg IMsg) error {
+	return defaultRegistry.Register(msgid, msg)
+}
+
+// MustRegisterDataClass 注册到默认注册表,注册失败时 panic
+func MustRegisterDataClass(msgid uint32, msg IMsg) {
+	defaultRegistry.MustRegister(msgid, msg)
+}
+
+// newRegRttiData 解析结构体 tp 的成员,生成注册信息, intMode 为注册表处理 int、uint、uintptr 的方式
+func newRegRttiData(msgid uint32, tp reflect.Type, intMode TIntMode) (*TRegRttiData, error) {
+	rtti := &TRegRttiData{ClassId: msgid, rType: tp}
+	rtti.marshaler = implementsDirectly(tp, marshalerType)
+	rtti.unmarshaler = implementsDirectly(tp, unmarshalerType)
+	rtti.FieldData = make([]TRegFieldOffsetData, 0, tp.NumField())
 	sumOffset := uintptr(0)
 	mergeIdx := 0
-	for i := 0; i < tp.NumField(); i++ {
-		fd := tp.Field(i)
+	since := 0
+	for fi := 0; fi < tp.NumField(); fi++ {
+		fd := tp.Field(fi)
 		ftp := fd.Type
+		tag, err := parseFieldTag(fd)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", tp.Name(), fd.Name, err)
+		}
+		if tag.skip { // 跳过的成员打断pod合并
+			sumOffset = 0
+			continue
+		}
+		if tag.since < since {
+			return nil, fmt.Errorf("%s.%s: since=%d is less than the previous member's since=%d", tp.Name(), fd.Name, tag.since, since)
+		}
+		since = tag.since
+		if err := checkFieldType(ftp, false); err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", tp.Name(), fd.Name, err)
+		}
+		if err := checkIntMode(ftp, intMode); err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", tp.Name(), fd.Name, err)
+		}
+		if tag.long && !hasKind(ftp, isString) {
+			return nil, fmt.Errorf("%s.%s: option long requires a string member, got %s", tp.Name(), fd.Name, ftp)
+		}
+		if tag.max > 0 && !(ftp.Kind() == reflect.Slice && ftp.Elem().Kind() == reflect.Uint8) {
+			return nil, fmt.Errorf("%s.%s: option max requires a []byte member, got %s", tp.Name(), fd.Name, ftp)
+		}
 
-		//fmt.Println(uintptr(PtrOf(ftp)))
-		rtti.FieldData[i] = TRegFieldOffsetData{rType: ftp, typeHash: uintptr(((*emptyInterface)(unsafe.Pointer(&ftp))).data), offset: fd.Offset, podMergeCount: 1, podSize: int(ftp.Size()), arraySize: 0, Kind: ftp.Kind()}
+		i := len(rtti.FieldData)
+		rtti.FieldData = append(rtti.FieldData, TRegFieldOffsetData{Name: tag.name, Exported: fd.IsExported(), Since: tag.since, LongString: tag.long, MaxLen: tag.max, rType: ftp, typeHash: uintptr(((*emptyInterface)(unsafe.Pointer(&ftp))).data), offset: fd.Offset, podMergeCount: 1, podSize: int(ftp.Size()), arraySize: 0, Kind: ftp.Kind()})
 		if !rtti.FieldData[i].isPod() {
 			switch rtti.FieldData[i].Kind {
 			case reflect.Slice:
@@ -127,12 +179,21 @@ func RegisterDataClass(msgid uint32, msg IMsg) {
 				rtti.FieldData[i].arrayKind = val.Kind()
 				rtti.FieldData[i].arraySize = int(val.Size())
 				rtti.FieldData[i].arrayType = val
+				rtti.FieldData[i].blob = val.Kind() == reflect.Uint8
 			case reflect.Array:
 				rtti.FieldData[i].arrayLen = uint32(ftp.Len())
 				val := ftp.Elem()
 				rtti.FieldData[i].arrayKind = val.Kind()
 				rtti.FieldData[i].arraySize = int(val.Size())
 				rtti.FieldData[i].arrayType = val
+				rtti.FieldData[i].blob = val.Kind() == reflect.Uint8
+			case reflect.Map:
+				key, val := ftp.Key(), ftp.Elem()
+				rtti.FieldData[i].mapKeyKind = key.Kind()
+				rtti.FieldData[i].mapKeyType = key
+				rtti.FieldData[i].arrayKind = val.Kind()
+				rtti.FieldData[i].arraySize = int(val.Size())
+				rtti.FieldData[i].arrayType = val
 			}
 			continue
 		}
@@ -150,9 +211,95 @@ func RegisterDataClass(msgid uint32, msg IMsg) {
 			}
 		}
 	}
-	G_ClassId[msgid] = rtti
-	G_DataClass[rType] = rtti
-	G_DataClass[pType] = rtti
+	rtti.bodySize = fixedBodySize(rtti.FieldData)
+	return rtti, nil
+}
+
+// checkFieldType 检查成员类型是否支持, elem 表示数组、切片和 map 的元素
+func checkFieldType(tp reflect.Type, elem bool) error {
+	kind := tp.Kind()
+	switch {
+	case isPod(kind), kind == reflect.String, kind == reflect.Struct:
+		return nil
+	case kind == reflect.Interface:
+		if tp.NumMethod() != 0 { // 读写时按 interface{} 的内存结构处理
+			return fmt.Errorf("unsupported interface type %s, only interface{} is supported", tp)
+		}
+		return nil
+	case kind == reflect.Ptr:
+		if tp.Elem().Kind() != reflect.Struct {
+			return fmt.Errorf("unsupported pointer type %s, only pointer to struct is supported", tp)
+		}
+		return nil
+	case elem && kind == reflect.Map:
+		return fmt.Errorf("unsupported nested type %s", tp)
+	case kind == reflect.Slice, kind == reflect.Array:
+		return checkFieldType(tp.Elem(), true)
+	case kind == reflect.Map:
+		if key := tp.Key().Kind(); !isPod(key) && key != reflect.String {
+			return fmt.Errorf("unsupported map key type %s", tp.Key())
+		}
+		return checkFieldType(tp.Elem(), true)
+	}
+	return fmt.Errorf("unsupported type %s", tp)
+}
+
+// hasKind 成员本身或数组、切片、map 的元素(包括嵌套的元素)是否是 match 的类型
+func hasKind(tp reflect.Type, match func(reflect.Kind) bool) bool {
+	switch tp.Kind() {
+	case reflect.Slice, reflect.Array:
+		return hasKind(tp.Elem(), match)
+	case reflect.Map:
+		return match(tp.Key().Kind()) || hasKind(tp.Elem(), match)
+	}
+	return match(tp.Kind())
+}
+
+func isString(kd reflect.Kind) bool { return kd == reflect.String }
+
+// tFieldTag 成员的 protocol tag: `protocol:"name,since=N,long,max=N"`, `protocol:"-"` 表示不序列化该成员
+type tFieldTag struct {
+	name  string // 在协议结构描述中使用的名字,默认为成员名
+	since int    // 成员加入协议的版本,后面的成员不能小于前面的成员
+	long  bool   // 成员中的字符串使用 uint32 长度,可以超过 65535 字节
+	max   int    // []byte 成员的最大字节数
+	skip  bool
+}
+
+func parseFieldTag(fd reflect.StructField) (tag tFieldTag, err error) {
+	tag.name = fd.Name
+	str, ok := fd.Tag.Lookup("protocol")
+	if !ok {
+		return tag, nil
+	}
+	if str == "-" {
+		tag.skip = true
+		return tag, nil
+	}
+	opts := strings.Split(str, ",")
+	if opts[0] != "" {
+		tag.name = opts[0]
+	}
+	if !isSchemaIdent(tag.name) {
+		return tag, fmt.Errorf("bad name in tag %q", str)
+	}
+	for _, opt := range opts[1:] {
+		if opt == "long" {
+			tag.long = true
+			continue
+		}
+		key, v, _ := strings.Cut(opt, "=")
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || (key != "since" && key != "max") {
+			return tag, fmt.Errorf("bad option %q in tag %q", opt, str)
+		}
+		if key == "since" {
+			tag.since = n
+		} else {
+			tag.max = n
+		}
+	}
+	return tag, nil
 }
 
 type emptyInterface struct {
@@ -168,15 +315,45 @@ func RTypeOf(obj interface{}) uintptr {
 }
 
 func Marshal(v interface{}) ([]byte, error) {
-	writter := NewProtocolWritter(10)
-	if err := writter.writeAny(v); err == nil {
-		return writter.Bytes(), nil
-	} else {
-		return nil, err
-	}
+	return defaultRegistry.Marshal(v)
 }
 
+// Marshal 序列化 v, v 必须在 reg 中注册,按协议上次编码的长度分配缓冲区
+func (reg *Registry) Marshal(v interface{}) ([]byte, error) {
+	return reg.AppendMarshal(nil, v)
+}
+
+// Unmarshal 反序列化数据,数据错误时返回 error,任何输入都不会 panic
+// 嵌套深度由 DecoderLimits.MaxDepth 限制,设为 0 时也不会超过 maxNestingDepth,不会栈溢出
 func Unmarshal(data []byte) (interface{}, error) {
-	Reader := NewProtocolReader(data)
+	return defaultRegistry.Unmarshal(data)
+}
+
+// Unmarshal 反序列化数据,数据中的 classId 只在 reg 中查找
+func (reg *Registry) Unmarshal(data []byte) (interface{}, error) {
+	Reader := reg.AcquireReader(data)
+	defer ReleaseReader(Reader)
 	return Reader.readAny()
 }
+
+// UnmarshalInto 将数据解码到调用方提供的对象中,dst 必须是已注册结构体的指针
+// 数据中的 classId 与 dst 注册的 classId 不一致时返回 *ErrClassMismatch
+func UnmarshalInto(data []byte, dst IMsg) error {
+	return defaultRegistry.UnmarshalInto(data, dst)
+}
+
+// UnmarshalInto 将数据解码到 dst 中,参见 UnmarshalInto
+func (reg *Registry) UnmarshalInto(data []byte, dst IMsg) error {
+	Reader := reg.AcquireReader(data)
+	defer ReleaseReader(Reader)
+	return Reader.readInto(dst)
+}
+
+// Decode 将数据解码为类型 T 的对象,T 必须是已注册的结构体
+func Decode[T any](data []byte) (*T, error) {
+	v := new(T)
+	if err := UnmarshalInto(data, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
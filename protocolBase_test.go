@@ -1,21 +1,47 @@
 // 协议序列化和反序列的实现
 // 作者 饶长泉
-// 考虑到兼容各个平台,数值类型不使用变长类型 int 和uint
-// string必须小于65536
-// 考虑到其他平台的支持情况,目前只支持一维数组和切片
+// 考虑到兼容各个平台,int、uint、uintptr 固定按 64 位读写,也可以通过 Registry.SetIntMode 禁止使用
+// string必须小于65536,更长的字符串需要在成员的 protocol tag 中指定 long
+// 数组和切片可以嵌套,如 [][]int32、[4][4]float32,每一层都带有元素个数
 // 只会导出公有成员的数据,忽略私有成员
 // 允许将结构体中的数组改为切片,若将切片成员改为数组,或将原数组长度改小.可以正常解析,但会丢失数据
 // 一旦数据结构确定,只能在最后追加成员,不允许删除成员,允许修改成员名字,但不允许私有成员和公有成员互相转换
 // 不支持成员为unsafe.Pointer,*interface{}对象
-// 不支持interface成员中存放 数值和结构体外的类型,比如map,slic array
+// interface成员中只能存放已注册的结构体和内置类型(数值、字符串、[]byte 和数值、字符串切片)
 package protocol
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"math"
 	"reflect"
+	"strings"
 	"testing"
 )
 
+type TestMsg struct {
+	A int
+	B bool
+	C uint16
+	D int32
+	E int64
+	F float32
+	G float64
+	H []TSlotData
+	I [2]TSlotData
+	J []interface{}
+	K [3]interface{}
+	L [4]int64
+	M []int16
+	N *TSlotData
+	O []*TSlotData
+	Q interface{}
+}
+
+var testMsgData = []byte{152, 109, 232, 3, 127, 1, 1, 0, 0, 0, 0, 0, 0, 0, 1, 255, 0, 255, 255, 255, 127, 255, 255, 255, 255, 255, 255, 255, 255, 205, 204, 140, 63, 56, 233, 47, 84, 251, 33, 9, 64, 2, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 2, 0, 0, 0, 2, 0, 0, 200, 0, 0, 0, 200, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 2, 0, 0, 0, 2, 0, 1, 200, 0, 0, 0, 200, 0, 0, 0, 2, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 3, 0, 0, 0, 3, 0, 0, 44, 1, 0, 0, 44, 1, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 4, 0, 0, 0, 4, 0, 1, 144, 1, 0, 0, 144, 1, 0, 0, 1, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 4, 0, 0, 0, 4, 0, 1, 144, 1, 0, 0, 144, 1, 0, 0, 3, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 4, 0, 0, 0, 4, 0, 1, 144, 1, 0, 0, 144, 1, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 188, 1, 0, 0, 188, 1, 1, 112, 173, 0, 0, 112, 173, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 4, 0, 0, 0, 4, 0, 1, 144, 1, 0, 0, 144, 1, 0, 0, 4, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 255, 255, 255, 255, 255, 255, 255, 255, 1, 0, 0, 0, 0, 0, 0, 0, 3, 0, 0, 0, 1, 0, 2, 0, 3, 0, 153, 109, 75, 66, 15, 0, 23, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 6, 0, 0, 0, 6, 0, 1, 6, 0, 0, 0, 6, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 7, 0, 0, 0, 7, 0, 1, 7, 0, 0, 0, 7, 0, 0, 0}
+
 func BenchmarkMarshal(b *testing.B) {
 	b.StopTimer()
 	testobj := &TMapInfo{1001, "宝山路", 19, []TSlotData{{1, 1, false, 0, 0}, {5, 2, false, 0, 0}, {6, 2, false, 0, 0}}, 30, 5, 2, 1, 3, 3, 3, 5000}
@@ -33,33 +59,12 @@ func BenchmarkUnmarshal(b *testing.B) {
 	}
 }
 func TestMarshal(t *testing.T) {
-
-	type TestMsg struct {
-		A int
-		B bool
-		C uint16
-		D int32
-		E int64
-		F float32
-		G float64
-		H []TSlotData
-		I [2]TSlotData
-		J []interface{}
-		K [3]interface{}
-		L [4]int64
-		M []int16
-		N *TSlotData
-		O []*TSlotData
-		Q interface{}
-	}
-	RegisterDataClass(ClassID_Test, (*TestMsg)(nil))
+	MustRegisterDataClass(ClassID_Test, (*TestMsg)(nil))
 	testobj := TestMsg{1, true, 0xff, 0x7fffffff, -1, 1.1, 3.141592653, []TSlotData{{2, 2, false, 200, 200},
 		{2, 2, true, 200, 200}}, [2]TSlotData{{3, 3, false, 300, 300},
 		{4, 4, true, 400, 400}}, []interface{}{TSlotData{4, 4, true, 400, 400}}, [3]interface{}{TSlotData{4, 4, true, 400, 400}, TSlotData{444, 444, true, 44400, 44400}, TSlotData{4, 4, true, 400, 400}}, [4]int64{1, 0, -1, 1},
 		[]int16{1, 2, 3}, new(TSlotData), []*TSlotData{&TSlotData{}, &TSlotData{6, 6, true, 6, 6}}, TSlotData{7, 7, true, 7, 7}}
 
-	testdata := []byte{152, 109, 232, 3, 127, 1, 1, 0, 0, 0, 0, 0, 0, 0, 1, 255, 0, 255, 255, 255, 127, 255, 255, 255, 255, 255, 255, 255, 255, 205, 204, 140, 63, 56, 233, 47, 84, 251, 33, 9, 64, 2, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 2, 0, 0, 0, 2, 0, 0, 200, 0, 0, 0, 200, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 2, 0, 0, 0, 2, 0, 1, 200, 0, 0, 0, 200, 0, 0, 0, 2, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 3, 0, 0, 0, 3, 0, 0, 44, 1, 0, 0, 44, 1, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 4, 0, 0, 0, 4, 0, 1, 144, 1, 0, 0, 144, 1, 0, 0, 1, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 4, 0, 0, 0, 4, 0, 1, 144, 1, 0, 0, 144, 1, 0, 0, 3, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 4, 0, 0, 0, 4, 0, 1, 144, 1, 0, 0, 144, 1, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 188, 1, 0, 0, 188, 1, 1, 112, 173, 0, 0, 112, 173, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 4, 0, 0, 0, 4, 0, 1, 144, 1, 0, 0, 144, 1, 0, 0, 4, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 255, 255, 255, 255, 255, 255, 255, 255, 1, 0, 0, 0, 0, 0, 0, 0, 3, 0, 0, 0, 1, 0, 2, 0, 3, 0, 153, 109, 75, 66, 15, 0, 23, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 6, 0, 0, 0, 6, 0, 1, 6, 0, 0, 0, 6, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 7, 0, 0, 0, 7, 0, 1, 7, 0, 0, 0, 7, 0, 0, 0}
-
 	type args struct {
 		v interface{}
 	}
@@ -71,7 +76,7 @@ func TestMarshal(t *testing.T) {
 	}{
 		{"TestMarshal1",
 			args{testobj},
-			testdata,
+			testMsgData,
 			false},
 	}
 	for _, tt := range tests {
@@ -89,33 +94,13 @@ func TestMarshal(t *testing.T) {
 }
 
 func TestUnmarshal(t *testing.T) {
-
-	type TestMsg struct {
-		A int
-		B bool
-		C uint16
-		D int32
-		E int64
-		F float32
-		G float64
-		H []TSlotData
-		I [2]TSlotData
-		J []interface{}
-		K [3]interface{}
-		L [4]int64
-		M []int16
-		N *TSlotData
-		O []*TSlotData
-		Q interface{}
-	}
-	RegisterDataClass(ClassID_Test, (*TestMsg)(nil))
+	MustRegisterDataClass(ClassID_Test, (*TestMsg)(nil))
 	testobj := TestMsg{1, true, 0xff, 0x7fffffff, -1, 1.1, 3.141592653, []TSlotData{{2, 2, false, 200, 200},
 		{2, 2, true, 200, 200}}, [2]TSlotData{{3, 3, false, 300, 300},
 		{4, 4, true, 400, 400}}, []interface{}{TSlotData{4, 4, true, 400, 400}}, [3]interface{}{TSlotData{4, 4, true, 400, 400}, TSlotData{444, 444, true, 44400, 44400}, TSlotData{4, 4, true, 400, 400}}, [4]int64{1, 0, -1, 1},
 		[]int16{1, 2, 3}, new(TSlotData), []*TSlotData{&TSlotData{}, &TSlotData{6, 6, true, 6, 6}}, TSlotData{7, 7, true, 7, 7}}
 
 	//testdata := []byte{152, 109, 232, 3, 104, 1, 1, 0, 0, 0, 0, 0, 0, 0, 1, 255, 0, 255, 255, 255, 127, 255, 255, 255, 255, 255, 255, 255, 255, 205, 204, 140, 63, 56, 233, 47, 84, 251, 33, 9, 64, 2, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 2, 0, 0, 0, 2, 0, 0, 200, 0, 0, 0, 200, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 2, 0, 0, 0, 2, 0, 1, 200, 0, 0, 0, 200, 0, 0, 0, 2, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 3, 0, 0, 0, 3, 0, 0, 44, 1, 0, 0, 44, 1, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 4, 0, 0, 0, 4, 0, 1, 144, 1, 0, 0, 144, 1, 0, 0, 1, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 4, 0, 0, 0, 4, 0, 1, 144, 1, 0, 0, 144, 1, 0, 0, 3, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 4, 0, 0, 0, 4, 0, 1, 144, 1, 0, 0, 144, 1, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 188, 1, 0, 0, 188, 1, 1, 112, 173, 0, 0, 112, 173, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 4, 0, 0, 0, 4, 0, 1, 144, 1, 0, 0, 144, 1, 0, 0, 4, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 255, 255, 255, 255, 255, 255, 255, 255, 1, 0, 0, 0, 0, 0, 0, 0, 3, 0, 0, 0, 1, 0, 2, 0, 3, 0, 153, 109, 75, 66, 15, 0, 23, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 6, 0, 0, 0, 6, 0, 1, 6, 0, 0, 0, 6, 0, 0, 0}
-	testdata := []byte{152,109,232,3,127,1,1,0,0,0,0,0,0,0,1,255,0,255,255,255,127,255,255,255,255,255,255,255,255,205,204,140,63,56,233,47,84,251,33,9,64,2,0,0,0,153,109,75,66,15,0,23,0,2,0,0,0,2,0,0,200,0,0,0,200,0,0,0,153,109,75,66,15,0,23,0,2,0,0,0,2,0,1,200,0,0,0,200,0,0,0,2,0,0,0,153,109,75,66,15,0,23,0,3,0,0,0,3,0,0,44,1,0,0,44,1,0,0,153,109,75,66,15,0,23,0,4,0,0,0,4,0,1,144,1,0,0,144,1,0,0,1,0,0,0,153,109,75,66,15,0,23,0,4,0,0,0,4,0,1,144,1,0,0,144,1,0,0,3,0,0,0,153,109,75,66,15,0,23,0,4,0,0,0,4,0,1,144,1,0,0,144,1,0,0,153,109,75,66,15,0,23,0,188,1,0,0,188,1,1,112,173,0,0,112,173,0,0,153,109,75,66,15,0,23,0,4,0,0,0,4,0,1,144,1,0,0,144,1,0,0,4,0,0,0,1,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,255,255,255,255,255,255,255,255,1,0,0,0,0,0,0,0,3,0,0,0,1,0,2,0,3,0,153,109,75,66,15,0,23,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,2,0,0,0,153,109,75,66,15,0,23,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,153,109,75,66,15,0,23,0,6,0,0,0,6,0,1,6,0,0,0,6,0,0,0,153,109,75,66,15,0,23,0,7,0,0,0,7,0,1,7,0,0,0,7,0,0,0}
 	type args struct {
 		data []byte
 	}
@@ -125,7 +110,7 @@ func TestUnmarshal(t *testing.T) {
 		want    interface{}
 		wantErr bool
 	}{
-		{"TestUnmarshal1", args{testdata}, &testobj, false},
+		{"TestUnmarshal1", args{testMsgData}, &testobj, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -142,3 +127,684 @@ func TestUnmarshal(t *testing.T) {
 		})
 	}
 }
+
+var mapInfoTestData = []byte{153, 109, 76, 66, 15, 0, 107, 0, 233, 3, 0, 0, 9, 0, 229, 174, 157, 229, 177, 177, 232, 183, 175, 19, 0, 2, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 1, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 153, 109, 75, 66, 15, 0, 23, 0, 5, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 30, 0, 0, 0, 5, 0, 0, 0, 2, 0, 0, 0, 1, 0, 0, 0, 3, 0, 0, 0, 3, 0, 0, 0, 3, 0, 0, 0, 136, 19, 0, 0}
+
+func newMapInfoTestObj() *TMapInfo {
+	return &TMapInfo{1001, "宝山路", 19, []TSlotData{{1, 1, false, 0, 0}, {5, 2, false, 0, 0}}, 30, 5, 2, 1, 3, 3, 3, 5000}
+}
+
+// unmarshalSeeds 返回各种成员类型的正确数据,作为截断测试和模糊测试的初始数据
+func unmarshalSeeds() [][]byte {
+	MustRegisterDataClass(ClassID_Test, (*TestMsg)(nil))
+	MustRegisterDataClass(ClassID_CodeGenTest, (*TCodeGenTest)(nil))
+	codeGenData, _ := Marshal(newCodeGenTestObj())
+	return [][]byte{testMsgData, mapInfoTestData, codeGenData}
+}
+
+// unmarshalAll 用反射和生成的代码解析 data,任何输入都不能 panic
+func unmarshalAll(data []byte) (err error) {
+	_, err = Unmarshal(data)
+	UnmarshalInto(data, &TMapInfo{})
+	withoutGenerated(func() { UnmarshalInto(data, &TMapInfo{}) }, (*TMapInfo)(nil))
+	NewDecoder(bytes.NewReader(data)).Decode()
+	return err
+}
+
+func TestUnmarshalTruncated(t *testing.T) {
+	for _, seed := range unmarshalSeeds() {
+		if err := unmarshalAll(seed); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+		for n := 0; n < len(seed); n++ {
+			if err := unmarshalAll(seed[:n]); err == nil {
+				t.Errorf("Unmarshal() of %d/%d bytes expected error", n, len(seed))
+			}
+		}
+	}
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	for _, seed := range unmarshalSeeds() {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		unmarshalAll(data)
+	})
+}
+
+func TestUnmarshalInto(t *testing.T) {
+	dst := &TMapInfo{Idx: 7, Name: "旧数据", SlotList: []TSlotData{{9, 9, true, 9, 9}, {9, 9, true, 9, 9}, {9, 9, true, 9, 9}}}
+	if err := UnmarshalInto(mapInfoTestData, dst); err != nil {
+		t.Fatalf("UnmarshalInto() error = %v", err)
+	}
+	if want := newMapInfoTestObj(); !reflect.DeepEqual(dst, want) {
+		t.Errorf("UnmarshalInto() = %+v, want %+v", dst, want)
+	}
+
+	var slot TSlotData
+	err := UnmarshalInto(mapInfoTestData, &slot)
+	var mismatch *ErrClassMismatch
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("UnmarshalInto() error = %v, want *ErrClassMismatch", err)
+	}
+	if mismatch.Want != ClassID_SlotData || mismatch.Got != ClassID_MapInfo {
+		t.Errorf("UnmarshalInto() mismatch = %+v", mismatch)
+	}
+}
+
+// TestEmptyMember 空字符串和 nil 指针可以解析
+func TestEmptyMember(t *testing.T) {
+	const ClassID_TestEmptyMember = ClassID_Test + 14
+	type TestEmptyMemberMsg struct {
+		Name  string
+		Slot  *TSlotData
+		Names []string
+		Slots []*TSlotData
+	}
+	MustRegisterDataClass(ClassID_TestEmptyMember, (*TestEmptyMemberMsg)(nil))
+	testobj := &TestEmptyMemberMsg{Names: []string{"", "a"}, Slots: []*TSlotData{nil, {1, 1, true, 1, 1}}}
+	data, err := Marshal(testobj)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	got, err := Decode[TestEmptyMemberMsg](data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, testobj) {
+		t.Errorf("Decode() = %+v, want %+v", got, testobj)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	got, err := Decode[TMapInfo](mapInfoTestData)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if want := newMapInfoTestObj(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %+v, want %+v", got, want)
+	}
+	if _, err := Decode[TSlotData](mapInfoTestData); err == nil {
+		t.Errorf("Decode() expected classId mismatch error")
+	}
+}
+
+// TestEmptyStringNilPointer 空字符串只写入长度 0, nil 指针写入空数据头,解码后保持为空串和 nil
+func TestEmptyStringNilPointer(t *testing.T) {
+	const ClassID_TestEmpty = ClassID_Test + 14
+	type TestEmptyMsg struct {
+		Name  string
+		Slot  *TSlotData
+		Names []string
+		Slots []*TSlotData
+		Arr   [2]*TSlotData
+	}
+	reg := NewRegistry()
+	reg.MustRegister(ClassID_SlotData, (*TSlotData)(nil))
+	if err := reg.Register(ClassID_TestEmpty, (*TestEmptyMsg)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := reg.Marshal(&TestEmptyMsg{})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	// 数据头(classId 1014) + Name(0) + Slot(空数据头) + Names(0) + Slots(0) + Arr(2 个空数据头)
+	empty := []byte{0x98, 0x6D, 0, 0, 6, 0}
+	want := append([]byte{0x98, 0x6D, 0xF6, 0x03, 38, 0, 0, 0}, empty...)
+	want = append(append(want, 0, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0), empty...)
+	want = append(want, empty...)
+	if !bytes.Equal(data, want) {
+		t.Errorf("Marshal() = %v, want %v", data, want)
+	}
+
+	msg := &TestEmptyMsg{Names: []string{"", "a", ""}, Slots: []*TSlotData{nil, {Idx: 1}, nil}, Arr: [2]*TSlotData{{Idx: 2}, nil}}
+	data, _ = reg.Marshal(msg)
+	got := &TestEmptyMsg{Name: "old", Slot: &TSlotData{}}
+	if err := reg.UnmarshalInto(data, got); err != nil || !reflect.DeepEqual(got, msg) {
+		t.Errorf("UnmarshalInto() = %+v, %v, want %+v", got, err, msg)
+	}
+
+	// 生成的代码
+	info := newMapInfoTestObj()
+	info.Name = ""
+	data, _ = Marshal(info)
+	if got := (&TMapInfo{Name: "old"}); UnmarshalInto(data, got) != nil || !reflect.DeepEqual(got, info) {
+		t.Errorf("UnmarshalInto() = %+v, want %+v", got, info)
+	}
+}
+
+func TestMapField(t *testing.T) {
+	const ClassID_TestMap = ClassID_Test + 1
+	type TestMapMsg struct {
+		A map[int32]TSlotData
+		B map[string]int16
+		C map[uint16]string
+		D map[string]*TSlotData
+		E map[int64]interface{}
+	}
+	MustRegisterDataClass(ClassID_TestMap, (*TestMapMsg)(nil))
+
+	got, err := Marshal(&TestMapMsg{B: map[string]int16{"b": 2, "a": 1}})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := []byte{152, 109, 233, 3, 36, 0, 0, 0, 0, 0, 2, 0, 0, 0, 1, 0, 97, 1, 0, 1, 0, 98, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Marshal() = %v, want %v", got, want)
+	}
+
+	testobj := &TestMapMsg{
+		A: map[int32]TSlotData{3: {3, 3, true, 3, 3}, -1: {1, 1, false, 1, 1}, 2: {}},
+		B: map[string]int16{"": 0, "宝山路": 1, "b": -2},
+		C: map[uint16]string{1: "", 0xffff: "max"},
+		D: map[string]*TSlotData{"nil": nil, "x": {6, 6, true, 6, 6}},
+		E: map[int64]interface{}{1: &TSlotData{7, 7, true, 7, 7}},
+	}
+	data, err := Marshal(testobj)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	for i := 0; i < 10; i++ { // map遍历顺序随机,序列化结果必须稳定
+		if again, _ := Marshal(testobj); !reflect.DeepEqual(again, data) {
+			t.Fatalf("Marshal() is not stable: %v != %v", again, data)
+		}
+	}
+	decoded, err := Decode[TestMapMsg](data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !reflect.DeepEqual(decoded, testobj) {
+		t.Errorf("Decode() = %+v, want %+v", decoded, testobj)
+	}
+}
+
+func TestFieldTag(t *testing.T) {
+	const ClassID_TestTag = ClassID_Test + 3
+	type TestTagMsg struct {
+		A     int32
+		Cache int32    `protocol:"-"`
+		B     int32    `protocol:"b"`
+		C     uint8    `protocol:",since=2"`
+		Skip  chan int `protocol:"-"`
+	}
+	MustRegisterDataClass(ClassID_TestTag, (*TestTagMsg)(nil))
+	rtti, _ := GetRegRttiDataByClassId(ClassID_TestTag)
+	if len(rtti.FieldData) != 3 {
+		t.Fatalf("FieldData = %+v, want 3 fields", rtti.FieldData)
+	}
+	if rtti.FieldData[0].podMergeCount > 1 || rtti.FieldData[1].podMergeCount != 2 {
+		t.Errorf("pod merge isn't broken by skipped field: %+v", rtti.FieldData)
+	}
+	if f := rtti.FieldData[1]; f.Name != "b" || f.Since != 0 {
+		t.Errorf("FieldData[1] = %+v", f)
+	}
+	if f := rtti.FieldData[2]; f.Name != "C" || f.Since != 2 {
+		t.Errorf("FieldData[2] = %+v", f)
+	}
+
+	data, err := Marshal(&TestTagMsg{A: 1, Cache: 9, B: 2, C: 3, Skip: make(chan int)})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := []byte{152, 109, 235, 3, 15, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("Marshal() = %v, want %v", data, want)
+	}
+	got, err := Decode[TestTagMsg](data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if *got != (TestTagMsg{A: 1, B: 2, C: 3}) {
+		t.Errorf("Decode() = %+v", got)
+	}
+
+	schema, _ := CurrentSchema()
+	fields := schema.Class(ClassID_TestTag).Fields
+	if len(fields) != 3 || fields[1].Name != "b" || fields[2].Since != 2 {
+		t.Errorf("schema fields = %+v", fields)
+	}
+
+	for name, msg := range map[string]IMsg{
+		"since": (*struct {
+			A int32 `protocol:",since=2"`
+			B int32 `protocol:",since=1"`
+		})(nil),
+		"option": (*struct {
+			A int32 `protocol:"a,omitempty"`
+		})(nil),
+		"name": (*struct {
+			A int32 `protocol:"a-b"`
+		})(nil),
+		"long": (*struct {
+			A int32 `protocol:",long"`
+		})(nil),
+		"max": (*struct {
+			A []int32 `protocol:",max=4"`
+		})(nil),
+	} {
+		if err := NewRegistry().Register(ClassID_Test+100, msg); err == nil {
+			t.Errorf("Register() with bad %s tag expected error", name)
+		}
+	}
+}
+
+func TestLongString(t *testing.T) {
+	const ClassID_TestLong = ClassID_Test + 6
+	type TestLongMsg struct {
+		Short string
+		Log   string            `protocol:",long"`
+		Lines []string          `protocol:",long"`
+		Docs  map[string]string `protocol:"docs,long"`
+	}
+	reg := NewRegistry()
+	for _, msg := range []struct {
+		classId uint32
+		msg     IMsg
+	}{{ClassID_TestLong, (*TestLongMsg)(nil)}, {ClassID_SlotData, (*TSlotData)(nil)}, {ClassID_MapInfo, (*TMapInfo)(nil)}} {
+		if err := reg.Register(msg.classId, msg.msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	long := strings.Repeat("x", math.MaxUint16+1)
+	msg := &TestLongMsg{Short: "a", Log: long, Lines: []string{"b", long}, Docs: map[string]string{"c": long}}
+	data, err := reg.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	// 数据头(8) + Short(2+1) 之后是 uint32 长度的 Log
+	if n := binary.LittleEndian.Uint32(data[11:]); n != uint32(len(long)) {
+		t.Errorf("Marshal() Log length = %d, want %d", n, len(long))
+	}
+	got, err := reg.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, msg) {
+		t.Errorf("Unmarshal() = %+v", got)
+	}
+	r := reg.NewProtocolReader(data)
+	r.SetLimits(DecoderLimits{MaxStringBytes: math.MaxUint16})
+	if _, err := r.readAny(); !errors.As(err, new(*ErrLimitExceeded)) {
+		t.Errorf("readAny() error = %v, want *ErrLimitExceeded", err)
+	}
+
+	w := reg.NewProtocolWritter(0)
+	if err := w.WriteLongString(long); err != nil {
+		t.Fatalf("WriteLongString() error = %v", err)
+	}
+	if s := reg.NewProtocolReader(w.Bytes()).ReadLongString(); s != long {
+		t.Errorf("ReadLongString() returned %d bytes, want %d", len(s), len(long))
+	}
+
+	// 普通字符串超长时返回错误,不截断数据
+	for _, tt := range []struct {
+		name string
+		msg  IMsg
+		path string
+	}{
+		{"reflect", &TestLongMsg{Short: long}, "TestLongMsg.Short"},
+		{"generated", &TMapInfo{Name: long}, "TMapInfo.Name"},
+	} {
+		_, err := reg.Marshal(tt.msg)
+		var encodeErr *ErrEncode
+		if !errors.Is(err, ErrStringTooLong) || !errors.As(err, &encodeErr) || encodeErr.Path != tt.path {
+			t.Errorf("%s: Marshal() error = %v, want ErrStringTooLong at %s", tt.name, err, tt.path)
+		}
+	}
+
+	schema, err := reg.Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, field := range schema.Class(ClassID_TestLong).Fields {
+		types = append(types, field.Type.String())
+	}
+	if want := []string{"string", "longstring", "[]longstring", "map[longstring]longstring"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("schema types = %v, want %v", types, want)
+	}
+	var buf bytes.Buffer
+	schema.Export(&buf)
+	if parsed, err := ParseSchema(strings.NewReader(buf.String())); err != nil || !reflect.DeepEqual(parsed, schema) {
+		t.Errorf("ParseSchema() = %v, %v, want exported schema", parsed, err)
+	}
+	// 加上 long 改变了数据格式,不兼容
+	old, _ := ParseSchema(strings.NewReader(strings.Replace(buf.String(), "Log longstring", "Log string", 1)))
+	if violations := CheckCompatibility(old, schema); len(violations) != 1 || violations[0].Field != 1 {
+		t.Errorf("CheckCompatibility() = %v, want Log changed", violations)
+	}
+}
+
+func TestBlob(t *testing.T) {
+	const ClassID_TestBlob = ClassID_Test + 7
+	type TestBlobMsg struct {
+		Image []byte `protocol:",max=8"`
+		Key   [4]byte
+		Data  []byte
+	}
+	reg := NewRegistry()
+	if err := reg.Register(ClassID_TestBlob, (*TestBlobMsg)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	msg := &TestBlobMsg{Image: []byte("png"), Key: [4]byte{1, 2, 3, 4}, Data: []byte("payload")}
+	data, err := reg.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	for _, alias := range []bool{false, true} {
+		r := reg.NewProtocolReader(data)
+		r.SetAliasBlobs(alias)
+		got := &TestBlobMsg{}
+		if err := r.ReadStruct(got); err != nil {
+			t.Fatalf("ReadStruct() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, msg) {
+			t.Errorf("ReadStruct() = %+v, want %+v", got, msg)
+		}
+		// 引用输入数据时 Data 与 data 共用内存,容量不能超出 Data 的范围
+		shared := &got.Data[0] == &data[len(data)-len(msg.Data)]
+		if shared != alias || cap(got.Data) != len(got.Data) {
+			t.Errorf("alias = %v, Data shares input = %v, cap = %d", alias, shared, cap(got.Data))
+		}
+	}
+
+	// 生成的代码使用的读写方法
+	w := reg.NewProtocolWritter(0)
+	if err := w.WriteBlob(msg.Data, 0); err != nil {
+		t.Fatal(err)
+	}
+	w.WriteBlob(msg.Key[:], 0)
+	r := reg.NewProtocolReader(w.Bytes())
+	var key [2]byte
+	if got := r.ReadBlob(0); !bytes.Equal(got, msg.Data) {
+		t.Errorf("ReadBlob() = %q, want %q", got, msg.Data)
+	}
+	if r.ReadBlobInto(key[:]); key != [2]byte{1, 2} || r.Len() != 0 || r.Error != nil {
+		t.Errorf("ReadBlobInto() = %v, remaining %d, error %v", key, r.Len(), r.Error)
+	}
+
+	_, err = reg.Marshal(&TestBlobMsg{Image: make([]byte, 9)})
+	var encodeErr *ErrEncode
+	if !errors.Is(err, ErrBlobTooLarge) || !errors.As(err, &encodeErr) || encodeErr.Path != "TestBlobMsg.Image" {
+		t.Errorf("Marshal() error = %v, want ErrBlobTooLarge at TestBlobMsg.Image", err)
+	}
+	if err := w.WriteBlob(make([]byte, 9), 8); !errors.Is(err, ErrBlobTooLarge) {
+		t.Errorf("WriteBlob() error = %v, want ErrBlobTooLarge", err)
+	}
+
+	// 没有 max 的旧版本写入的超长数据
+	old := NewRegistry()
+	old.MustRegister(ClassID_TestBlob, (*struct {
+		Image []byte
+	})(nil))
+	tooLarge, _ := old.Marshal(&struct{ Image []byte }{make([]byte, 9)})
+	tests := []struct {
+		name   string
+		data   []byte
+		limits DecoderLimits
+		limit  string
+	}{
+		{"max tag", tooLarge, DecoderLimits{}, "max tag"},
+		{"blob bytes", data, DecoderLimits{MaxBlobBytes: 4}, "blob bytes"},
+		{"slice length", data, DecoderLimits{MaxSliceLen: 1}, ""},
+	}
+	for _, tt := range tests {
+		r := reg.NewProtocolReader(tt.data)
+		r.SetLimits(tt.limits)
+		err := r.ReadStruct(&TestBlobMsg{})
+		var limitErr *ErrLimitExceeded
+		if tt.limit == "" && err != nil || tt.limit != "" && (!errors.As(err, &limitErr) || limitErr.Limit != tt.limit) {
+			t.Errorf("%s: ReadStruct() error = %v, want %q", tt.name, err, tt.limit)
+		}
+	}
+}
+
+func TestIntMode(t *testing.T) {
+	const ClassID_TestInt = ClassID_Test + 8
+	type TestIntMsg struct {
+		A int
+		B []uint
+		C [2]int
+		D map[int]uintptr
+	}
+	// 与 TestIntMsg 的数据格式相同
+	type TestInt64Msg struct {
+		A int64
+		B []uint64
+		C [2]int64
+		D map[int64]uint64
+	}
+	reg, reg64 := NewRegistry(), NewRegistry()
+	if err := reg.Register(ClassID_TestInt, (*TestIntMsg)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg64.Register(ClassID_TestInt, (*TestInt64Msg)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := reg.Marshal(&TestIntMsg{A: -1, B: []uint{1, 2}, C: [2]int{-3, 4}, D: map[int]uintptr{-5: 6}})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want, _ := reg64.Marshal(&TestInt64Msg{A: -1, B: []uint64{1, 2}, C: [2]int64{-3, 4}, D: map[int64]uint64{-5: 6}})
+	if !bytes.Equal(data, want) {
+		t.Errorf("Marshal() = %v, want %v", data, want)
+	}
+	got := &TestIntMsg{}
+	if err := reg.UnmarshalInto(want, got); err != nil || got.A != -1 || got.B[1] != 2 || got.C[0] != -3 || got.D[-5] != 6 {
+		t.Errorf("UnmarshalInto() = %+v, %v", got, err)
+	}
+
+	// 超出本机范围的值在 32 位平台上返回 ErrIntRange
+	big, _ := reg64.Marshal(&TestInt64Msg{A: 1 << 40})
+	err = reg.UnmarshalInto(big, &TestIntMsg{})
+	if nativeInt64 && err != nil || !nativeInt64 && !errors.Is(err, ErrIntRange) {
+		t.Errorf("UnmarshalInto() error = %v", err)
+	}
+	w := reg.NewProtocolWritter(0)
+	w.WriteInt(-2)
+	w.WriteUint(3)
+	r := reg.NewProtocolReader(w.Bytes())
+	if w.Len() != 16 || r.ReadInt() != -2 || r.ReadUint() != 3 || r.Error != nil {
+		t.Errorf("WriteInt/ReadInt = %v, %v", w.Bytes(), r.Error)
+	}
+
+	reject := NewRegistry()
+	reject.SetIntMode(IntReject)
+	for name, msg := range map[string]IMsg{
+		"int":   (*struct{ A int })(nil),
+		"slice": (*struct{ A []uint })(nil),
+		"map":   (*struct{ A map[string]uintptr })(nil),
+	} {
+		if err := reject.Register(ClassID_TestInt, msg); err == nil {
+			t.Errorf("Register() with %s member expected error", name)
+		}
+	}
+	if err := reject.Register(ClassID_TestInt, (*TestInt64Msg)(nil)); err != nil {
+		t.Errorf("Register() error = %v", err)
+	}
+}
+
+// withSwapBytes 强制开启字节序转换执行 f,在小端平台上模拟大端平台
+func withSwapBytes(f func()) {
+	defer func(old bool) { swapBytes = old }(swapBytes)
+	swapBytes = true
+	f()
+}
+
+func TestSwapBytes(t *testing.T) {
+	const ClassID_TestSwap = ClassID_Test + 9
+	type TestSwapMsg struct {
+		A int32
+		B uint16 // A、B 合并
+		C float64
+		D bool // C、D 合并
+		E [2]int16
+		F []uint32
+		G map[uint16]int64
+		H []byte
+	}
+	reg := NewRegistry()
+	if err := reg.Register(ClassID_TestSwap, (*TestSwapMsg)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	msg := &TestSwapMsg{A: -2, B: 0x1234, C: 1.5, D: true, E: [2]int16{1, -1}, F: []uint32{0x01020304}, G: map[uint16]int64{2: -3, 1: 4}, H: []byte{5, 6}}
+	data, err := reg.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	// 字节序转换后数值成员按大端写入,长度和数据头不变
+	var want bytes.Buffer
+	want.Write(data[:6])
+	for _, v := range []interface{}{msg.A, msg.B, msg.C, msg.D, uint32(len(msg.E)), msg.E, uint32(len(msg.F)), msg.F,
+		uint32(len(msg.G)), uint16(1), msg.G[1], uint16(2), msg.G[2], uint32(len(msg.H)), msg.H} {
+		if n, ok := v.(uint32); ok { // 长度字段始终是小端
+			binary.Write(&want, binary.LittleEndian, n)
+		} else {
+			binary.Write(&want, binary.BigEndian, v)
+		}
+	}
+	withSwapBytes(func() {
+		swapped, err := reg.Marshal(msg)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		if !bytes.Equal(swapped, want.Bytes()) {
+			t.Errorf("Marshal() = %v, want %v", swapped, want.Bytes())
+		}
+		got := &TestSwapMsg{}
+		if err := reg.UnmarshalInto(swapped, got); err != nil || !reflect.DeepEqual(got, msg) {
+			t.Errorf("UnmarshalInto() = %+v, %v", got, err)
+		}
+
+		// 反射方式编解码嵌套的协议
+		withoutGenerated(func() {
+			data, err := Marshal(newMapInfoTestObj())
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			got, err := Unmarshal(data)
+			if err != nil || !reflect.DeepEqual(got, newMapInfoTestObj()) {
+				t.Errorf("Unmarshal() = %+v, %v", got, err)
+			}
+		}, (*TMapInfo)(nil), (*TSlotData)(nil))
+	})
+}
+
+func TestNestedArray(t *testing.T) {
+	const ClassID_TestNested = ClassID_Test + 10
+	type TestNestedMsg struct {
+		Grid   [][]int32
+		Mat    [2][3]float32
+		Chunks [][]byte
+		Names  [][2]string
+		Rows   map[int32][]int16
+		Slots  [][]*TSlotData
+	}
+	// 数组长度与 TestNestedMsg 不同,数据格式兼容
+	type TestNestedMsg2 struct {
+		Grid [][2]int32
+		Mat  [][]float32
+	}
+	reg, reg2 := NewRegistry(), NewRegistry()
+	for _, r := range []*Registry{reg, reg2} {
+		if err := r.Register(ClassID_SlotData, (*TSlotData)(nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := reg.Register(ClassID_TestNested, (*TestNestedMsg)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg2.Register(ClassID_TestNested, (*TestNestedMsg2)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	msg := &TestNestedMsg{
+		Grid:   [][]int32{{1, 2, 3}, nil, {4}},
+		Mat:    [2][3]float32{{1, 2, 3}, {4, 5, 6}},
+		Chunks: [][]byte{{1, 2}, {3}},
+		Names:  [][2]string{{"a", "b"}, {"c", ""}},
+		Rows:   map[int32][]int16{2: {-1}, 1: {1, 2}},
+		Slots:  [][]*TSlotData{{{Idx: 1}, nil}, {{Idx: 2}}},
+	}
+	data, err := reg.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	// 每一层都带有元素个数
+	var want bytes.Buffer
+	binary.Write(&want, binary.LittleEndian, []uint32{3, 3, 1, 2, 3, 0, 1, 4})
+	if !bytes.HasPrefix(data[6:], want.Bytes()) {
+		t.Errorf("Marshal() Grid = %v, want %v", data[6:6+want.Len()], want.Bytes())
+	}
+	got := &TestNestedMsg{}
+	if err := reg.UnmarshalInto(data, got); err != nil || !reflect.DeepEqual(got, msg) {
+		t.Errorf("UnmarshalInto() = %+v, %v", got, err)
+	}
+
+	// 多余的元素丢弃,不足的元素为零值
+	got2 := &TestNestedMsg2{}
+	if err := reg2.UnmarshalInto(data, got2); err != nil {
+		t.Fatalf("UnmarshalInto() error = %v", err)
+	}
+	if want := [][2]int32{{1, 2}, {}, {4, 0}}; !reflect.DeepEqual(got2.Grid, want) {
+		t.Errorf("UnmarshalInto() Grid = %v, want %v", got2.Grid, want)
+	}
+	if want := [][]float32{{1, 2, 3}, {4, 5, 6}}; !reflect.DeepEqual(got2.Mat, want) {
+		t.Errorf("UnmarshalInto() Mat = %v, want %v", got2.Mat, want)
+	}
+
+	// 错误路径包含每一层的下标
+	data, _ = reg.Marshal(&TestNestedMsg{Names: [][2]string{{"a", "b"}, {"c", "long"}}})
+	r := reg.NewProtocolReader(data)
+	r.SetLimits(DecoderLimits{MaxStringBytes: 3})
+	var decodeErr *ErrDecode
+	if _, err := r.readAny(); !errors.As(err, &decodeErr) || decodeErr.Path != "TestNestedMsg.Names[1][1]" {
+		t.Errorf("readAny() error = %v, want error at TestNestedMsg.Names[1][1]", err)
+	}
+}
+
+func TestBuiltinAny(t *testing.T) {
+	const ClassID_TestBuiltin = ClassID_Test + 11
+	type TestBuiltinMsg struct {
+		J []interface{}
+		M map[string]interface{}
+	}
+	type myInt int32
+	reg := NewRegistry()
+	if err := reg.Register(ClassID_TestBuiltin, (*TestBuiltinMsg)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Register(ClassID_Int32, (*TLimitNode)(nil)); err == nil {
+		t.Errorf("Register() with builtin classId expected error")
+	}
+	msg := &TestBuiltinMsg{
+		J: []interface{}{true, int8(-1), uint8(2), int16(-3), uint16(4), int32(-5), uint32(6), int64(-7), uint64(8), -9, uint(10),
+			float32(1.5), -2.5, "宝山路", []byte{1, 2}, []bool{true}, []int8{-1}, []int16{-2}, []uint16{3}, []int32{-4, 5},
+			[]uint32{6}, []int64{-7}, []uint64{8}, []int{-9}, []uint{10}, []float32{1.5}, []float64{2.5}, []string{"a", ""}, nil},
+		M: map[string]interface{}{"hp": int32(100), "name": "boss", "pos": []float32{1, 2}},
+	}
+	data, err := reg.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	got, err := reg.Unmarshal(data)
+	if err != nil || !reflect.DeepEqual(got, msg) {
+		t.Errorf("Unmarshal() = %+v, %v", got, err)
+	}
+
+	// 数据格式: 数据头 + 值
+	data, _ = reg.Marshal("abc")
+	if want := []byte{0x98, 0x6D, byte(ClassID_String), 0, 13, 0, 3, 0, 0, 0, 'a', 'b', 'c'}; !bytes.Equal(data, want) {
+		t.Errorf("Marshal() = %v, want %v", data, want)
+	}
+	if v, err := reg.Unmarshal(data); v != "abc" || err != nil {
+		t.Errorf("Unmarshal() = %v, %v", v, err)
+	}
+	if _, err := reg.Marshal(&TestBuiltinMsg{J: []interface{}{myInt(1)}}); !errors.Is(err, ErrNotRegistered) {
+		t.Errorf("Marshal() error = %v, want ErrNotRegistered", err)
+	}
+	data[4] = 8 // 数据长度小于值的长度
+	if _, err := reg.Unmarshal(data); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Unmarshal() error = %v, want ErrTruncated", err)
+	}
+}
@@ -0,0 +1,131 @@
+package protocol
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// 内置类型的 classId, interface 成员中存放数值、字符串、[]byte 和数值、字符串切片时使用
+// 数据格式为数据头 + 值,值的格式与结构体成员相同,字符串的长度使用 uint32
+// 1 ~ MaxBuiltinClassId 保留给内置类型,注册协议时不能使用
+const (
+	ClassID_Bool    uint32 = 1
+	ClassID_Int8    uint32 = 2
+	ClassID_Uint8   uint32 = 3
+	ClassID_Int16   uint32 = 4
+	ClassID_Uint16  uint32 = 5
+	ClassID_Int32   uint32 = 6
+	ClassID_Uint32  uint32 = 7
+	ClassID_Int64   uint32 = 8
+	ClassID_Uint64  uint32 = 9
+	ClassID_Int     uint32 = 10 // 按 64 位读写
+	ClassID_Uint    uint32 = 11 // 按 64 位读写
+	ClassID_Float32 uint32 = 12
+	ClassID_Float64 uint32 = 13
+	ClassID_String  uint32 = 14
+	ClassID_Bytes   uint32 = 15 // []byte
+
+	// 切片的 classId 为元素的 classId + 32, []uint8 即 []byte
+	ClassID_BoolSlice    uint32 = 33
+	ClassID_Int8Slice    uint32 = 34
+	ClassID_Int16Slice   uint32 = 36
+	ClassID_Uint16Slice  uint32 = 37
+	ClassID_Int32Slice   uint32 = 38
+	ClassID_Uint32Slice  uint32 = 39
+	ClassID_Int64Slice   uint32 = 40
+	ClassID_Uint64Slice  uint32 = 41
+	ClassID_IntSlice     uint32 = 42
+	ClassID_UintSlice    uint32 = 43
+	ClassID_Float32Slice uint32 = 44
+	ClassID_Float64Slice uint32 = 45
+	ClassID_StringSlice  uint32 = 46
+
+	MaxBuiltinClassId uint32 = 127
+)
+
+// builtinClasses 内置类型的注册信息,只用于读写数据头,不会出现在注册表中
+var builtinClasses, builtinTypes = func() (map[uint32]*TRegRttiData, map[reflect.Type]*TRegRttiData) {
+	classes, types := make(map[uint32]*TRegRttiData), make(map[reflect.Type]*TRegRttiData)
+	for classId, v := range map[uint32]interface{}{
+		ClassID_Bool: false, ClassID_Int8: int8(0), ClassID_Uint8: uint8(0), ClassID_Int16: int16(0),
+		ClassID_Uint16: uint16(0), ClassID_Int32: int32(0), ClassID_Uint32: uint32(0), ClassID_Int64: int64(0),
+		ClassID_Uint64: uint64(0), ClassID_Int: 0, ClassID_Uint: uint(0), ClassID_Float32: float32(0),
+		ClassID_Float64: float64(0), ClassID_String: "", ClassID_Bytes: []byte(nil),
+		ClassID_BoolSlice: []bool(nil), ClassID_Int8Slice: []int8(nil), ClassID_Int16Slice: []int16(nil),
+		ClassID_Uint16Slice: []uint16(nil), ClassID_Int32Slice: []int32(nil), ClassID_Uint32Slice: []uint32(nil),
+		ClassID_Int64Slice: []int64(nil), ClassID_Uint64Slice: []uint64(nil), ClassID_IntSlice: []int(nil),
+		ClassID_UintSlice: []uint(nil), ClassID_Float32Slice: []float32(nil), ClassID_Float64Slice: []float64(nil),
+		ClassID_StringSlice: []string(nil),
+	} {
+		rtti := &TRegRttiData{ClassId: classId, rType: reflect.TypeOf(v)}
+		classes[classId] = rtti
+		types[rtti.rType] = rtti
+	}
+	return classes, types
+}()
+
+// checkClassId 注册的 classId 不能与内置类型冲突
+func checkClassId(classId uint32) error {
+	if classId >= 1 && classId <= MaxBuiltinClassId {
+		return fmt.Errorf("classId %d is reserved for builtin types (1~%d)", classId, MaxBuiltinClassId)
+	}
+	return nil
+}
+
+// writeBuiltin 写入 interface 中存放的内置类型, obj 不是内置类型时返回 false
+// 只匹配类型本身,以内置类型为基础类型的自定义类型需要注册为结构体成员
+func (b *ProtocolWritter) writeBuiltin(obj interface{}) (bool, error) {
+	rtti, ok := builtinTypes[reflect.TypeOf(obj)]
+	if !ok {
+		return false, nil
+	}
+	tp, ptr := rtti.rType, PtrOf(obj)
+	size, owner, err := b.dataHeadSize(ptr, rtti)
+	if err != nil {
+		return true, err
+	}
+	if owner {
+		defer b.clearSizes()
+	}
+	head := ProtocolDataHeaderWritter{}
+	b.WriteDataHeadSized(rtti, size, &head)
+	if tp.Kind() == reflect.Slice {
+		err = b.writeArray(tp, ptr, true)
+	} else {
+		err = b.writeElem(tp.Kind(), tp, ptr, true)
+	}
+	if err == nil {
+		err = b.EndStruct(&head)
+	}
+	if err != nil {
+		b.buf = b.buf[:head.startPos]
+	}
+	return true, err
+}
+
+// readBuiltin 读取内置类型的值,数据比值长时忽略多余的数据
+func (r *ProtocolReader) readBuiltin(head *ProtocolDataHeader, rtti *TRegRttiData) (interface{}, bool) {
+	if !r.enter(head) {
+		return nil, false
+	}
+	defer r.leave()
+	tp := rtti.rType
+	val := reflect.New(tp)
+	ptr := unsafe.Pointer(val.Pointer())
+	var ok bool
+	if tp.Kind() == reflect.Slice {
+		ok = r.readArray(tp, ptr, true)
+	} else {
+		ok = r.readElem(tp.Kind(), tp, ptr, true)
+	}
+	if !ok {
+		return nil, false
+	}
+	end := head.startPos + int(head.dataLength)
+	if r.off > end {
+		return nil, r.failAt(head, ErrTruncated)
+	}
+	r.off = end
+	return val.Elem().Interface(), true
+}
@@ -0,0 +1,340 @@
+// goprotocol-gen 生成代码使用的接口
+// 生成的 MarshalProtocol/UnmarshalProtocol 不依赖反射和 unsafe 偏移,输出与 Marshal 完全一致
+// 注册时会检查结构体指针是否实现了这两个接口,序列化和反序列化时优先使用
+package protocol
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"sort"
+	"unsafe"
+)
+
+// IProtocolMarshaler 将自身(包含数据头)写入 w
+type IProtocolMarshaler interface {
+	MarshalProtocol(w *ProtocolWritter) error
+}
+
+// IProtocolUnmarshaler 从 r 的当前位置读取一个完整的数据(包含数据头)
+type IProtocolUnmarshaler interface {
+	UnmarshalProtocol(r *ProtocolReader) error
+}
+
+var (
+	marshalerType   = reflect.TypeOf((*IProtocolMarshaler)(nil)).Elem()
+	unmarshalerType = reflect.TypeOf((*IProtocolUnmarshaler)(nil)).Elem()
+)
+
+// implementsDirectly 结构体指针是否实现了 iface
+// 匿名成员实现的方法会被提升到外层结构体,外层结构体自己没有声明这些方法时需要使用反射方式
+func implementsDirectly(tp reflect.Type, iface reflect.Type) bool {
+	ptp := reflect.PtrTo(tp)
+	if !ptp.Implements(iface) {
+		return false
+	}
+	for i := 0; i < tp.NumField(); i++ {
+		if fd := tp.Field(i); fd.Anonymous {
+			ft := fd.Type
+			if ft.Kind() != reflect.Ptr {
+				ft = reflect.PtrTo(ft)
+			}
+			if ft.Implements(iface) {
+				return declaresMethods(ptp, iface)
+			}
+		}
+	}
+	return true
+}
+
+// declaresMethods ptp 是否自己声明了 iface 的所有方法
+// 提升的方法是编译器生成的包装函数,源文件为 <autogenerated>
+func declaresMethods(ptp reflect.Type, iface reflect.Type) bool {
+	for i := 0; i < iface.NumMethod(); i++ {
+		m, _ := ptp.MethodByName(iface.Method(i).Name)
+		pc := m.Func.Pointer()
+		fn := runtime.FuncForPC(pc)
+		if fn == nil {
+			return false
+		}
+		if file, _ := fn.FileLine(pc); file == "<autogenerated>" {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *ProtocolWritter) WriteBool(v bool) {
+	if v {
+		b.WriteByte(1)
+	} else {
+		b.WriteByte(0)
+	}
+}
+func (b *ProtocolWritter) WriteInt8(v int8)   { b.WriteByte(byte(v)) }
+func (b *ProtocolWritter) WriteUint8(v uint8) { b.WriteByte(v) }
+func (b *ProtocolWritter) WriteInt16(v int16) { b.WriteUint16(uint16(v)) }
+func (b *ProtocolWritter) WriteInt32(v int32) { b.WriteUint32(uint32(v)) }
+func (b *ProtocolWritter) WriteInt64(v int64) { b.WriteUint64(uint64(v)) }
+func (b *ProtocolWritter) WriteUint64(v uint64) {
+	m, ok := b.tryGrowByReslice(8)
+	if !ok {
+		m = b.grow(8)
+	}
+	binary.LittleEndian.PutUint64(b.buf[m:], v)
+}
+func (b *ProtocolWritter) WriteFloat32(v float32) { b.WriteUint32(math.Float32bits(v)) }
+func (b *ProtocolWritter) WriteFloat64(v float64) { b.WriteUint64(math.Float64bits(v)) }
+
+// WriteInt int、uint、uintptr 固定按 64 位写入,与平台无关
+func (b *ProtocolWritter) WriteInt(v int)         { b.WriteInt64(int64(v)) }
+func (b *ProtocolWritter) WriteUint(v uint)       { b.WriteUint64(uint64(v)) }
+func (b *ProtocolWritter) WriteUintptr(v uintptr) { b.WriteUint64(uint64(v)) }
+func (b *ProtocolWritter) WriteArrayLen(n int)    { b.WriteUint32(uint32(n)) }
+
+// WriteBlob 写入 []byte 或 [N]byte 成员: 长度(uint32) + 内容
+// max 为 tag 中的 max, 大于 0 时超过 max 个字节返回 ErrBlobTooLarge
+func (b *ProtocolWritter) WriteBlob(p []byte, max int) error {
+	if max > 0 && len(p) > max {
+		return fmt.Errorf("%w: %d bytes > max=%d", ErrBlobTooLarge, len(p), max)
+	}
+	b.WriteUint32(uint32(len(p)))
+	b.Write(p)
+	return nil
+}
+
+// ReadNil 如果当前位置是 nil 指针写入的空数据头,则跳过它并返回 true
+func (r *ProtocolReader) ReadNil() bool {
+	if r.Error != nil {
+		return true
+	}
+	var head ProtocolDataHeader
+	startPos := r.off
+	r.ReadDataHead(&head)
+	if head.isValid && head.dataLength == uint32(head.headerLength) {
+		return true
+	}
+	r.off = startPos
+	return false
+}
+
+// BeginStruct 读取 msg 的数据头,数据头中的 classId 必须与 msg 注册的 classId 一致
+// 空数据头(nil 指针写入的数据)与反射方式一样解析为零值,成员由指针改为结构体后可以读取旧数据
+// 读取完成员后需要调用 EndStruct 跳到数据末尾
+func (r *ProtocolReader) BeginStruct(msg IMsg) (head ProtocolDataHeader) {
+	if r.Error != nil {
+		return
+	}
+	rttiData, ok := r.reg.FromObj(msg)
+	if !ok {
+		r.fail(ErrNotRegistered)
+		return
+	}
+	if !r.readFullHead(&head) {
+		return
+	}
+	if head.classId != rttiData.ClassId && head.dataLength != uint32(head.headerLength) {
+		if _, ok := r.reg.ByClassId(head.classId); !ok {
+			r.failAt(&head, &ErrUnknownClass{ClassId: head.classId})
+		} else {
+			r.failAt(&head, &ErrTypeMismatch{ClassId: head.classId, Want: rttiData.rType})
+		}
+		return
+	}
+	r.enter(&head)
+	return
+}
+
+// More 返回 BeginStruct 读取的结构体中是否还有未读取的成员
+// 旧版本的数据成员较少,读取新增的成员前需要检查
+func (r *ProtocolReader) More(head *ProtocolDataHeader) bool {
+	return r.Error == nil && r.off < head.startPos+int(head.dataLength)
+}
+
+// EndStruct 跳过结构体中未读取的数据(新版本追加的成员),返回读取过程中的错误
+func (r *ProtocolReader) EndStruct(head *ProtocolDataHeader) error {
+	if r.Error == nil {
+		r.off = head.startPos + int(head.dataLength)
+		r.leave()
+	}
+	return r.Error
+}
+
+// ReadStruct 读取一个结构体到 v 中,v 必须是已注册结构体的指针
+func (r *ProtocolReader) ReadStruct(v IMsg) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	rttiData, ok := r.reg.FromObj(v)
+	if !ok {
+		r.fail(ErrNotRegistered)
+	} else if rttiData.unmarshaler {
+		return v.(IProtocolUnmarshaler).UnmarshalProtocol(r)
+	} else if !r.readStruct(rttiData.rType, PtrOf(v)) {
+		r.Error = r.parseError()
+	}
+	return r.Error
+}
+
+// ReadAny 读取一个已注册的对象,用于 interface 成员
+func (r *ProtocolReader) ReadAny() interface{} {
+	if r.Error != nil {
+		return nil
+	}
+	obj, err := r.readAny()
+	if err != nil {
+		r.Error = err
+	}
+	return obj
+}
+
+// next 返回接下来的 n 个字节,数据不足时设置 r.Error 并返回 nil
+func (r *ProtocolReader) next(n int) []byte {
+	if r.Error != nil {
+		return nil
+	}
+	if r.Len() < n {
+		r.fail(ErrTruncated)
+		return nil
+	}
+	b := r.buf[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *ProtocolReader) ReadUint8() uint8 {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+func (r *ProtocolReader) ReadUint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+func (r *ProtocolReader) ReadUint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+func (r *ProtocolReader) ReadUint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+func (r *ProtocolReader) ReadBool() bool       { return r.ReadUint8() != 0 }
+func (r *ProtocolReader) ReadInt8() int8       { return int8(r.ReadUint8()) }
+func (r *ProtocolReader) ReadInt16() int16     { return int16(r.ReadUint16()) }
+func (r *ProtocolReader) ReadInt32() int32     { return int32(r.ReadUint32()) }
+func (r *ProtocolReader) ReadInt64() int64     { return int64(r.ReadUint64()) }
+func (r *ProtocolReader) ReadFloat32() float32 { return math.Float32frombits(r.ReadUint32()) }
+func (r *ProtocolReader) ReadFloat64() float64 { return math.Float64frombits(r.ReadUint64()) }
+
+// ReadInt int、uint、uintptr 固定按 64 位读取,超出本机范围时设置 r.Error 为 ErrIntRange
+func (r *ProtocolReader) ReadInt() (v int) {
+	if r.Error == nil {
+		r.readVarInt(reflect.Int, unsafe.Pointer(&v))
+	}
+	return
+}
+func (r *ProtocolReader) ReadUint() (v uint) {
+	if r.Error == nil {
+		r.readVarInt(reflect.Uint, unsafe.Pointer(&v))
+	}
+	return
+}
+func (r *ProtocolReader) ReadUintptr() (v uintptr) {
+	if r.Error == nil {
+		r.readVarInt(reflect.Uintptr, unsafe.Pointer(&v))
+	}
+	return
+}
+
+// ReadString 读取字符串: 长度(uint16) + utf8内容
+func (r *ProtocolReader) ReadString() string {
+	return r.readStringData(int(r.ReadUint16()))
+}
+
+// ReadLongString 读取长字符串: 长度(uint32) + utf8内容,用于带 long tag 的成员
+func (r *ProtocolReader) ReadLongString() string {
+	return r.readStringData(lenInt(r.ReadUint32()))
+}
+
+func (r *ProtocolReader) readStringData(l int) string {
+	if r.Error != nil || !r.checkLimit("string bytes", l, r.limits.MaxStringBytes) {
+		return ""
+	}
+	b := r.next(l)
+	if r.aliasStrings {
+		return aliasString(b)
+	}
+	return string(b)
+}
+
+// ReadBlob 读取 []byte 成员: 长度(uint32) + 内容, max 为 tag 中的 max, 0 表示不限制
+// 开启 SetAliasBlobs 时返回的切片引用输入数据
+func (r *ProtocolReader) ReadBlob(max int) []byte {
+	n := r.ReadUint32()
+	if r.Error != nil || n == 0 || !r.checkBlobLen(n, max) {
+		return nil
+	}
+	b := r.next(int(n))
+	if r.aliasBlobs {
+		return b[:n:n]
+	}
+	return append([]byte(nil), b...)
+}
+
+// ReadBlobInto 读取 [N]byte 成员,数据比 dst 长时丢弃多余的部分
+func (r *ProtocolReader) ReadBlobInto(dst []byte) {
+	n := r.ReadUint32()
+	if r.Error != nil || !r.checkBlobLen(n, 0) {
+		return
+	}
+	copy(dst, r.next(int(n)))
+}
+
+// ReadArrayLen 读取数组、切片或map的元素个数, minSize 为每个元素编码后最少占用的字节数
+// 元素个数乘以 minSize 超过剩余数据长度或元素个数超过 DecoderLimits.MaxSliceLen 时视为数据错误,避免分配过大的内存
+// 生成的代码按元素类型传入 minSize,与反射方式的检查一致
+func (r *ProtocolReader) ReadArrayLen(minSize int) int {
+	n := r.ReadUint32()
+	if r.Error != nil || !r.checkArrayLen(n, minSize) {
+		return 0
+	}
+	return int(n)
+}
+
+// TMapEntry map 中的一个元素
+type TMapEntry[K comparable, V any] struct {
+	Key K
+	Val V
+}
+
+// SortedMapEntries 返回按 key 升序排列的 map 元素,与反射方式的写入顺序一致
+func SortedMapEntries[K cmp.Ordered, V any](m map[K]V) []TMapEntry[K, V] {
+	entries := make([]TMapEntry[K, V], 0, len(m))
+	for k, v := range m {
+		entries = append(entries, TMapEntry[K, V]{k, v})
+	}
+	sort.Slice(entries, func(i, j int) bool { return cmp.Less(entries[i].Key, entries[j].Key) })
+	return entries
+}
+
+// SortedBoolMapEntries 返回按 false、true 排列的 bool 为 key 的 map 元素,与反射方式的写入顺序一致
+func SortedBoolMapEntries[K ~bool, V any](m map[K]V) []TMapEntry[K, V] {
+	entries := make([]TMapEntry[K, V], 0, len(m))
+	for _, k := range [2]K{false, true} {
+		if v, ok := m[k]; ok {
+			entries = append(entries, TMapEntry[K, V]{k, v})
+		}
+	}
+	return entries
+}
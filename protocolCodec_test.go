@@ -0,0 +1,160 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// withoutGenerated 关闭默认注册表中 goprotocol-gen 生成的代码,使用反射方式执行 f
+func withoutGenerated(f func(), msgs ...IMsg) {
+	WithoutGenerated(defaultRegistry, f, msgs...)
+}
+
+func TestGeneratedCode(t *testing.T) {
+	testobj := newMapInfoTestObj()
+	gen, err := Marshal(testobj)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var refl []byte
+	var reflObj interface{}
+	withoutGenerated(func() {
+		refl, err = Marshal(testobj)
+		reflObj, _ = Unmarshal(gen)
+	}, (*TMapInfo)(nil), (*TSlotData)(nil))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(gen, refl) || !reflect.DeepEqual(gen, mapInfoTestData) {
+		t.Errorf("generated Marshal() = %v, reflect = %v", gen, refl)
+	}
+	genObj, err := Unmarshal(refl)
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(genObj, reflObj) || !reflect.DeepEqual(genObj, testobj) {
+		t.Errorf("generated Unmarshal() = %+v, reflect = %+v", genObj, reflObj)
+	}
+}
+
+func TestGeneratedCodeVersion(t *testing.T) {
+	// 旧版本只有 Idx 和 SlotType
+	old := []byte{153, 109, 75, 66, 15, 0, 14, 0, 6, 0, 0, 0, 2, 0}
+	got, err := Decode[TSlotData](old)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if want := (&TSlotData{Idx: 6, SlotType: 2}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %+v, want %+v", got, want)
+	}
+	// 新版本追加了一个 int32 成员,后面紧跟另一条消息
+	newer := []byte{153, 109, 75, 66, 15, 0, 27, 0, 6, 0, 0, 0, 2, 0, 1, 7, 0, 0, 0, 8, 0, 0, 0, 9, 0, 0, 0}
+	r := NewProtocolReader(append(newer, old...))
+	var slot TSlotData
+	if err := r.ReadStruct(&slot); err != nil {
+		t.Fatalf("ReadStruct() error = %v", err)
+	}
+	if want := (TSlotData{6, 2, true, 7, 8}); slot != want {
+		t.Errorf("ReadStruct() = %+v, want %+v", slot, want)
+	}
+	if err := r.ReadStruct(&slot); err != nil || slot != (TSlotData{Idx: 6, SlotType: 2}) {
+		t.Errorf("ReadStruct() = %+v, %v", slot, err)
+	}
+}
+
+// TestGeneratedCodeClassId 结构体成员的数据头中 classId 不对时,生成的代码与反射方式返回相同的错误,空数据头都解析为零值
+func TestGeneratedCodeClassId(t *testing.T) {
+	slot, _ := Marshal(&TSlotData{Idx: 1})
+	tests := []struct {
+		name    string
+		data    []byte
+		errType interface{}
+	}{
+		{"empty header", []byte{0x98, 0x6D, 0, 0, 6, 0}, nil},
+		{"other class", slot, new(*ErrTypeMismatch)},
+	}
+	for _, tt := range tests {
+		for _, generated := range []bool{true, false} {
+			var err error
+			info := newMapInfoTestObj()
+			decode := func() { err = NewProtocolReader(tt.data).ReadStruct(info) }
+			if generated {
+				decode()
+			} else {
+				withoutGenerated(decode, (*TMapInfo)(nil))
+			}
+			if tt.errType == nil {
+				if err != nil || !reflect.DeepEqual(info, &TMapInfo{}) {
+					t.Errorf("%s: generated = %v: ReadStruct() = %+v, %v, want zero value", tt.name, generated, info, err)
+				}
+			} else if !errors.As(err, tt.errType) {
+				t.Errorf("%s: generated = %v: ReadStruct() error = %v, want %s", tt.name, generated, err, reflect.TypeOf(tt.errType).Elem().Elem())
+			}
+		}
+	}
+}
+
+// TestReadArrayLen 元素个数按每个元素最少占用的字节数检查,与反射方式一致
+func TestReadArrayLen(t *testing.T) {
+	// 元素个数 3,后面只有 12 个字节
+	data := []byte{3, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	for _, tt := range []struct {
+		minSize int
+		want    int
+	}{{1, 3}, {4, 3}, {6, 0}, {8, 0}} {
+		r := NewProtocolReader(data)
+		if n := r.ReadArrayLen(tt.minSize); n != tt.want || (n == 0) != errors.Is(r.Error, ErrTruncated) {
+			t.Errorf("ReadArrayLen(%d) = %d, %v, want %d", tt.minSize, n, r.Error, tt.want)
+		}
+	}
+}
+
+// TestSortedBoolMapEntries 生成的代码写入 bool 为 key 的 map 时与反射方式的顺序一致
+func TestSortedBoolMapEntries(t *testing.T) {
+	const ClassID_TestBoolMap = ClassID_Test + 15
+	type TestBoolMapMsg struct {
+		M map[bool]int32
+	}
+	reg := NewRegistry()
+	if err := reg.Register(ClassID_TestBoolMap, (*TestBoolMapMsg)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []map[bool]int32{{true: 1, false: 2}, {true: 3}, {}} {
+		data, err := reg.Marshal(&TestBoolMapMsg{M: m})
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		w := AcquireWriter()
+		w.WriteArrayLen(len(m))
+		for _, e := range SortedBoolMapEntries(m) {
+			w.WriteBool(e.Key)
+			w.WriteInt32(e.Val)
+		}
+		if !bytes.HasSuffix(data, w.Bytes()) {
+			t.Errorf("SortedBoolMapEntries(%v) wrote % x, want suffix of % x", m, w.Bytes(), data)
+		}
+		ReleaseWriter(w)
+	}
+}
+
+func TestImplementsDirectly(t *testing.T) {
+	type TEmbedded struct {
+		TSlotData
+		N int32
+	}
+	tests := []struct {
+		tp   reflect.Type
+		want bool
+	}{
+		{reflect.TypeOf(TSlotData{}), true},
+		{reflect.TypeOf(TEmbedded{}), false}, // 提升的 MarshalProtocol 只写入 TSlotData
+		{reflect.TypeOf(struct{ *TMapInfo }{}), false},
+	}
+	for _, tt := range tests {
+		if got := implementsDirectly(tt.tp, marshalerType); got != tt.want {
+			t.Errorf("implementsDirectly(%s) = %v, want %v", tt.tp, got, tt.want)
+		}
+	}
+}
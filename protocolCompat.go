@@ -0,0 +1,127 @@
+package protocol
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// TCompatViolation 协议结构中不兼容的修改
+type TCompatViolation struct {
+	ClassId uint32
+	Class   string
+	Field   int    // 成员序号,删除整个协议时为 -1
+	Name    string // 成员名,修改成员时为旧版本中的名字
+	Reason  string
+}
+
+func (v TCompatViolation) String() string {
+	if v.Field < 0 {
+		return fmt.Sprintf("class %s(%d): %s", v.Class, v.ClassId, v.Reason)
+	}
+	return fmt.Sprintf("class %s(%d) field %d %s: %s", v.Class, v.ClassId, v.Field, v.Name, v.Reason)
+}
+
+// CheckCompatibility 检查 cur 相对 old 的修改是否符合协议的演进规则:
+// 不允许删除协议和成员,只能在最后追加成员,追加成员的 since 不能小于原有成员,成员类型不能修改,
+// 允许数组改为切片和增大数组长度,允许修改成员名字,但私有成员和公有成员不能互相转换,
+// 编码相同的类型可以互相修改: 结构体与指向同一个协议的指针, int 与 int64, uint、uintptr 与 uint64
+func CheckCompatibility(old, cur *TSchema) []TCompatViolation {
+	var violations []TCompatViolation
+	for _, oc := range old.Classes {
+		nc := cur.Class(oc.ClassId)
+		if nc == nil {
+			violations = append(violations, TCompatViolation{ClassId: oc.ClassId, Class: oc.Name, Field: -1, Reason: "class removed"})
+			continue
+		}
+		for i, of := range oc.Fields {
+			v := TCompatViolation{ClassId: oc.ClassId, Class: oc.Name, Field: i, Name: of.Name}
+			if i >= len(nc.Fields) {
+				v.Reason = "field removed"
+				violations = append(violations, v)
+				continue
+			}
+			nf := nc.Fields[i]
+			if ok, lossy := compatType(old, cur, of.Type, nf.Type); !ok || lossy {
+				v.Reason = fmt.Sprintf("type changed from %s to %s", of.Type, nf.Type)
+				if ok {
+					v.Reason += ", data will be lost"
+				}
+				violations = append(violations, v)
+			} else if of.Exported != nf.Exported {
+				v.Reason = "private and public members can't be swapped"
+				if nf.Name != of.Name {
+					v.Reason = fmt.Sprintf("renamed to %s, %s", nf.Name, v.Reason)
+				}
+				violations = append(violations, v)
+			}
+		}
+		if len(oc.Fields) == 0 {
+			continue
+		}
+		last := oc.Fields[len(oc.Fields)-1].Since
+		for i := len(oc.Fields); i < len(nc.Fields); i++ {
+			if nf := nc.Fields[i]; nf.Since < last {
+				violations = append(violations, TCompatViolation{ClassId: oc.ClassId, Class: oc.Name, Field: i, Name: nf.Name,
+					Reason: fmt.Sprintf("appended with since=%d, less than since=%d of the last old field", nf.Since, last)})
+			}
+		}
+	}
+	return violations
+}
+
+// CheckRegistryCompatibility 检查当前注册的协议相对 old 的修改
+func CheckRegistryCompatibility(old *TSchema) ([]TCompatViolation, error) {
+	return defaultRegistry.CheckCompatibility(old)
+}
+
+// CheckCompatibility 检查 reg 中的协议相对 old 的修改
+func (reg *Registry) CheckCompatibility(old *TSchema) ([]TCompatViolation, error) {
+	cur, err := reg.Schema()
+	if err != nil {
+		return nil, err
+	}
+	return CheckCompatibility(old, cur), nil
+}
+
+// wireKind 编码相同的类型返回同一个 Kind: int、uint、uintptr 固定按 64 位读写,结构体和指针都写入数据头和成员
+func wireKind(kind reflect.Kind) reflect.Kind {
+	switch kind {
+	case reflect.Int:
+		return reflect.Int64
+	case reflect.Uint, reflect.Uintptr:
+		return reflect.Uint64
+	case reflect.Ptr:
+		return reflect.Struct
+	}
+	return kind
+}
+
+// compatType 检查类型的修改是否兼容, lossy 表示可以解析但会丢失数据
+func compatType(old, cur *TSchema, ot, nt *TSchemaType) (ok, lossy bool) {
+	if ot.Kind == reflect.Array && nt.Kind == reflect.Slice {
+		return compatType(old, cur, ot.Elem, nt.Elem)
+	}
+	if ot.Kind == reflect.Slice && nt.Kind == reflect.Array {
+		ok, _ = compatType(old, cur, ot.Elem, nt.Elem)
+		return ok, true
+	}
+	if wireKind(ot.Kind) != wireKind(nt.Kind) || ot.Long != nt.Long { // 长字符串与普通字符串的长度字段不同
+		return false, false
+	}
+	switch wireKind(ot.Kind) {
+	case reflect.Struct:
+		oc, nc := old.ClassByName(ot.Class), cur.ClassByName(nt.Class)
+		return oc != nil && nc != nil && oc.ClassId == nc.ClassId, false
+	case reflect.Array:
+		ok, lossy = compatType(old, cur, ot.Elem, nt.Elem)
+		return ok, lossy || nt.Len < ot.Len
+	case reflect.Slice:
+		return compatType(old, cur, ot.Elem, nt.Elem)
+	case reflect.Map:
+		if ok, _ := compatType(old, cur, ot.Key, nt.Key); !ok {
+			return false, false
+		}
+		return compatType(old, cur, ot.Elem, nt.Elem)
+	}
+	return true, false
+}
@@ -0,0 +1,116 @@
+package protocol
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckRegistryCompatibility(t *testing.T) {
+	old, err := ParseSchema(strings.NewReader(`class TSlotData = 1000011 {
+	Idx int32
+	SlotType uint16
+}
+
+class TMapInfo = 1000012 {
+	Idx int32
+	Name string
+	RefreshPoint uint16
+	SlotList [2]TSlotData
+	MaxCount int32
+	MaxLineUpCount int32
+	MaxClerk int32
+	MaxCook int32
+	CookExp int32
+	OrderExp int32
+	DeliveryExp int32
+	PointRefreshTime int32
+	Removed bool
+}
+`))
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	violations, err := CheckRegistryCompatibility(old)
+	if err != nil {
+		t.Fatalf("CheckRegistryCompatibility() error = %v", err)
+	}
+	if len(violations) != 1 || violations[0].ClassId != ClassID_MapInfo || violations[0].Field != 12 || violations[0].Reason != "field removed" {
+		t.Errorf("CheckRegistryCompatibility() = %v", violations)
+	}
+
+	cur, _ := CurrentSchema()
+	if violations := CheckCompatibility(cur, cur); len(violations) != 0 {
+		t.Errorf("CheckCompatibility() = %v, want none", violations)
+	}
+}
+
+// TestCheckCompatibilityExported 私有和公有按 Go 的成员判断,与 protocol tag 指定的成员名无关
+func TestCheckCompatibilityExported(t *testing.T) {
+	const ClassID_TestExported = ClassID_Test + 16
+	type TestExportedMsg struct {
+		B int32 `protocol:"b"` // 改名,仍然是公有成员
+		C int32 `protocol:"c"` // 私有成员改为公有成员,名字没有变
+	}
+	old, err := ParseSchema(strings.NewReader("class TestExportedMsg = 1016 {\n\tB int32\n\tc int32 private\n}\n"))
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	reg := NewRegistry()
+	if err := reg.Register(ClassID_TestExported, (*TestExportedMsg)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	violations, err := reg.CheckCompatibility(old)
+	if err != nil {
+		t.Fatalf("CheckCompatibility() error = %v", err)
+	}
+	if len(violations) != 1 || violations[0].Field != 1 || violations[0].Reason != "private and public members can't be swapped" {
+		t.Errorf("CheckCompatibility() = %v, want field 1 swapped", violations)
+	}
+}
+
+// TestCheckCompatibilityWireType 编码相同的类型修改是兼容的
+func TestCheckCompatibilityWireType(t *testing.T) {
+	old, err := ParseSchema(strings.NewReader(`class TSlotData = 1000011 {
+	Idx int32
+}
+
+class T = 1 {
+	A int
+	B []uint64
+	C uintptr
+	D TSlotData
+	E [2]*TSlotData
+	F map[int]TSlotData
+	G int
+	H *TSlotData
+}
+`))
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	cur, err := ParseSchema(strings.NewReader(`class TSlotData = 1000011 {
+	Idx int32
+}
+
+class T = 1 {
+	A int64
+	B []uint
+	C uint64
+	D *TSlotData
+	E [2]TSlotData
+	F map[int64]*TSlotData
+	G uint64
+	H TMapInfo
+}
+
+class TMapInfo = 1000012 {
+}
+`))
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	violations := CheckCompatibility(old, cur)
+	if len(violations) != 2 || violations[0].Name != "G" || violations[1].Name != "H" {
+		t.Errorf("CheckCompatibility() = %v, want G and H changed", violations)
+	}
+}
@@ -0,0 +1,103 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// Dispatcher 按数据头中的 classId 把消息分发给 Handle 注册的处理函数,代替解码后的 switch msg.(type)
+// 可以并发使用,处理函数在调用 Dispatch 的 goroutine 中执行
+type Dispatcher struct {
+	reg      *Registry
+	mu       sync.RWMutex
+	handlers map[uint32]func(ctx context.Context, r *ProtocolReader) error
+	fallback func(ctx context.Context, classId uint32, data []byte) error
+}
+
+// NewDispatcher 创建使用默认注册表的 Dispatcher
+func NewDispatcher() *Dispatcher {
+	return defaultRegistry.NewDispatcher()
+}
+
+// NewDispatcher 创建只分发 reg 中注册的协议的 Dispatcher
+func (reg *Registry) NewDispatcher() *Dispatcher {
+	return &Dispatcher{reg: reg, handlers: make(map[uint32]func(ctx context.Context, r *ProtocolReader) error)}
+}
+
+// Handle 注册处理 T 类型消息的函数, T 必须是 d 的注册表中已注册的结构体
+// 每个 classId 只能注册一个处理函数,重复注册时返回错误
+func Handle[T any](d *Dispatcher, h func(ctx context.Context, msg *T) error) error {
+	rtti, ok := d.reg.FromType(reflect.TypeOf((*T)(nil)))
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotRegistered, reflect.TypeOf((*T)(nil)).Elem())
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.handlers[rtti.ClassId]; ok {
+		return fmt.Errorf("handler for %s (classId %d) is already registered", rtti.rType, rtti.ClassId)
+	}
+	d.handlers[rtti.ClassId] = func(ctx context.Context, r *ProtocolReader) error {
+		msg := new(T)
+		if err := r.readInto(msg); err != nil {
+			return err
+		}
+		return h(ctx, msg)
+	}
+	return nil
+}
+
+// HandleFallback 设置没有处理函数的 classId(包括未注册的)的处理函数, data 为完整的消息数据
+// 没有设置时 Dispatch 返回 *ErrNoHandler
+func (d *Dispatcher) HandleFallback(h func(ctx context.Context, classId uint32, data []byte) error) {
+	d.mu.Lock()
+	d.fallback = h
+	d.mu.Unlock()
+}
+
+// Dispatch 读取 data 的数据头,按其中的 classId 解码后调用对应的处理函数,返回解码或处理函数的错误
+// ctx 已经取消时不解码,直接返回 ctx.Err()
+func (d *Dispatcher) Dispatch(ctx context.Context, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r := d.reg.AcquireReader(data)
+	defer ReleaseReader(r)
+	var head ProtocolDataHeader
+	if !r.readFullHead(&head) {
+		return r.Error
+	}
+	d.mu.RLock()
+	h, ok := d.handlers[head.classId]
+	fallback := d.fallback
+	d.mu.RUnlock()
+	if !ok {
+		if fallback == nil {
+			return &ErrNoHandler{ClassId: head.classId}
+		}
+		return fallback(ctx, head.classId, data)
+	}
+	r.off = head.startPos // 处理函数重新读取数据头
+	return h(ctx, r)
+}
+
+// Serve 从 dec 中逐条读取消息并分发,流结束时返回 nil, ctx 取消、读取失败或处理函数出错时返回错误
+// dec 应使用与 d 相同的注册表
+func (d *Dispatcher) Serve(ctx context.Context, dec *Decoder) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		frame, err := dec.ReadFrame()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := d.Dispatch(ctx, frame); err != nil {
+			return err
+		}
+	}
+}
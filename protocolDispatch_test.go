@@ -0,0 +1,106 @@
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDispatcher(t *testing.T) {
+	type ctxKey struct{}
+	type unregistered struct{ A int32 }
+	reg := NewRegistry()
+	reg.MustRegister(ClassID_SlotData, (*TSlotData)(nil))
+	reg.MustRegister(ClassID_MapInfo, (*TMapInfo)(nil))
+	reg.MustRegister(ClassID_CodeGenTest, (*TCodeGenTest)(nil))
+	d := reg.NewDispatcher()
+	var got []interface{}
+	errStop := errors.New("stop")
+	if err := Handle(d, func(ctx context.Context, msg *TSlotData) error {
+		got = append(got, msg, ctx.Value(ctxKey{}))
+		if msg.Idx < 0 {
+			return errStop
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if err := Handle(d, func(ctx context.Context, msg *TMapInfo) error {
+		got = append(got, msg)
+		return nil
+	}); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if err := Handle(d, func(context.Context, *TSlotData) error { return nil }); err == nil {
+		t.Errorf("Handle() expected error for duplicate handler")
+	}
+	if err := Handle(d, func(context.Context, *unregistered) error { return nil }); !errors.Is(err, ErrNotRegistered) {
+		t.Errorf("Handle() error = %v, want ErrNotRegistered", err)
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "v")
+	slot, _ := reg.Marshal(&TSlotData{Idx: 1})
+	if err := d.Dispatch(ctx, slot); err != nil || !reflect.DeepEqual(got, []interface{}{&TSlotData{Idx: 1}, "v"}) {
+		t.Errorf("Dispatch() = %v, handled %v", err, got)
+	}
+	got = nil
+	if err := d.Dispatch(ctx, mapInfoTestData); err != nil || !reflect.DeepEqual(got, []interface{}{newMapInfoTestObj()}) {
+		t.Errorf("Dispatch() = %v, handled %v", err, got)
+	}
+
+	// 没有处理函数的 classId
+	codeGen, _ := reg.Marshal(newCodeGenTestObj())
+	var noHandler *ErrNoHandler
+	if err := d.Dispatch(ctx, codeGen); !errors.As(err, &noHandler) || noHandler.ClassId != ClassID_CodeGenTest {
+		t.Errorf("Dispatch() error = %v, want *ErrNoHandler", err)
+	}
+	other := NewRegistry()
+	other.MustRegister(0xFFFE, (*TSlotData)(nil))
+	unknown, _ := other.Marshal(&TSlotData{}) // 没有注册的 classId
+	var fallback []uint32
+	d.HandleFallback(func(ctx context.Context, classId uint32, data []byte) error {
+		fallback = append(fallback, classId)
+		return nil
+	})
+	for _, data := range [][]byte{codeGen, unknown} {
+		if err := d.Dispatch(ctx, data); err != nil {
+			t.Errorf("Dispatch() error = %v", err)
+		}
+	}
+	if want := []uint32{ClassID_CodeGenTest, 0xFFFE}; !reflect.DeepEqual(fallback, want) {
+		t.Errorf("fallback = %v, want %v", fallback, want)
+	}
+
+	got = nil
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := d.Dispatch(canceled, slot); !errors.Is(err, context.Canceled) || got != nil {
+		t.Errorf("Dispatch() = %v, handled %v, want context.Canceled", err, got)
+	}
+	if err := d.Dispatch(ctx, slot[:len(slot)-1]); !errors.Is(err, ErrTruncated) || got != nil {
+		t.Errorf("Dispatch() = %v, handled %v, want ErrTruncated", err, got)
+	}
+	bad, _ := reg.Marshal(&TSlotData{Idx: -1})
+	if err := d.Dispatch(ctx, bad); !errors.Is(err, errStop) {
+		t.Errorf("Dispatch() error = %v, want handler error", err)
+	}
+
+	// 从流中逐条分发
+	var stream bytes.Buffer
+	enc := reg.NewEncoder(&stream)
+	for _, msg := range []IMsg{&TSlotData{Idx: 2}, newMapInfoTestObj(), &TSlotData{Idx: -1}, &TSlotData{Idx: 3}} {
+		enc.Encode(msg)
+	}
+	got = nil
+	if err := d.Serve(ctx, reg.NewDecoder(&stream)); !errors.Is(err, errStop) || len(got) != 5 {
+		t.Errorf("Serve() = %v, handled %v, want handler error after 3 messages", err, got)
+	}
+	if err := d.Serve(ctx, reg.NewDecoder(&stream)); err != nil || len(got) != 7 {
+		t.Errorf("Serve() = %v, handled %v, want nil at end of stream", err, got)
+	}
+	if err := d.Serve(canceled, reg.NewDecoder(bytes.NewReader(slot))); !errors.Is(err, context.Canceled) {
+		t.Errorf("Serve() error = %v, want context.Canceled", err)
+	}
+}
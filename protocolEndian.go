@@ -0,0 +1,73 @@
+package protocol
+
+import "unsafe"
+
+// swapBytes 本机是大端时为 true, 数值类型按内存读写后需要逐个转换字节序, 数据中始终是小端
+// 测试中可以强制开启,在小端平台上验证转换逻辑
+var swapBytes = isBigEndian()
+
+// isBigEndian 检测本机字节序
+func isBigEndian() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 0
+}
+
+// swapElems 反转 p 中每 size 个字节的顺序,末尾不足 size 的部分不处理
+func swapElems(p []byte, size int) {
+	if size <= 1 {
+		return
+	}
+	for i := 0; i+size <= len(p); i += size {
+		for j, k := i, i+size-1; j < k; j, k = j+1, k-1 {
+			p[j], p[k] = p[k], p[j]
+		}
+	}
+}
+
+// swapRun 按成员的大小转换合并的 pod 成员, fields 为合并的成员
+func swapRun(p []byte, fields []TRegFieldOffsetData) {
+	base := fields[0].offset
+	for i := range fields {
+		off, size := int(fields[i].offset-base), int(fields[i].rType.Size())
+		if off+size > len(p) {
+			return
+		}
+		swapElems(p[off:off+size], size)
+	}
+}
+
+// writePod 写入 n 个大小为 size 的数值, ptr 指向第一个数值
+func (b *ProtocolWritter) writePod(ptr unsafe.Pointer, size, n int) {
+	start := b.Len()
+	b.writeMemory(ptr, size*n)
+	if swapBytes {
+		swapElems(b.buf[start:], size)
+	}
+}
+
+// writePodRun 写入合并的 pod 成员, fields 为合并的成员
+func (b *ProtocolWritter) writePodRun(ptr unsafe.Pointer, fields []TRegFieldOffsetData) {
+	start := b.Len()
+	b.writeMemory(ptr, fields[0].podSize)
+	if swapBytes {
+		swapRun(b.buf[start:], fields)
+	}
+}
+
+// readPod 读取 n 个大小为 size 的数值到 ptr 指向的内存,数据不足时只读取剩余的数据,返回读取的字节数
+func (r *ProtocolReader) readPod(ptr unsafe.Pointer, size, n int) int {
+	rn := r.readMemory(ptr, size*n)
+	if swapBytes && rn > 0 {
+		swapElems(unsafe.Slice((*byte)(ptr), rn), size)
+	}
+	return rn
+}
+
+// readPodRun 读取合并的 pod 成员,数据不足时只读取剩余的数据,返回读取的字节数
+func (r *ProtocolReader) readPodRun(ptr unsafe.Pointer, fields []TRegFieldOffsetData) int {
+	rn := r.readMemory(ptr, fields[0].podSize)
+	if swapBytes && rn > 0 {
+		swapRun(unsafe.Slice((*byte)(ptr), rn), fields)
+	}
+	return rn
+}
@@ -0,0 +1,95 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+var (
+	ErrBadSign       = errors.New("bad data sign")         // 数据头中的标记错误
+	ErrBadHeader     = errors.New("bad data header")       // 数据头中的数据长度小于数据头长度
+	ErrTruncated     = errors.New("data truncated")        // 数据长度不足
+	ErrNotRegistered = errors.New("object isn't register") // 对象的类型没有注册
+	ErrStringTooLong = errors.New("string is too long")    // 字符串超过了长度字段能表示的最大长度
+	ErrBlobTooLarge  = errors.New("blob is too large")     // []byte 成员超过了 tag 中的 max
+	ErrIntRange      = errors.New("integer out of range")  // int、uint、uintptr 的值超出了本机的范围
+)
+
+// ErrUnknownClass 数据头中的 classId 没有注册
+type ErrUnknownClass struct {
+	ClassId uint32
+}
+
+func (e *ErrUnknownClass) Error() string {
+	return fmt.Sprintf("unknown classId %d", e.ClassId)
+}
+
+// ErrNoHandler Dispatcher 中没有处理该 classId 的函数,也没有设置 HandleFallback
+type ErrNoHandler struct {
+	ClassId uint32
+}
+
+func (e *ErrNoHandler) Error() string {
+	return fmt.Sprintf("no handler for classId %d", e.ClassId)
+}
+
+// ErrTypeMismatch 数据头中的 classId 与成员类型注册的 classId 不一致
+type ErrTypeMismatch struct {
+	ClassId uint32       // 数据头中的 classId
+	Want    reflect.Type // 成员的类型
+}
+
+func (e *ErrTypeMismatch) Error() string {
+	return fmt.Sprintf("classId %d isn't %s", e.ClassId, e.Want)
+}
+
+// ErrDecode 解码错误,记录出错的位置, Err 为具体的错误,可以通过 errors.Is/As 判断
+type ErrDecode struct {
+	Offset  int    // 出错的数据在数据中的偏移
+	ClassId uint32 // 出错的协议,嵌套时为最外层的协议
+	Path    string // 出错的成员,如 TMapInfo.SlotList[2].PlaceItemId
+	Err     error
+}
+
+func (e *ErrDecode) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("decode error at offset %d: %v", e.Offset, e.Err)
+	}
+	return fmt.Sprintf("decode %s error at offset %d: %v", e.Path, e.Offset, e.Err)
+}
+
+func (e *ErrDecode) Unwrap() error { return e.Err }
+
+// ErrEncode 编码错误,记录出错的成员, Err 为具体的错误,可以通过 errors.Is/As 判断
+type ErrEncode struct {
+	ClassId uint32 // 出错的协议,嵌套时为最外层的协议
+	Path    string // 出错的成员,如 TMapInfo.SlotList[2].PlaceItemId
+	Err     error
+}
+
+func (e *ErrEncode) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("encode error: %v", e.Err)
+	}
+	return fmt.Sprintf("encode %s error: %v", e.Path, e.Err)
+}
+
+func (e *ErrEncode) Unwrap() error { return e.Err }
+
+// mapKeyPath map 元素在成员路径中的表示,如 ["a"], [2]
+func mapKeyPath(key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return "[" + strconv.Quote(key.String()) + "]"
+	}
+	return fmt.Sprintf("[%v]", key.Interface())
+}
+
+// prependPath 在成员路径 path 前加上外层的成员名
+func prependPath(path, name string) string {
+	if path == "" || path[0] == '[' {
+		return name + path
+	}
+	return name + "." + path
+}
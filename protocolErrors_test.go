@@ -0,0 +1,180 @@
+package protocol
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeError(t *testing.T) {
+	const ClassID_LimitNode = ClassID_Test + 4
+	reg := NewRegistry()
+	for _, msg := range []struct {
+		classId uint32
+		msg     IMsg
+	}{{ClassID_LimitNode, (*TLimitNode)(nil)}, {ClassID_SlotData, (*TSlotData)(nil)}, {ClassID_MapInfo, (*TMapInfo)(nil)}, {ClassID_CodeGenTest, (*TCodeGenTest)(nil)}} {
+		if err := reg.Register(msg.classId, msg.msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	marshal := func(msg IMsg) []byte {
+		data, err := reg.Marshal(msg)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		return data
+	}
+	// 修改 mapInfoTestData 的第 off 个字节, SlotList[1] 的数据头从第 52 个字节开始
+	corrupt := func(off int, b byte) []byte {
+		data := append([]byte(nil), mapInfoTestData...)
+		data[off] = b
+		return data
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		limits  DecoderLimits
+		target  error // errors.Is 的目标,为 nil 时检查 errType
+		errType interface{}
+		offset  int
+		classId uint32
+		path    string
+	}{
+		{"bad sign", corrupt(0, 0), DecoderLimits{}, ErrBadSign, nil, 0, 0, ""},
+		{"truncated", mapInfoTestData[:50], DecoderLimits{}, ErrTruncated, nil, 0, ClassID_MapInfo, ""},
+		{"unknown class", corrupt(2, 0), DecoderLimits{}, nil, new(*ErrUnknownClass), 0, ClassID_MapInfo &^ 0xFF, ""},
+		{"nested bad sign", corrupt(52, 0), DecoderLimits{}, ErrBadSign, nil, 52, ClassID_MapInfo, "TMapInfo.SlotList[1]"},
+		{"nested bad header", corrupt(58, 1), DecoderLimits{}, ErrBadHeader, nil, 52, ClassID_MapInfo, "TMapInfo.SlotList[1]"},
+		{"type mismatch", corrupt(54, 76), DecoderLimits{}, nil, new(*ErrTypeMismatch), 52, ClassID_MapInfo, "TMapInfo.SlotList[1]"},
+		{"string limit", marshal(&TLimitNode{Next: &TLimitNode{Next: &TLimitNode{Name: "abc"}}}), DecoderLimits{MaxStringBytes: 2},
+			nil, new(*ErrLimitExceeded), 32, ClassID_LimitNode, "TLimitNode.Next.Next.Name"},
+		{"interface", marshal(&TLimitNode{Any: &TLimitNode{List: []int32{1, 2}}}), DecoderLimits{MaxSliceLen: 1},
+			nil, new(*ErrLimitExceeded), 30, ClassID_LimitNode, "TLimitNode.Any.List"},
+		{"map value", marshal(newCodeGenTestObj()), DecoderLimits{MaxObjects: 5},
+			nil, new(*ErrLimitExceeded), -1, ClassID_CodeGenTest, "TCodeGenTest.MI[3]"},
+	}
+	check := func(t *testing.T, err error, offset int, classId uint32, path string) {
+		var decodeErr *ErrDecode
+		if !errors.As(err, &decodeErr) {
+			t.Fatalf("error = %v, want *ErrDecode", err)
+		}
+		if offset >= 0 && decodeErr.Offset != offset || decodeErr.ClassId != classId || decodeErr.Path != path {
+			t.Errorf("error at offset %d classId %d path %q, want offset %d classId %d path %q (%v)",
+				decodeErr.Offset, decodeErr.ClassId, decodeErr.Path, offset, classId, path, err)
+		}
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := reg.NewProtocolReader(tt.data)
+			r.SetLimits(tt.limits)
+			_, err := r.readAny()
+			if tt.target != nil && !errors.Is(err, tt.target) {
+				t.Fatalf("readAny() error = %v, want %v", err, tt.target)
+			}
+			if tt.errType != nil && !errors.As(err, tt.errType) {
+				t.Fatalf("readAny() error = %v, want %s", err, reflect.TypeOf(tt.errType).Elem().Elem())
+			}
+			check(t, err, tt.offset, tt.classId, tt.path)
+		})
+	}
+
+	// 生成的代码解析失败时,通过反射方式定位的成员与反射方式一致
+	withoutGenerated(func() {
+		_, err := reg.Unmarshal(corrupt(54, 76))
+		check(t, err, 52, ClassID_MapInfo, "TMapInfo.SlotList[1]")
+	}, (*TMapInfo)(nil), (*TSlotData)(nil))
+	err := reg.UnmarshalInto(corrupt(52, 0), &TMapInfo{})
+	check(t, err, 52, ClassID_MapInfo, "TMapInfo.SlotList[1]")
+	if err := reg.UnmarshalInto(mapInfoTestData, &TSlotData{}); !errors.As(err, new(*ErrClassMismatch)) {
+		t.Errorf("UnmarshalInto() error = %v, want *ErrClassMismatch", err)
+	}
+}
+
+// TEncodeGen 手写的 MarshalProtocol,与 goprotocol-gen 生成的代码一致
+type TEncodeGen struct {
+	Any   interface{}
+	Flags map[uint8]interface{}
+}
+
+func (m *TEncodeGen) MarshalProtocol(w *ProtocolWritter) error {
+	if m == nil {
+		w.WriteEmptyHeader()
+		return nil
+	}
+	head, err := w.BeginStruct(m)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteAny(m.Any); err != nil {
+		return err
+	}
+	w.WriteArrayLen(len(m.Flags))
+	for _, e0 := range SortedMapEntries(m.Flags) {
+		w.WriteUint8(e0.Key)
+		if err := w.WriteAny(e0.Val); err != nil {
+			return err
+		}
+	}
+	return w.EndStruct(&head)
+}
+
+func TestEncodeError(t *testing.T) {
+	const ClassID_LimitNode = ClassID_Test + 4
+	const ClassID_EncodeGen = ClassID_Test + 5
+	type unregistered struct{ A int32 }
+	reg := NewRegistry()
+	for _, msg := range []struct {
+		classId uint32
+		msg     IMsg
+	}{{ClassID_LimitNode, (*TLimitNode)(nil)}, {ClassID_SlotData, (*TSlotData)(nil)}, {ClassID_EncodeGen, (*TEncodeGen)(nil)}} {
+		if err := reg.Register(msg.classId, msg.msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name    string
+		msg     IMsg
+		classId uint32
+		path    string
+	}{
+		{"unregistered", &unregistered{}, 0, ""},
+		{"interface", &TLimitNode{Any: &unregistered{}}, ClassID_LimitNode, "TLimitNode.Any"},
+		{"nested", &TLimitNode{Next: &TLimitNode{Any: unregistered{}}}, ClassID_LimitNode, "TLimitNode.Next.Any"},
+		{"generated", &TEncodeGen{Any: &TLimitNode{Next: &TLimitNode{Any: &unregistered{}}}}, ClassID_EncodeGen, "TEncodeGen.Any.Next.Any"},
+		{"generated map", &TEncodeGen{Flags: map[uint8]interface{}{1: &TSlotData{}, 2: &unregistered{}}}, ClassID_EncodeGen, "TEncodeGen.Flags[2]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := reg.NewProtocolWritter(0)
+			if err := w.WriteAny(&TSlotData{Idx: 1}); err != nil {
+				t.Fatal(err)
+			}
+			n := w.Len()
+			err := w.WriteAny(tt.msg)
+			if !errors.Is(err, ErrNotRegistered) {
+				t.Fatalf("WriteAny() error = %v, want ErrNotRegistered", err)
+			}
+			var encodeErr *ErrEncode
+			if tt.path != "" && (!errors.As(err, &encodeErr) || encodeErr.ClassId != tt.classId || encodeErr.Path != tt.path) {
+				t.Errorf("WriteAny() error = %v, want classId %d path %q", err, tt.classId, tt.path)
+			}
+			if w.Len() != n { // 出错时回滚到消息的起始位置
+				t.Errorf("WriteAny() left %d bytes after error", w.Len()-n)
+			}
+		})
+	}
+
+	// nil interface 写入空数据头,解析后仍然为 nil
+	data, err := reg.Marshal(&TLimitNode{Next: &TLimitNode{}})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	got, err := reg.Unmarshal(data)
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if node := got.(*TLimitNode); node.Any != nil || node.Next == nil || node.Next.Any != nil {
+		t.Errorf("Unmarshal() = %+v", node)
+	}
+}
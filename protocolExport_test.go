@@ -0,0 +1,17 @@
+package protocol
+
+// WithoutGenerated 关闭 reg 中 msgs 的 goprotocol-gen 生成的代码,使用反射方式执行 f,供 protocol_test 包中的测试使用
+func WithoutGenerated(reg *Registry, f func(), msgs ...IMsg) {
+	for _, msg := range msgs {
+		rtti, _ := reg.FromObj(msg)
+		rtti.marshaler, rtti.unmarshaler = false, false
+		defer func() { rtti.marshaler, rtti.unmarshaler = true, true }()
+	}
+	f()
+}
+
+// UsesGenerated reg 中 msg 的序列化和反序列化是否使用 goprotocol-gen 生成的代码
+func UsesGenerated(reg *Registry, msg IMsg) bool {
+	rtti, ok := reg.FromObj(msg)
+	return ok && rtti.marshaler && rtti.unmarshaler
+}
@@ -0,0 +1,66 @@
+// 根据注册信息生成其他语言的客户端代码
+package protocol
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// TCodeGenOptions 生成客户端代码的选项
+type TCodeGenOptions struct {
+	Namespace string   // C# 的 namespace
+	ClassIds  []uint32 // 需要生成的协议,为空时生成所有已注册的协议
+}
+
+// codeGenClasses 返回 reg 中需要生成代码的协议,按 classId 排序
+func (reg *Registry) codeGenClasses(opts TCodeGenOptions) ([]*TRegRttiData, error) {
+	if len(opts.ClassIds) == 0 {
+		return reg.Classes(), nil
+	}
+	var classes []*TRegRttiData
+	for _, classId := range opts.ClassIds {
+		rtti, ok := reg.ByClassId(classId)
+		if !ok {
+			return nil, fmt.Errorf("classId %d isn't register", classId)
+		}
+		classes = append(classes, rtti)
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i].ClassId < classes[j].ClassId })
+	return classes, nil
+}
+
+// className 生成代码中的类名,与 Go 的结构体名相同
+func className(rtti *TRegRttiData) string {
+	return rtti.rType.Name()
+}
+
+// classIdName classId 常量名, TMapInfo 对应 ClassID_MapInfo
+func classIdName(rtti *TRegRttiData) string {
+	name := className(rtti)
+	if len(name) > 1 && name[0] == 'T' && strings.ToUpper(name[1:2]) == name[1:2] {
+		name = name[1:]
+	}
+	return "ClassID_" + name
+}
+
+// codeGenStruct 返回结构体成员类型对应的 reg 中已注册的协议
+func (reg *Registry) codeGenStruct(tp reflect.Type) (*TRegRttiData, error) {
+	if tp.Kind() == reflect.Ptr {
+		tp = tp.Elem()
+	}
+	rtti, ok := reg.FromType(tp)
+	if !ok {
+		return nil, fmt.Errorf("%s isn't register", tp)
+	}
+	return rtti, nil
+}
+
+// codeGenFieldName 生成代码中的成员名,占位成员 _ 按序号命名
+func codeGenFieldName(field *TRegFieldOffsetData, idx int) string {
+	if field.Name == "_" {
+		return fmt.Sprintf("_blank%d", idx)
+	}
+	return field.Name
+}
@@ -0,0 +1,737 @@
+package protocol
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// GenerateCSharp 根据默认注册表的注册信息生成 C# 代码
+func GenerateCSharp(w io.Writer, opts TCodeGenOptions) error {
+	return defaultRegistry.GenerateCSharp(w, opts)
+}
+
+// GenerateCSharp 根据 reg 的注册信息生成 C# 代码(兼容 Unity)
+// 生成的代码包含读写数据头、数值和字符串的运行时,以及每个协议对应的类和 classId 常量
+// int/uint/uintptr 按 64 位服务器的 8 字节处理
+func (reg *Registry) GenerateCSharp(w io.Writer, opts TCodeGenOptions) error {
+	classes, err := reg.codeGenClasses(opts)
+	if err != nil {
+		return err
+	}
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = "GoProtocol"
+	}
+	g := &csGenerator{reg: reg}
+	g.printf("// Code generated by goprotocol. DO NOT EDIT.\n\n")
+	g.printf("using System;\nusing System.Collections.Generic;\nusing System.Text;\n\n")
+	g.printf("namespace %s\n{\n", namespace)
+	g.buf.WriteString(csRuntime)
+
+	g.printf("\n    public static class ProtocolClassIds\n    {\n")
+	for _, rtti := range classes {
+		g.printf("        public const uint %s = %d;\n", classIdName(rtti), rtti.ClassId)
+	}
+	g.printf("    }\n")
+
+	g.printf("\n    public static class ProtocolFactory\n    {\n")
+	g.printf("        public static IProtocolMessage Create(uint classId)\n        {\n")
+	g.printf("            switch (classId)\n            {\n")
+	for _, rtti := range classes {
+		g.printf("                case ProtocolClassIds.%s: return new %s();\n", classIdName(rtti), className(rtti))
+	}
+	g.printf("                default: return null;\n            }\n        }\n\n")
+	g.printf("        // ReadAny 读取 interface 成员中存放的协议, nil 返回 null\n")
+	g.printf("        public static IProtocolMessage ReadAny(ProtocolReader r)\n        {\n")
+	g.printf("            ProtocolHeader h = r.PeekHeader();\n")
+	g.printf("            if (h.IsNil) { r.ReadNil(); return null; }\n")
+	g.printf("            IProtocolMessage m = Create(h.ClassId);\n")
+	g.printf("            if (m == null) throw new ProtocolException(\"unknown classId \" + h.ClassId);\n")
+	g.printf("            m.Deserialize(r);\n            return m;\n        }\n    }\n")
+
+	for _, rtti := range classes {
+		if err := g.class(rtti); err != nil {
+			return err
+		}
+	}
+	g.printf("}\n")
+	_, err = w.Write(g.buf.Bytes())
+	return err
+}
+
+type csGenerator struct {
+	reg    *Registry
+	buf    bytes.Buffer
+	indent int
+	long   bool // 正在生成的成员带有 long tag,字符串使用 uint32 长度
+}
+
+func (g *csGenerator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// line 按当前缩进输出一行
+func (g *csGenerator) line(format string, args ...interface{}) {
+	if format != "" {
+		g.buf.WriteString(strings.Repeat("    ", g.indent))
+		fmt.Fprintf(&g.buf, format, args...)
+	}
+	g.buf.WriteByte('\n')
+}
+
+// stringMethod 字符串的读写方法, op 为 Write/Read
+func (g *csGenerator) stringMethod(op string) string {
+	if g.long {
+		return op + "LongString"
+	}
+	return op + "String"
+}
+
+func (g *csGenerator) class(rtti *TRegRttiData) error {
+	name := className(rtti)
+	g.printf("\n    public class %s : IProtocolMessage\n    {\n", name)
+	g.indent = 2
+	g.line("public uint ProtocolClassId { get { return ProtocolClassIds.%s; } }", classIdName(rtti))
+	g.line("")
+	for i := range rtti.FieldData {
+		field := &rtti.FieldData[i]
+		tp, err := g.csType(field.rType)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %v", name, field.Name, err)
+		}
+		if init := csInit(field.rType, tp); init != "" {
+			g.line("public %s %s = %s;", tp, csFieldName(field, i), init)
+		} else {
+			g.line("public %s %s;", tp, csFieldName(field, i))
+		}
+	}
+
+	g.line("")
+	g.line("public void Serialize(ProtocolWriter w)")
+	g.line("{")
+	g.indent++
+	g.line("int start = w.BeginStruct(ProtocolClassId);")
+	for i := range rtti.FieldData {
+		field := &rtti.FieldData[i]
+		g.long = field.LongString
+		if err := g.write(csFieldName(field, i), field.rType, 0); err != nil {
+			return err
+		}
+	}
+	g.line("w.EndStruct(start);")
+	g.indent--
+	g.line("}")
+
+	g.line("")
+	g.line("public void Deserialize(ProtocolReader r)")
+	g.line("{")
+	g.indent++
+	for i := range rtti.FieldData {
+		field := &rtti.FieldData[i]
+		tp, _ := g.csType(field.rType)
+		init := csInit(field.rType, tp)
+		if init == "" {
+			init = "default(" + tp + ")"
+		}
+		g.line("%s = %s;", csFieldName(field, i), init)
+	}
+	g.line("ProtocolHeader h = r.BeginStruct(ProtocolClassId);")
+	for i := range rtti.FieldData {
+		field := &rtti.FieldData[i]
+		g.line("if (r.More(h))")
+		g.line("{")
+		g.indent++
+		g.long = field.LongString
+		if err := g.read(csFieldName(field, i), field.rType, 0); err != nil {
+			return err
+		}
+		g.indent--
+		g.line("}")
+	}
+	g.line("r.EndStruct(h);")
+	g.indent--
+	g.line("}")
+	g.printf("    }\n")
+	return nil
+}
+
+var csPodTypes = map[reflect.Kind][2]string{
+	reflect.Bool:    {"bool", "Bool"},
+	reflect.Int8:    {"sbyte", "Int8"},
+	reflect.Uint8:   {"byte", "UInt8"},
+	reflect.Int16:   {"short", "Int16"},
+	reflect.Uint16:  {"ushort", "UInt16"},
+	reflect.Int32:   {"int", "Int32"},
+	reflect.Uint32:  {"uint", "UInt32"},
+	reflect.Int64:   {"long", "Int64"},
+	reflect.Uint64:  {"ulong", "UInt64"},
+	reflect.Int:     {"long", "Int64"},
+	reflect.Uint:    {"ulong", "UInt64"},
+	reflect.Uintptr: {"ulong", "UInt64"},
+	reflect.Float32: {"float", "Float32"},
+	reflect.Float64: {"double", "Float64"},
+}
+
+// csType Go 类型对应的 C# 类型
+func (g *csGenerator) csType(tp reflect.Type) (string, error) {
+	if pod, ok := csPodTypes[tp.Kind()]; ok {
+		return pod[0], nil
+	}
+	switch tp.Kind() {
+	case reflect.String:
+		return "string", nil
+	case reflect.Struct, reflect.Ptr:
+		rtti, err := g.reg.codeGenStruct(tp)
+		if err != nil {
+			return "", err
+		}
+		return className(rtti), nil
+	case reflect.Interface:
+		return "IProtocolMessage", nil
+	case reflect.Slice, reflect.Array:
+		elem, err := g.csType(tp.Elem())
+		if err != nil {
+			return "", err
+		}
+		if tp.Kind() == reflect.Array {
+			return elem + "[]", nil
+		}
+		return "List<" + elem + ">", nil
+	case reflect.Map:
+		key, err := g.csType(tp.Key())
+		if err != nil {
+			return "", err
+		}
+		val, err := g.csType(tp.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "Dictionary<" + key + ", " + val + ">", nil
+	}
+	return "", fmt.Errorf("unsupported type %s", tp)
+}
+
+// csInit 成员的初始值,与 Go 的零值对应
+func csInit(tp reflect.Type, cs string) string {
+	switch tp.Kind() {
+	case reflect.String:
+		return `""`
+	case reflect.Struct, reflect.Slice, reflect.Map:
+		return "new " + cs + "()"
+	case reflect.Array: // 嵌套的数组为交错数组,只分配第一维,如 new float[4][]
+		elem := strings.TrimSuffix(cs, "[]")
+		base := strings.TrimRight(elem, "[]")
+		return fmt.Sprintf("new %s[%d]%s", base, tp.Len(), elem[len(base):])
+	}
+	return ""
+}
+
+// csElemInit 读取元素前的初始值,数组元素需要先分配
+func csElemInit(tp reflect.Type, cs string) string {
+	if tp.Kind() == reflect.Array {
+		return csInit(tp, cs)
+	}
+	return "default(" + cs + ")"
+}
+
+func (g *csGenerator) write(expr string, tp reflect.Type, depth int) error {
+	cs, err := g.csType(tp)
+	if err != nil {
+		return err
+	}
+	if pod, ok := csPodTypes[tp.Kind()]; ok {
+		g.line("w.Write%s(%s);", pod[1], expr)
+		return nil
+	}
+	switch tp.Kind() {
+	case reflect.String:
+		g.line("w.%s(%s);", g.stringMethod("Write"), expr)
+	case reflect.Struct:
+		g.line("(%s ?? new %s()).Serialize(w);", expr, cs)
+	case reflect.Ptr, reflect.Interface:
+		g.line("w.WriteMessage(%s);", expr)
+	case reflect.Slice:
+		e := fmt.Sprintf("e%d", depth)
+		g.line("w.WriteArrayLen(%s == null ? 0 : %s.Count);", expr, expr)
+		g.line("if (%s != null)", expr)
+		g.line("{")
+		g.indent++
+		g.line("foreach (var %s in %s)", e, expr)
+		g.line("{")
+		g.indent++
+		if err := g.write(e, tp.Elem(), depth+1); err != nil {
+			return err
+		}
+		g.indent--
+		g.line("}")
+		g.indent--
+		g.line("}")
+	case reflect.Array: // Go 的数组总是写入声明的长度
+		i, e := fmt.Sprintf("i%d", depth), fmt.Sprintf("e%d", depth)
+		elem, _ := g.csType(tp.Elem())
+		g.line("w.WriteArrayLen(%d);", tp.Len())
+		g.line("for (int %s = 0; %s < %d; %s++)", i, i, tp.Len(), i)
+		g.line("{")
+		g.indent++
+		g.line("%s %s = %s != null && %s < %s.Length ? %s[%s] : default(%s);", elem, e, expr, i, expr, expr, i, elem)
+		if err := g.write(e, tp.Elem(), depth+1); err != nil {
+			return err
+		}
+		g.indent--
+		g.line("}")
+	case reflect.Map:
+		k := fmt.Sprintf("k%d", depth)
+		g.line("w.WriteArrayLen(%s == null ? 0 : %s.Count);", expr, expr)
+		g.line("if (%s != null)", expr)
+		g.line("{")
+		g.indent++
+		g.line("foreach (var %s in ProtocolWriter.SortedKeys(%s))", k, expr)
+		g.line("{")
+		g.indent++
+		if err := g.write(k, tp.Key(), depth+1); err != nil {
+			return err
+		}
+		if err := g.write(expr+"["+k+"]", tp.Elem(), depth+1); err != nil {
+			return err
+		}
+		g.indent--
+		g.line("}")
+		g.indent--
+		g.line("}")
+	}
+	return nil
+}
+
+func (g *csGenerator) read(target string, tp reflect.Type, depth int) error {
+	cs, err := g.csType(tp)
+	if err != nil {
+		return err
+	}
+	if pod, ok := csPodTypes[tp.Kind()]; ok {
+		g.line("%s = r.Read%s();", target, pod[1])
+		return nil
+	}
+	switch tp.Kind() {
+	case reflect.String:
+		g.line("%s = r.%s();", target, g.stringMethod("Read"))
+	case reflect.Struct:
+		g.line("%s = r.ReadStruct(new %s());", target, cs)
+	case reflect.Ptr:
+		g.line("%s = r.ReadNil() ? null : r.ReadStruct(new %s());", target, cs)
+	case reflect.Interface:
+		g.line("%s = ProtocolFactory.ReadAny(r);", target)
+	case reflect.Slice:
+		n, i, e := fmt.Sprintf("n%d", depth), fmt.Sprintf("i%d", depth), fmt.Sprintf("e%d", depth)
+		elem, _ := g.csType(tp.Elem())
+		g.line("int %s = r.ReadArrayLen();", n)
+		g.line("%s = new %s(%s);", target, cs, n)
+		g.line("for (int %s = 0; %s < %s; %s++)", i, i, n, i)
+		g.line("{")
+		g.indent++
+		g.line("%s %s = %s;", elem, e, csElemInit(tp.Elem(), elem))
+		if err := g.read(e, tp.Elem(), depth+1); err != nil {
+			return err
+		}
+		g.line("%s.Add(%s);", target, e)
+		g.indent--
+		g.line("}")
+	case reflect.Array: // 数据中的元素个数可能与数组长度不一致,多余的元素读取后丢弃
+		n, i, e := fmt.Sprintf("n%d", depth), fmt.Sprintf("i%d", depth), fmt.Sprintf("e%d", depth)
+		elem, _ := g.csType(tp.Elem())
+		g.line("int %s = r.ReadArrayLen();", n)
+		g.line("for (int %s = 0; %s < %s; %s++)", i, i, n, i)
+		g.line("{")
+		g.indent++
+		g.line("%s %s = %s;", elem, e, csElemInit(tp.Elem(), elem))
+		if err := g.read(e, tp.Elem(), depth+1); err != nil {
+			return err
+		}
+		g.line("if (%s < %d) %s[%s] = %s;", i, tp.Len(), target, i, e)
+		g.indent--
+		g.line("}")
+	case reflect.Map:
+		n, i, k, v := fmt.Sprintf("n%d", depth), fmt.Sprintf("i%d", depth), fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth)
+		key, _ := g.csType(tp.Key())
+		val, _ := g.csType(tp.Elem())
+		g.line("int %s = r.ReadArrayLen();", n)
+		g.line("%s = new %s(%s);", target, cs, n)
+		g.line("for (int %s = 0; %s < %s; %s++)", i, i, n, i)
+		g.line("{")
+		g.indent++
+		g.line("%s %s = default(%s);", key, k, key)
+		g.line("%s %s = %s;", val, v, csElemInit(tp.Elem(), val))
+		if err := g.read(k, tp.Key(), depth+1); err != nil {
+			return err
+		}
+		if err := g.read(v, tp.Elem(), depth+1); err != nil {
+			return err
+		}
+		g.line("%s[%s] = %s;", target, k, v)
+		g.indent--
+		g.line("}")
+	}
+	return nil
+}
+
+var csKeywords = map[string]bool{
+	"abstract": true, "as": true, "base": true, "bool": true, "break": true, "byte": true, "case": true, "catch": true,
+	"char": true, "checked": true, "class": true, "const": true, "continue": true, "decimal": true, "default": true,
+	"delegate": true, "do": true, "double": true, "else": true, "enum": true, "event": true, "explicit": true,
+	"extern": true, "false": true, "finally": true, "fixed": true, "float": true, "for": true, "foreach": true,
+	"goto": true, "if": true, "implicit": true, "in": true, "int": true, "interface": true, "internal": true,
+	"is": true, "lock": true, "long": true, "namespace": true, "new": true, "null": true, "object": true,
+	"operator": true, "out": true, "override": true, "params": true, "private": true, "protected": true,
+	"public": true, "readonly": true, "ref": true, "return": true, "sbyte": true, "sealed": true, "short": true,
+	"sizeof": true, "stackalloc": true, "static": true, "string": true, "struct": true, "switch": true,
+	"this": true, "throw": true, "true": true, "try": true, "typeof": true, "uint": true, "ulong": true,
+	"unchecked": true, "unsafe": true, "ushort": true, "using": true, "virtual": true, "void": true,
+	"volatile": true, "while": true,
+}
+
+// csFieldName C# 成员名,与关键字冲突时加 @ 前缀;与生成的成员或类名冲突时加 _ 前缀
+func csFieldName(field *TRegFieldOffsetData, idx int) string {
+	name := codeGenFieldName(field, idx)
+	if csKeywords[name] {
+		return "@" + name
+	}
+	switch name {
+	case "ProtocolClassId", "Serialize", "Deserialize", "w", "r", "h", "start":
+		return "_" + name
+	}
+	return name
+}
+
+// csRuntime 生成代码依赖的读写实现
+const csRuntime = `    public class ProtocolException : Exception
+    {
+        public ProtocolException(string message) : base(message) { }
+    }
+
+    public interface IProtocolMessage
+    {
+        uint ProtocolClassId { get; }
+        void Serialize(ProtocolWriter w);
+        void Deserialize(ProtocolReader r);
+    }
+
+    // 数据头: 标记(uint16) + classId(uint16/uint32) + 数据长度(uint16/uint32,包含数据头)
+    public struct ProtocolHeader
+    {
+        public int Start;
+        public int HeaderLength;
+        public uint ClassId;
+        public int DataLength;
+
+        public bool IsNil { get { return DataLength == HeaderLength; } }
+    }
+
+    public class ProtocolWriter
+    {
+        public const ushort SignFlag = 0x6D98;
+
+        private byte[] buf = new byte[256];
+        private int len;
+
+        public int Length { get { return len; } }
+
+        public byte[] ToArray()
+        {
+            byte[] data = new byte[len];
+            Buffer.BlockCopy(buf, 0, data, 0, len);
+            return data;
+        }
+
+        private void Grow(int n)
+        {
+            if (len + n > buf.Length)
+                Array.Resize(ref buf, Math.Max(buf.Length * 2, len + n));
+        }
+
+        public void WriteBool(bool v) { WriteUInt8(v ? (byte)1 : (byte)0); }
+        public void WriteInt8(sbyte v) { WriteUInt8((byte)v); }
+        public void WriteUInt8(byte v)
+        {
+            Grow(1);
+            buf[len++] = v;
+        }
+        public void WriteInt16(short v) { WriteUInt16((ushort)v); }
+        public void WriteUInt16(ushort v)
+        {
+            Grow(2);
+            buf[len++] = (byte)v;
+            buf[len++] = (byte)(v >> 8);
+        }
+        public void WriteInt32(int v) { WriteUInt32((uint)v); }
+        public void WriteUInt32(uint v)
+        {
+            Grow(4);
+            buf[len++] = (byte)v;
+            buf[len++] = (byte)(v >> 8);
+            buf[len++] = (byte)(v >> 16);
+            buf[len++] = (byte)(v >> 24);
+        }
+        public void WriteInt64(long v) { WriteUInt64((ulong)v); }
+        public void WriteUInt64(ulong v)
+        {
+            WriteUInt32((uint)v);
+            WriteUInt32((uint)(v >> 32));
+        }
+        public void WriteFloat32(float v)
+        {
+            byte[] b = BitConverter.GetBytes(v);
+            if (!BitConverter.IsLittleEndian) Array.Reverse(b);
+            WriteBytes(b);
+        }
+        public void WriteFloat64(double v) { WriteInt64(BitConverter.DoubleToInt64Bits(v)); }
+
+        public void WriteBytes(byte[] b)
+        {
+            Grow(b.Length);
+            Buffer.BlockCopy(b, 0, buf, len, b.Length);
+            len += b.Length;
+        }
+
+        // 字符串: utf8长度(uint16) + utf8内容
+        public void WriteString(string s)
+        {
+            byte[] b = Encoding.UTF8.GetBytes(s ?? "");
+            if (b.Length > 0xFFFF) throw new ProtocolException("string is too long");
+            WriteUInt16((ushort)b.Length);
+            WriteBytes(b);
+        }
+
+        // 长字符串: utf8长度(uint32) + utf8内容,对应带 long tag 的成员
+        public void WriteLongString(string s)
+        {
+            byte[] b = Encoding.UTF8.GetBytes(s ?? "");
+            WriteUInt32((uint)b.Length);
+            WriteBytes(b);
+        }
+
+        public void WriteArrayLen(int n) { WriteUInt32((uint)n); }
+
+        // nil 指针和 interface 写入空数据头
+        public void WriteEmptyHeader()
+        {
+            WriteUInt16(SignFlag);
+            WriteUInt16(0);
+            WriteUInt16(6);
+        }
+
+        public void WriteMessage(IProtocolMessage m)
+        {
+            if (m == null) WriteEmptyHeader();
+            else m.Serialize(this);
+        }
+
+        // BeginStruct 写入数据头,返回数据头的位置,写完成员后调用 EndStruct 回填数据长度
+        public int BeginStruct(uint classId)
+        {
+            int start = len;
+            if (classId > 0xFFFF)
+            {
+                WriteUInt16((ushort)(SignFlag | 1));
+                WriteUInt32(classId);
+            }
+            else
+            {
+                WriteUInt16(SignFlag);
+                WriteUInt16((ushort)classId);
+            }
+            WriteUInt16(0);
+            return start;
+        }
+
+        public void EndStruct(int start)
+        {
+            ushort sign = (ushort)(buf[start] | buf[start + 1] << 8);
+            int lenPos = start + ((sign & 1) != 0 ? 6 : 4);
+            int dataLength = len - start;
+            if (dataLength > 0xFFFF) // 数据长度改为 uint32
+            {
+                Grow(2);
+                Buffer.BlockCopy(buf, lenPos + 2, buf, lenPos + 4, len - lenPos - 2);
+                len += 2;
+                dataLength += 2;
+                sign |= 2;
+                buf[start] = (byte)sign;
+                buf[start + 1] = (byte)(sign >> 8);
+                buf[lenPos] = (byte)dataLength;
+                buf[lenPos + 1] = (byte)(dataLength >> 8);
+                buf[lenPos + 2] = (byte)(dataLength >> 16);
+                buf[lenPos + 3] = (byte)(dataLength >> 24);
+            }
+            else
+            {
+                buf[lenPos] = (byte)dataLength;
+                buf[lenPos + 1] = (byte)(dataLength >> 8);
+            }
+        }
+
+        // SortedKeys 按 key 升序排列, 字符串按 utf8 编码比较, 与 Go 保持一致
+        public static List<K> SortedKeys<K, V>(Dictionary<K, V> m)
+        {
+            List<K> keys = new List<K>(m.Keys);
+            if (typeof(K) == typeof(string))
+                keys.Sort((a, b) => Utf8Compare((string)(object)a, (string)(object)b));
+            else
+                keys.Sort();
+            return keys;
+        }
+
+        private static int Utf8Compare(string a, string b)
+        {
+            byte[] x = Encoding.UTF8.GetBytes(a);
+            byte[] y = Encoding.UTF8.GetBytes(b);
+            for (int i = 0; i < x.Length && i < y.Length; i++)
+            {
+                if (x[i] != y[i]) return x[i] < y[i] ? -1 : 1;
+            }
+            return x.Length.CompareTo(y.Length);
+        }
+    }
+
+    public class ProtocolReader
+    {
+        private readonly byte[] buf;
+        private int off;
+        private readonly int end;
+
+        public ProtocolReader(byte[] data) : this(data, 0, data.Length) { }
+        public ProtocolReader(byte[] data, int offset, int count)
+        {
+            buf = data;
+            off = offset;
+            end = offset + count;
+        }
+
+        public int Position { get { return off; } }
+        public int Remaining { get { return end - off; } }
+
+        private int Next(int n)
+        {
+            if (n < 0 || Remaining < n) throw new ProtocolException("data is truncated");
+            int pos = off;
+            off += n;
+            return pos;
+        }
+
+        public bool ReadBool() { return ReadUInt8() != 0; }
+        public sbyte ReadInt8() { return (sbyte)ReadUInt8(); }
+        public byte ReadUInt8() { return buf[Next(1)]; }
+        public short ReadInt16() { return (short)ReadUInt16(); }
+        public ushort ReadUInt16()
+        {
+            int p = Next(2);
+            return (ushort)(buf[p] | buf[p + 1] << 8);
+        }
+        public int ReadInt32() { return (int)ReadUInt32(); }
+        public uint ReadUInt32()
+        {
+            int p = Next(4);
+            return (uint)(buf[p] | buf[p + 1] << 8 | buf[p + 2] << 16 | buf[p + 3] << 24);
+        }
+        public long ReadInt64() { return (long)ReadUInt64(); }
+        public ulong ReadUInt64()
+        {
+            ulong lo = ReadUInt32();
+            ulong hi = ReadUInt32();
+            return lo | hi << 32;
+        }
+        public float ReadFloat32()
+        {
+            byte[] b = new byte[4];
+            Buffer.BlockCopy(buf, Next(4), b, 0, 4);
+            if (!BitConverter.IsLittleEndian) Array.Reverse(b);
+            return BitConverter.ToSingle(b, 0);
+        }
+        public double ReadFloat64() { return BitConverter.Int64BitsToDouble(ReadInt64()); }
+
+        public string ReadString()
+        {
+            int n = ReadUInt16();
+            return Encoding.UTF8.GetString(buf, Next(n), n);
+        }
+        public string ReadLongString()
+        {
+            int n = (int)ReadUInt32(); // 超过 int.MaxValue 时为负数, Next 视为数据错误
+            return Encoding.UTF8.GetString(buf, Next(n), n);
+        }
+
+        // ReadArrayLen 每个元素至少占用一个字节,超过剩余数据长度时视为数据错误
+        public int ReadArrayLen()
+        {
+            uint n = ReadUInt32();
+            if (n > (uint)Remaining) throw new ProtocolException("array length out of range");
+            return (int)n;
+        }
+
+        public ProtocolHeader PeekHeader()
+        {
+            int pos = off;
+            ProtocolHeader h = ReadHeader();
+            off = pos;
+            return h;
+        }
+
+        public ProtocolHeader ReadHeader()
+        {
+            ProtocolHeader h = new ProtocolHeader();
+            h.Start = off;
+            ushort sign = ReadUInt16();
+            if ((sign & 0xFFFC) != ProtocolWriter.SignFlag) throw new ProtocolException("bad sign");
+            h.HeaderLength = 6;
+            if ((sign & 1) != 0)
+            {
+                h.HeaderLength += 2;
+                h.ClassId = ReadUInt32();
+            }
+            else
+            {
+                h.ClassId = ReadUInt16();
+            }
+            if ((sign & 2) != 0)
+            {
+                h.HeaderLength += 2;
+                h.DataLength = (int)ReadUInt32();
+            }
+            else
+            {
+                h.DataLength = ReadUInt16();
+            }
+            if (h.DataLength < h.HeaderLength || h.DataLength > end - h.Start) throw new ProtocolException("bad data length");
+            return h;
+        }
+
+        // ReadNil 如果是 nil 写入的空数据头,跳过它并返回 true
+        public bool ReadNil()
+        {
+            ProtocolHeader h = PeekHeader();
+            if (!h.IsNil) return false;
+            off = h.Start + h.DataLength;
+            return true;
+        }
+
+        public ProtocolHeader BeginStruct(uint classId)
+        {
+            ProtocolHeader h = ReadHeader();
+            if (!h.IsNil && h.ClassId != classId) throw new ProtocolException("classId mismatch: want " + classId + ", got " + h.ClassId);
+            return h;
+        }
+
+        // More 旧版本的数据成员较少,读取成员前需要检查
+        public bool More(ProtocolHeader h) { return off < h.Start + h.DataLength; }
+
+        // EndStruct 跳过新版本追加的成员
+        public void EndStruct(ProtocolHeader h) { off = h.Start + h.DataLength; }
+
+        public T ReadStruct<T>(T m) where T : IProtocolMessage
+        {
+            m.Deserialize(this);
+            return m;
+        }
+    }
+`
@@ -0,0 +1,161 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+const ClassID_CodeGenTest = ClassID_Test + 2
+
+// TCodeGenTest 覆盖生成代码支持的所有成员类型
+type TCodeGenTest struct {
+	A     int8
+	B     uint8
+	C     int16
+	D     uint64
+	E     bool
+	F     float32
+	G     float64
+	H     int
+	S     string
+	Slot  TSlotData
+	P     *TSlotData
+	Nil   *TSlotData
+	Any   interface{}
+	List  []string
+	Slots []*TSlotData
+	Arr   [3]int16
+	M     map[string]int32
+	MI    map[int32]*TSlotData
+	Empty map[float64]bool
+	Log   string `protocol:",long"`
+	Grid  [][]int32
+	Mat   [2][2]float32
+	Rows  map[int32][]string
+	_     int32
+	Base  uint16
+}
+
+var codeGenTestClasses = []uint32{ClassID_SlotData, ClassID_MapInfo, ClassID_CodeGenTest}
+
+func newCodeGenTestObj() *TCodeGenTest {
+	return &TCodeGenTest{
+		A: -1, B: 255, C: -300, D: math.MaxUint64, E: true, F: 1.5, G: -2.25, H: -7,
+		S:     "宝山路",
+		Slot:  TSlotData{1, 2, true, 3, 4},
+		P:     &TSlotData{5, 6, false, 7, 8},
+		Any:   &TSlotData{9, 9, true, 9, 9},
+		List:  []string{"", "b", "a"},
+		Slots: []*TSlotData{nil, {1, 1, true, 1, 1}},
+		Arr:   [3]int16{1, -2, 3},
+		M:     map[string]int32{"b": 2, "a": 1, "": 0, "宝": 3, "z": 4},
+		MI:    map[int32]*TSlotData{-5: nil, 3: {3, 3, true, 3, 3}},
+		Grid:  [][]int32{{1, 2}, nil, {3}},
+		Mat:   [2][2]float32{{1, 2}, {3, 4}},
+		Rows:  map[int32][]string{2: {"b"}, 1: {"a", ""}},
+		Base:  0xffff,
+	}
+}
+
+func TestGenerateCSharp(t *testing.T) {
+	MustRegisterDataClass(ClassID_CodeGenTest, (*TCodeGenTest)(nil))
+	var buf bytes.Buffer
+	if err := GenerateCSharp(&buf, TCodeGenOptions{ClassIds: codeGenTestClasses}); err != nil {
+		t.Fatalf("GenerateCSharp() error = %v", err)
+	}
+	golden := filepath.Join("testdata", "csharp", "Protocol.cs")
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("GenerateCSharp() output differs from %s, run go test -update", golden)
+	}
+
+	if err := GenerateCSharp(&buf, TCodeGenOptions{ClassIds: []uint32{ClassID_Test + 100}}); err == nil {
+		t.Errorf("GenerateCSharp() expected error for unregistered classId")
+	}
+}
+
+// TestCSharpRoundTrip 用生成的 C# 代码解析 Go 的序列化数据,重新序列化后必须与原数据一致
+func TestCSharpRoundTrip(t *testing.T) {
+	dotnet, err := exec.LookPath("dotnet")
+	if err != nil || testing.Short() {
+		t.Skip("dotnet not found or -short")
+	}
+	MustRegisterDataClass(ClassID_CodeGenTest, (*TCodeGenTest)(nil))
+	dir := t.TempDir()
+	for _, name := range []string{"Program.cs", "RoundTrip.csproj"} {
+		src, err := os.ReadFile(filepath.Join("testdata", "csharp", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := os.Create(filepath.Join(dir, "Protocol.cs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = GenerateCSharp(f, TCodeGenOptions{ClassIds: codeGenTestClasses})
+	f.Close()
+	if err != nil {
+		t.Fatalf("GenerateCSharp() error = %v", err)
+	}
+
+	long := newCodeGenTestObj()
+	long.List = make([]string, 300)
+	for i := range long.List {
+		long.List[i] = strings.Repeat("x", 300)
+	}
+	long.Log = strings.Repeat("日志", 20000)
+	var input []string
+	var want [][]byte
+	for _, msg := range []IMsg{newMapInfoTestObj(), newCodeGenTestObj(), &TCodeGenTest{}, long} {
+		data, err := Marshal(msg)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		input = append(input, hex.EncodeToString(data))
+		want = append(want, data)
+	}
+	if !bytes.Equal(want[0], mapInfoTestData) {
+		t.Fatalf("Marshal() = %v, want %v", want[0], mapInfoTestData)
+	}
+
+	cmd := exec.Command(dotnet, "run", "--project", dir)
+	cmd.Env = append(os.Environ(), "DOTNET_CLI_TELEMETRY_OPTOUT=1", "DOTNET_NOLOGO=1")
+	cmd.Stdin = strings.NewReader(strings.Join(input, "\n") + "\n")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("dotnet run: %v\n%s", err, out)
+	}
+	lines := strings.Fields(string(out))
+	if len(lines) != len(want) {
+		t.Fatalf("dotnet run output %d lines, want %d\n%s", len(lines), len(want), out)
+	}
+	for i, line := range lines {
+		got, err := hex.DecodeString(line)
+		if err != nil {
+			t.Fatalf("dotnet run output: %v\n%s", err, out)
+		}
+		if !bytes.Equal(got, want[i]) {
+			t.Errorf("C# round trip %d = %v, want %v", i, got, want[i])
+		}
+	}
+}
@@ -0,0 +1,695 @@
+package protocol
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// GenerateTypeScript 根据默认注册表的注册信息生成 TypeScript 代码
+func GenerateTypeScript(w io.Writer, opts TCodeGenOptions) error {
+	return defaultRegistry.GenerateTypeScript(w, opts)
+}
+
+// GenerateTypeScript 根据 reg 的注册信息生成 TypeScript 代码
+// 数值使用 DataView 按小端读写, 64 位整数(包括 int/uint/uintptr)对应 bigint
+func (reg *Registry) GenerateTypeScript(w io.Writer, opts TCodeGenOptions) error {
+	classes, err := reg.codeGenClasses(opts)
+	if err != nil {
+		return err
+	}
+	g := &tsGenerator{reg: reg}
+	g.printf("// Code generated by goprotocol. DO NOT EDIT.\n\n")
+	g.buf.WriteString(tsRuntime)
+
+	g.printf("\n")
+	for _, rtti := range classes {
+		g.printf("export const %s = %d;\n", classIdName(rtti), rtti.ClassId)
+	}
+
+	g.printf("\nexport function createMessage(classId: number): IProtocolMessage | null {\n")
+	g.printf("  switch (classId) {\n")
+	for _, rtti := range classes {
+		g.printf("    case %s:\n      return new %s();\n", classIdName(rtti), className(rtti))
+	}
+	g.printf("    default:\n      return null;\n  }\n}\n\n")
+	g.printf("// readAny 读取 interface 成员中存放的协议, nil 返回 null\n")
+	g.printf("export function readAny(r: ProtocolReader): IProtocolMessage | null {\n")
+	g.printf("  const h = r.peekHeader();\n")
+	g.printf("  if (h.isNil) {\n    r.readNil();\n    return null;\n  }\n")
+	g.printf("  const m = createMessage(h.classId);\n")
+	g.printf("  if (m === null) {\n    throw new ProtocolError(\"unknown classId \" + h.classId);\n  }\n")
+	g.printf("  m.deserialize(r);\n  return m;\n}\n")
+
+	for _, rtti := range classes {
+		if err := g.class(rtti); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(g.buf.Bytes())
+	return err
+}
+
+type tsGenerator struct {
+	reg    *Registry
+	buf    bytes.Buffer
+	indent int
+	long   bool // 正在生成的成员带有 long tag,字符串使用 uint32 长度
+}
+
+func (g *tsGenerator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// line 按当前缩进输出一行
+func (g *tsGenerator) line(format string, args ...interface{}) {
+	if format != "" {
+		g.buf.WriteString(strings.Repeat("  ", g.indent))
+		fmt.Fprintf(&g.buf, format, args...)
+	}
+	g.buf.WriteByte('\n')
+}
+
+// stringMethod 字符串的读写方法, op 为 write/read
+func (g *tsGenerator) stringMethod(op string) string {
+	if g.long {
+		return op + "LongString"
+	}
+	return op + "String"
+}
+
+func (g *tsGenerator) class(rtti *TRegRttiData) error {
+	name := className(rtti)
+	g.printf("\nexport class %s implements IProtocolMessage {\n", name)
+	g.indent = 1
+	for i := range rtti.FieldData {
+		field := &rtti.FieldData[i]
+		tp, err := g.tsType(field.rType)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %v", name, field.Name, err)
+		}
+		g.line("%s: %s = %s;", codeGenFieldName(field, i), tp, g.tsZero(field.rType))
+	}
+	g.line("")
+	g.line("get protocolClassId(): number {")
+	g.line("  return %s;", classIdName(rtti))
+	g.line("}")
+
+	g.line("")
+	g.line("serialize(w: ProtocolWriter): void {")
+	g.indent++
+	g.line("const start = w.beginStruct(this.protocolClassId);")
+	for i := range rtti.FieldData {
+		field := &rtti.FieldData[i]
+		g.long = field.LongString
+		g.write("this."+codeGenFieldName(field, i), field.rType, 0)
+	}
+	g.line("w.endStruct(start);")
+	g.indent--
+	g.line("}")
+
+	g.line("")
+	g.line("deserialize(r: ProtocolReader): void {")
+	g.indent++
+	for i := range rtti.FieldData {
+		field := &rtti.FieldData[i]
+		g.line("this.%s = %s;", codeGenFieldName(field, i), g.tsZero(field.rType))
+	}
+	g.line("const h = r.beginStruct(this.protocolClassId);")
+	for i := range rtti.FieldData {
+		field := &rtti.FieldData[i]
+		g.line("if (r.more(h)) {")
+		g.indent++
+		g.long = field.LongString
+		g.read("this."+codeGenFieldName(field, i), field.rType, 0)
+		g.indent--
+		g.line("}")
+	}
+	g.line("r.endStruct(h);")
+	g.indent--
+	g.line("}")
+	g.printf("}\n")
+	return nil
+}
+
+// tsPodTypes 数值类型对应的 TypeScript 类型和读写方法的后缀
+var tsPodTypes = map[reflect.Kind][2]string{
+	reflect.Bool:    {"boolean", "Bool"},
+	reflect.Int8:    {"number", "Int8"},
+	reflect.Uint8:   {"number", "Uint8"},
+	reflect.Int16:   {"number", "Int16"},
+	reflect.Uint16:  {"number", "Uint16"},
+	reflect.Int32:   {"number", "Int32"},
+	reflect.Uint32:  {"number", "Uint32"},
+	reflect.Int64:   {"bigint", "Int64"},
+	reflect.Uint64:  {"bigint", "Uint64"},
+	reflect.Int:     {"bigint", "Int64"},
+	reflect.Uint:    {"bigint", "Uint64"},
+	reflect.Uintptr: {"bigint", "Uint64"},
+	reflect.Float32: {"number", "Float32"},
+	reflect.Float64: {"number", "Float64"},
+}
+
+// tsType Go 类型对应的 TypeScript 类型
+func (g *tsGenerator) tsType(tp reflect.Type) (string, error) {
+	if pod, ok := tsPodTypes[tp.Kind()]; ok {
+		return pod[0], nil
+	}
+	switch tp.Kind() {
+	case reflect.String:
+		return "string", nil
+	case reflect.Struct, reflect.Ptr:
+		rtti, err := g.reg.codeGenStruct(tp)
+		if err != nil {
+			return "", err
+		}
+		if tp.Kind() == reflect.Ptr {
+			return className(rtti) + " | null", nil
+		}
+		return className(rtti), nil
+	case reflect.Interface:
+		return "IProtocolMessage | null", nil
+	case reflect.Slice, reflect.Array:
+		elem, err := g.tsType(tp.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "Array<" + elem + ">", nil
+	case reflect.Map:
+		key, err := g.tsType(tp.Key())
+		if err != nil {
+			return "", err
+		}
+		val, err := g.tsType(tp.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "Map<" + key + ", " + val + ">", nil
+	}
+	return "", fmt.Errorf("unsupported type %s", tp)
+}
+
+// tsZero 与 Go 零值对应的初始值
+func (g *tsGenerator) tsZero(tp reflect.Type) string {
+	switch tp.Kind() {
+	case reflect.Bool:
+		return "false"
+	case reflect.String:
+		return `""`
+	case reflect.Struct:
+		rtti, _ := g.reg.codeGenStruct(tp)
+		return "new " + className(rtti) + "()"
+	case reflect.Ptr, reflect.Interface:
+		return "null"
+	case reflect.Slice:
+		return "[]"
+	case reflect.Array:
+		return fmt.Sprintf("Array.from({ length: %d }, () => %s)", tp.Len(), g.tsZero(tp.Elem()))
+	case reflect.Map:
+		return "new Map()"
+	}
+	if tsPodTypes[tp.Kind()][0] == "bigint" {
+		return "0n"
+	}
+	return "0"
+}
+
+func (g *tsGenerator) write(expr string, tp reflect.Type, depth int) {
+	if pod, ok := tsPodTypes[tp.Kind()]; ok {
+		g.line("w.write%s(%s);", pod[1], expr)
+		return
+	}
+	switch tp.Kind() {
+	case reflect.String:
+		g.line("w.%s(%s);", g.stringMethod("write"), expr)
+	case reflect.Struct:
+		g.line("(%s ?? %s).serialize(w);", expr, g.tsZero(tp))
+	case reflect.Ptr, reflect.Interface:
+		g.line("w.writeMessage(%s);", expr)
+	case reflect.Slice:
+		a, e := fmt.Sprintf("a%d", depth), fmt.Sprintf("e%d", depth)
+		g.line("{")
+		g.indent++
+		g.line("const %s = %s ?? [];", a, expr)
+		g.line("w.writeArrayLen(%s.length);", a)
+		g.line("for (const %s of %s) {", e, a)
+		g.indent++
+		g.write(e, tp.Elem(), depth+1)
+		g.indent--
+		g.line("}")
+		g.indent--
+		g.line("}")
+	case reflect.Array: // Go 的数组总是写入声明的长度
+		a, i, e := fmt.Sprintf("a%d", depth), fmt.Sprintf("i%d", depth), fmt.Sprintf("e%d", depth)
+		g.line("{")
+		g.indent++
+		g.line("const %s = %s ?? [];", a, expr)
+		g.line("w.writeArrayLen(%d);", tp.Len())
+		g.line("for (let %s = 0; %s < %d; %s++) {", i, i, tp.Len(), i)
+		g.indent++
+		g.line("const %s = %s[%s] ?? %s;", e, a, i, g.tsZero(tp.Elem()))
+		g.write(e, tp.Elem(), depth+1)
+		g.indent--
+		g.line("}")
+		g.indent--
+		g.line("}")
+	case reflect.Map:
+		m, k := fmt.Sprintf("m%d", depth), fmt.Sprintf("k%d", depth)
+		val, _ := g.tsType(tp.Elem())
+		g.line("{")
+		g.indent++
+		g.line("const %s = %s ?? new Map();", m, expr)
+		g.line("w.writeArrayLen(%s.size);", m)
+		g.line("for (const %s of sortedKeys(%s)) {", k, m)
+		g.indent++
+		g.write(k, tp.Key(), depth+1)
+		g.write(fmt.Sprintf("(%s.get(%s) as %s)", m, k, val), tp.Elem(), depth+1)
+		g.indent--
+		g.line("}")
+		g.indent--
+		g.line("}")
+	}
+}
+
+// readExpr 读取数值、字符串和协议的表达式
+func (g *tsGenerator) readExpr(tp reflect.Type) string {
+	if pod, ok := tsPodTypes[tp.Kind()]; ok {
+		return "r.read" + pod[1] + "()"
+	}
+	switch tp.Kind() {
+	case reflect.String:
+		return "r." + g.stringMethod("read") + "()"
+	case reflect.Struct:
+		return "r.readStruct(" + g.tsZero(tp) + ")"
+	case reflect.Ptr:
+		rtti, _ := g.reg.codeGenStruct(tp)
+		return "r.readNil() ? null : r.readStruct(new " + className(rtti) + "())"
+	}
+	return "readAny(r)"
+}
+
+// read 输出读取到 target 的代码,切片、数组和 map 的 target 必须是空的容器
+func (g *tsGenerator) read(target string, tp reflect.Type, depth int) {
+	switch tp.Kind() {
+	case reflect.Slice:
+		n, i, e := fmt.Sprintf("n%d", depth), fmt.Sprintf("i%d", depth), fmt.Sprintf("e%d", depth)
+		g.line("const %s = r.readArrayLen();", n)
+		g.line("for (let %s = 0; %s < %s; %s++) {", i, i, n, i)
+		g.indent++
+		if isContainer(tp.Elem()) {
+			g.readElem(e, tp.Elem(), depth+1)
+			g.line("%s.push(%s);", target, e)
+		} else {
+			g.line("%s.push(%s);", target, g.readExpr(tp.Elem()))
+		}
+		g.indent--
+		g.line("}")
+	case reflect.Array: // 数据中的元素个数可能与数组长度不一致,多余的元素读取后丢弃
+		n, i, e := fmt.Sprintf("n%d", depth), fmt.Sprintf("i%d", depth), fmt.Sprintf("e%d", depth)
+		g.line("const %s = r.readArrayLen();", n)
+		g.line("for (let %s = 0; %s < %s; %s++) {", i, i, n, i)
+		g.indent++
+		g.readElem(e, tp.Elem(), depth+1)
+		g.line("if (%s < %d) {", i, tp.Len())
+		g.line("  %s[%s] = %s;", target, i, e)
+		g.line("}")
+		g.indent--
+		g.line("}")
+	case reflect.Map:
+		n, i, k, v := fmt.Sprintf("n%d", depth), fmt.Sprintf("i%d", depth), fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth)
+		g.line("const %s = r.readArrayLen();", n)
+		g.line("for (let %s = 0; %s < %s; %s++) {", i, i, n, i)
+		g.indent++
+		g.line("const %s = %s;", k, g.readExpr(tp.Key()))
+		if isContainer(tp.Elem()) {
+			g.readElem(v, tp.Elem(), depth+1)
+			g.line("%s.set(%s, %s);", target, k, v)
+		} else {
+			g.line("%s.set(%s, %s);", target, k, g.readExpr(tp.Elem()))
+		}
+		g.indent--
+		g.line("}")
+	default:
+		g.line("%s = %s;", target, g.readExpr(tp))
+	}
+}
+
+// readElem 输出读取元素到常量 e 的代码,嵌套的数组、切片先创建零值再读取
+func (g *tsGenerator) readElem(e string, tp reflect.Type, depth int) {
+	if !isContainer(tp) {
+		g.line("const %s = %s;", e, g.readExpr(tp))
+		return
+	}
+	ts, _ := g.tsType(tp)
+	g.line("const %s: %s = %s;", e, ts, g.tsZero(tp))
+	g.read(e, tp, depth)
+}
+
+func isContainer(tp reflect.Type) bool {
+	kind := tp.Kind()
+	return kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map
+}
+
+// tsRuntime 生成代码依赖的读写实现
+const tsRuntime = `export class ProtocolError extends Error {}
+
+export interface IProtocolMessage {
+  readonly protocolClassId: number;
+  serialize(w: ProtocolWriter): void;
+  deserialize(r: ProtocolReader): void;
+}
+
+// 数据头: 标记(uint16) + classId(uint16/uint32) + 数据长度(uint16/uint32,包含数据头)
+export interface ProtocolHeader {
+  start: number;
+  headerLength: number;
+  classId: number;
+  dataLength: number;
+  isNil: boolean;
+}
+
+export const SignFlag = 0x6d98;
+
+const textEncoder = new TextEncoder();
+const textDecoder = new TextDecoder("utf-8", { fatal: true });
+
+export class ProtocolWriter {
+  private buf = new Uint8Array(256);
+  private view = new DataView(this.buf.buffer);
+  private len = 0;
+
+  get length(): number {
+    return this.len;
+  }
+
+  toBytes(): Uint8Array {
+    return this.buf.slice(0, this.len);
+  }
+
+  // grow 预留 n 个字节,返回写入位置,扩容后 view 会被替换
+  private grow(n: number): number {
+    if (this.len + n > this.buf.length) {
+      const buf = new Uint8Array(Math.max(this.buf.length * 2, this.len + n));
+      buf.set(this.buf.subarray(0, this.len));
+      this.buf = buf;
+      this.view = new DataView(buf.buffer);
+    }
+    const pos = this.len;
+    this.len += n;
+    return pos;
+  }
+
+  writeBool(v: boolean): void {
+    this.writeUint8(v ? 1 : 0);
+  }
+  writeInt8(v: number): void {
+    const pos = this.grow(1);
+    this.view.setInt8(pos, v);
+  }
+  writeUint8(v: number): void {
+    const pos = this.grow(1);
+    this.view.setUint8(pos, v);
+  }
+  writeInt16(v: number): void {
+    const pos = this.grow(2);
+    this.view.setInt16(pos, v, true);
+  }
+  writeUint16(v: number): void {
+    const pos = this.grow(2);
+    this.view.setUint16(pos, v, true);
+  }
+  writeInt32(v: number): void {
+    const pos = this.grow(4);
+    this.view.setInt32(pos, v, true);
+  }
+  writeUint32(v: number): void {
+    const pos = this.grow(4);
+    this.view.setUint32(pos, v, true);
+  }
+  writeInt64(v: bigint): void {
+    const pos = this.grow(8);
+    this.view.setBigInt64(pos, BigInt.asIntN(64, v), true);
+  }
+  writeUint64(v: bigint): void {
+    const pos = this.grow(8);
+    this.view.setBigUint64(pos, BigInt.asUintN(64, v), true);
+  }
+  writeFloat32(v: number): void {
+    const pos = this.grow(4);
+    this.view.setFloat32(pos, v, true);
+  }
+  writeFloat64(v: number): void {
+    const pos = this.grow(8);
+    this.view.setFloat64(pos, v, true);
+  }
+
+  writeBytes(b: Uint8Array): void {
+    const pos = this.grow(b.length);
+    this.buf.set(b, pos);
+  }
+
+  // 字符串: utf8长度(uint16) + utf8内容
+  writeString(s: string): void {
+    const b = textEncoder.encode(s ?? "");
+    if (b.length > 0xffff) {
+      throw new ProtocolError("string is too long");
+    }
+    this.writeUint16(b.length);
+    this.writeBytes(b);
+  }
+
+  // 长字符串: utf8长度(uint32) + utf8内容,对应带 long tag 的成员
+  writeLongString(s: string): void {
+    const b = textEncoder.encode(s ?? "");
+    this.writeUint32(b.length);
+    this.writeBytes(b);
+  }
+
+  writeArrayLen(n: number): void {
+    this.writeUint32(n);
+  }
+
+  // nil 指针和 interface 写入空数据头
+  writeEmptyHeader(): void {
+    this.writeUint16(SignFlag);
+    this.writeUint16(0);
+    this.writeUint16(6);
+  }
+
+  writeMessage(m: IProtocolMessage | null | undefined): void {
+    if (m === null || m === undefined) {
+      this.writeEmptyHeader();
+    } else {
+      m.serialize(this);
+    }
+  }
+
+  // beginStruct 写入数据头,返回数据头的位置,写完成员后调用 endStruct 回填数据长度
+  beginStruct(classId: number): number {
+    const start = this.len;
+    if (classId > 0xffff) {
+      this.writeUint16(SignFlag | 1);
+      this.writeUint32(classId);
+    } else {
+      this.writeUint16(SignFlag);
+      this.writeUint16(classId);
+    }
+    this.writeUint16(0);
+    return start;
+  }
+
+  endStruct(start: number): void {
+    const sign = this.view.getUint16(start, true);
+    const lenPos = start + ((sign & 1) !== 0 ? 6 : 4);
+    const dataLength = this.len - start;
+    if (dataLength > 0xffff) {
+      // 数据长度改为 uint32
+      const end = this.len;
+      this.grow(2);
+      this.buf.copyWithin(lenPos + 4, lenPos + 2, end);
+      this.view.setUint16(start, sign | 2, true);
+      this.view.setUint32(lenPos, dataLength + 2, true);
+    } else {
+      this.view.setUint16(lenPos, dataLength, true);
+    }
+  }
+}
+
+// sortedKeys 按 key 升序排列, 字符串按 utf8 编码比较, 与 Go 保持一致
+export function sortedKeys<K>(m: Map<K, unknown>): K[] {
+  return Array.from(m.keys()).sort(compareKeys);
+}
+
+function compareKeys(a: unknown, b: unknown): number {
+  if (typeof a === "string" && typeof b === "string") {
+    const x = textEncoder.encode(a);
+    const y = textEncoder.encode(b);
+    for (let i = 0; i < x.length && i < y.length; i++) {
+      if (x[i] !== y[i]) {
+        return x[i] < y[i] ? -1 : 1;
+      }
+    }
+    return x.length - y.length;
+  }
+  if (typeof a === "number" && typeof b === "number") {
+    if (Number.isNaN(a) || Number.isNaN(b)) {
+      return Number.isNaN(a) ? (Number.isNaN(b) ? 0 : -1) : 1;
+    }
+  }
+  const x = a as number | bigint;
+  const y = b as number | bigint;
+  return x < y ? -1 : x > y ? 1 : 0;
+}
+
+export class ProtocolReader {
+  private readonly view: DataView;
+  private off: number;
+  private readonly end: number;
+
+  constructor(private readonly data: Uint8Array) {
+    this.view = new DataView(data.buffer, data.byteOffset, data.byteLength);
+    this.off = 0;
+    this.end = data.length;
+  }
+
+  get position(): number {
+    return this.off;
+  }
+
+  get remaining(): number {
+    return this.end - this.off;
+  }
+
+  private next(n: number): number {
+    if (n < 0 || this.remaining < n) {
+      throw new ProtocolError("data is truncated");
+    }
+    const pos = this.off;
+    this.off += n;
+    return pos;
+  }
+
+  readBool(): boolean {
+    return this.readUint8() !== 0;
+  }
+  readInt8(): number {
+    return this.view.getInt8(this.next(1));
+  }
+  readUint8(): number {
+    return this.view.getUint8(this.next(1));
+  }
+  readInt16(): number {
+    return this.view.getInt16(this.next(2), true);
+  }
+  readUint16(): number {
+    return this.view.getUint16(this.next(2), true);
+  }
+  readInt32(): number {
+    return this.view.getInt32(this.next(4), true);
+  }
+  readUint32(): number {
+    return this.view.getUint32(this.next(4), true);
+  }
+  readInt64(): bigint {
+    return this.view.getBigInt64(this.next(8), true);
+  }
+  readUint64(): bigint {
+    return this.view.getBigUint64(this.next(8), true);
+  }
+  readFloat32(): number {
+    return this.view.getFloat32(this.next(4), true);
+  }
+  readFloat64(): number {
+    return this.view.getFloat64(this.next(8), true);
+  }
+
+  readString(): string {
+    const n = this.readUint16();
+    const pos = this.next(n);
+    return textDecoder.decode(this.data.subarray(pos, pos + n));
+  }
+  readLongString(): string {
+    const n = this.readUint32();
+    const pos = this.next(n);
+    return textDecoder.decode(this.data.subarray(pos, pos + n));
+  }
+
+  // readArrayLen 每个元素至少占用一个字节,超过剩余数据长度时视为数据错误
+  readArrayLen(): number {
+    const n = this.readUint32();
+    if (n > this.remaining) {
+      throw new ProtocolError("array length out of range");
+    }
+    return n;
+  }
+
+  peekHeader(): ProtocolHeader {
+    const pos = this.off;
+    const h = this.readHeader();
+    this.off = pos;
+    return h;
+  }
+
+  readHeader(): ProtocolHeader {
+    const start = this.off;
+    const sign = this.readUint16();
+    if ((sign & 0xfffc) !== SignFlag) {
+      throw new ProtocolError("bad sign");
+    }
+    let headerLength = 6;
+    let classId: number;
+    let dataLength: number;
+    if ((sign & 1) !== 0) {
+      headerLength += 2;
+      classId = this.readUint32();
+    } else {
+      classId = this.readUint16();
+    }
+    if ((sign & 2) !== 0) {
+      headerLength += 2;
+      dataLength = this.readUint32();
+    } else {
+      dataLength = this.readUint16();
+    }
+    if (dataLength < headerLength || dataLength > this.end - start) {
+      throw new ProtocolError("bad data length");
+    }
+    return { start, headerLength, classId, dataLength, isNil: dataLength === headerLength };
+  }
+
+  // readNil 如果是 nil 写入的空数据头,跳过它并返回 true
+  readNil(): boolean {
+    const h = this.peekHeader();
+    if (!h.isNil) {
+      return false;
+    }
+    this.off = h.start + h.dataLength;
+    return true;
+  }
+
+  beginStruct(classId: number): ProtocolHeader {
+    const h = this.readHeader();
+    if (!h.isNil && h.classId !== classId) {
+      throw new ProtocolError("classId mismatch: want " + classId + ", got " + h.classId);
+    }
+    return h;
+  }
+
+  // more 旧版本的数据成员较少,读取成员前需要检查
+  more(h: ProtocolHeader): boolean {
+    return this.off < h.start + h.dataLength;
+  }
+
+  // endStruct 跳过新版本追加的成员
+  endStruct(h: ProtocolHeader): void {
+    this.off = h.start + h.dataLength;
+  }
+
+  readStruct<T extends IProtocolMessage>(m: T): T {
+    m.deserialize(this);
+    return m;
+  }
+}
+`
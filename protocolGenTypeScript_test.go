@@ -0,0 +1,34 @@
+package protocol
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateTypeScript(t *testing.T) {
+	MustRegisterDataClass(ClassID_CodeGenTest, (*TCodeGenTest)(nil))
+	var buf bytes.Buffer
+	if err := GenerateTypeScript(&buf, TCodeGenOptions{ClassIds: codeGenTestClasses}); err != nil {
+		t.Fatalf("GenerateTypeScript() error = %v", err)
+	}
+	golden := filepath.Join("testdata", "typescript", "protocol.ts")
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("GenerateTypeScript() output differs from %s, run go test -update", golden)
+	}
+
+	buf.Reset()
+	if err := GenerateTypeScript(&buf, TCodeGenOptions{ClassIds: codeGenTestClasses}); err != nil || !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("GenerateTypeScript() output isn't stable, error = %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package protocol
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+// TIntMode 注册表处理 int、uint、uintptr 成员的方式,这些类型的长度与平台有关
+type TIntMode int
+
+const (
+	IntFixed64 TIntMode = iota // 固定按 64 位小端读写,解码时超出本机范围返回 ErrIntRange,默认方式
+	IntReject                  // 注册时返回错误,只允许使用固定长度的数值类型
+)
+
+// nativeInt64 int、uint、uintptr 在本机是 64 位,可以直接按内存读写
+const nativeInt64 = unsafe.Sizeof(int(0)) == 8 && unsafe.Sizeof(uintptr(0)) == 8
+
+// SetIntMode 设置 int、uint、uintptr 成员的处理方式,只影响之后注册的协议
+func (reg *Registry) SetIntMode(mode TIntMode) {
+	reg.mu.Lock()
+	reg.intMode = mode
+	reg.mu.Unlock()
+}
+
+// IntMode 返回 reg 处理 int、uint、uintptr 成员的方式
+func (reg *Registry) IntMode() TIntMode {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.intMode
+}
+
+// isVarInt 是否是长度与平台有关的整数类型
+func isVarInt(kd reflect.Kind) bool {
+	return kd == reflect.Int || kd == reflect.Uint || kd == reflect.Uintptr
+}
+
+// isMemPod 是否是可以直接按内存读写的数值类型, 32 位平台上的 int、uint、uintptr 需要转换
+func isMemPod(kd reflect.Kind) bool {
+	return isPod(kd) && (nativeInt64 || !isVarInt(kd))
+}
+
+// checkIntMode IntReject 方式下成员本身或数组、切片、map 的元素不能是 int、uint、uintptr
+func checkIntMode(tp reflect.Type, mode TIntMode) error {
+	if mode == IntReject && hasKind(tp, isVarInt) {
+		return fmt.Errorf("platform dependent type %s is rejected by the registry, use int32/int64 or uint32/uint64", tp)
+	}
+	return nil
+}
+
+// writeVarInt 把 int、uint、uintptr 按 64 位写入
+func (b *ProtocolWritter) writeVarInt(kind reflect.Kind, ptr unsafe.Pointer) {
+	switch kind {
+	case reflect.Int:
+		b.WriteInt64(int64(*(*int)(ptr)))
+	case reflect.Uint:
+		b.WriteUint64(uint64(*(*uint)(ptr)))
+	case reflect.Uintptr:
+		b.WriteUint64(uint64(*(*uintptr)(ptr)))
+	}
+}
+
+// readVarInt 读取 64 位整数到 int、uint、uintptr 中,超出本机范围时返回 ErrIntRange
+func (r *ProtocolReader) readVarInt(kind reflect.Kind, ptr unsafe.Pointer) bool {
+	lo, ok1 := r.readUint32()
+	hi, ok2 := r.readUint32()
+	if !ok1 || !ok2 {
+		return r.fail(ErrTruncated)
+	}
+	v := uint64(hi)<<32 | uint64(lo)
+	switch kind {
+	case reflect.Int:
+		if int64(int(int64(v))) != int64(v) {
+			return r.fail(fmt.Errorf("%w: %d overflows int", ErrIntRange, int64(v)))
+		}
+		*(*int)(ptr) = int(int64(v))
+	case reflect.Uint:
+		if uint64(uint(v)) != v {
+			return r.fail(fmt.Errorf("%w: %d overflows uint", ErrIntRange, v))
+		}
+		*(*uint)(ptr) = uint(v)
+	case reflect.Uintptr:
+		if uint64(uintptr(v)) != v {
+			return r.fail(fmt.Errorf("%w: %d overflows uintptr", ErrIntRange, v))
+		}
+		*(*uintptr)(ptr) = uintptr(v)
+	}
+	return true
+}
+
+// lenInt 数据中的长度转换为 int, 32 位平台上超出 int 范围时返回 math.MaxInt,由长度检查报错
+func lenInt(n uint32) int {
+	if uint64(n) > math.MaxInt {
+		return math.MaxInt
+	}
+	return int(n)
+}
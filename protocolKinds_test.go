@@ -0,0 +1,85 @@
+package protocol_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	protocol "github.com/raochq/goprotocol"
+	"github.com/raochq/goprotocol/cmd/goprotocol-gen/testdata/kinds"
+)
+
+func newAllKindsTestObj() *kinds.TAllKinds {
+	slot := protocol.TSlotData{Idx: 1, SlotType: 2, BoSit: true, PlaceItemId: 3, SitPersonId: 4}
+	return &kinds.TAllKinds{
+		A: -1, B: true, D: 0xFFFF, E: 3.14, Id: 7, Name: "name",
+		Bytes:  []byte{1, 2, 3},
+		Arr:    [3]int64{1, -2, 3},
+		Strs:   []string{"", "a", "bc"},
+		Slots:  []protocol.TSlotData{slot, {}},
+		PSlots: [2]*protocol.TSlotData{nil, &slot},
+		Items:  []interface{}{&kinds.TInner{X: 1, Y: "x"}, &slot, nil, int32(5), "s"},
+		Ptr:    &kinds.TInner{X: 2, Y: "p"},
+		Inner:  kinds.TInner{X: 3, Y: "i"},
+		Any:    &kinds.TInner{X: 4},
+		Msg:    &slot,
+		ById:   map[kinds.ItemId]kinds.TInner{3: {X: 3}, 1: {X: 1}, 2: {Y: "2"}},
+		ByName: map[string]*kinds.TInner{"b": {X: 2}, "a": nil, "c": {Y: "c"}},
+		Flags:  map[uint8]interface{}{9: int32(9), 1: &slot, 5: "five"},
+		Log:    "log",
+		Docs:   map[int32]string{2: "two", -1: "minus"},
+		Image:  kinds.Blob{0xFF, 0},
+		Key:    [16]byte{15: 1},
+		Grid:   [][]int32{{1, 2}, {}, {3}},
+		Matrix: [4][4]float32{{1}, {0, 2}, {}, {0, 0, 0, 4}},
+		Chunks: [][]byte{{1}, {2, 3}},
+		Tags:   map[int32][]string{1: {"a", "b"}, 0: {"z"}},
+		Toggle: map[bool]kinds.Name{true: "on", false: "off"},
+		TInner: kinds.TInner{X: 5, Y: "embedded"},
+		Level:  6,
+	}
+}
+
+// TestGeneratedKinds 编译 goprotocol-gen 为所有成员类型生成的代码,检查与反射方式的编码结果相同
+func TestGeneratedKinds(t *testing.T) {
+	const ClassID_AllKinds, ClassID_Inner = protocol.ClassID_Test + 18, protocol.ClassID_Test + 19
+	reg := protocol.NewRegistry()
+	reg.MustRegister(ClassID_AllKinds, (*kinds.TAllKinds)(nil))
+	reg.MustRegister(ClassID_Inner, (*kinds.TInner)(nil))
+	reg.MustRegister(protocol.ClassID_SlotData, (*protocol.TSlotData)(nil))
+	generated := []protocol.IMsg{(*kinds.TAllKinds)(nil), (*kinds.TInner)(nil), (*protocol.TSlotData)(nil)}
+	for _, msg := range generated {
+		if !protocol.UsesGenerated(reg, msg) {
+			t.Fatalf("%T doesn't use the generated code", msg)
+		}
+	}
+
+	for _, testobj := range []*kinds.TAllKinds{newAllKindsTestObj(), {}} {
+		gen, err := reg.Marshal(testobj)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		var refl []byte
+		var genObj, reflObj interface{}
+		var reflErr, genErr error
+		protocol.WithoutGenerated(reg, func() {
+			refl, err = reg.Marshal(testobj)
+			reflObj, reflErr = reg.Unmarshal(gen)
+		}, generated...)
+		if err != nil {
+			t.Fatalf("Marshal() without generated code error = %v", err)
+		}
+		if !bytes.Equal(gen, refl) {
+			t.Fatalf("generated Marshal() = %v\nwant %v", gen, refl)
+		}
+		if size, err := reg.Size(testobj); err != nil || size != len(gen) {
+			t.Errorf("Size() = %d, %v, want %d", size, err, len(gen))
+		}
+		if genObj, genErr = reg.Unmarshal(refl); genErr != nil || reflErr != nil {
+			t.Fatalf("Unmarshal() error = %v, without generated code %v", genErr, reflErr)
+		}
+		if !reflect.DeepEqual(genObj, reflObj) {
+			t.Errorf("generated Unmarshal() = %+v\nwant %+v", genObj, reflObj)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package protocol
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DecoderLimits 解码时的资源限制,用于防止恶意数据导致内存耗尽或栈溢出
+// 值为 0 的项不做限制,只有嵌套深度始终不超过 maxNestingDepth
+type DecoderLimits struct {
+	MaxBytes       int // 单条消息的最大长度(包含数据头)
+	MaxSliceLen    int // 数组、切片的最大长度和 map 的最大元素个数,不包括 []byte 和 [N]byte
+	MaxDepth       int // 结构体、指针、interface 的最大嵌套深度,为 0 或超过 maxNestingDepth 时使用 maxNestingDepth
+	MaxStringBytes int // 字符串的最大字节数
+	MaxObjects     int // 一条消息中带数据头的对象(消息本身及嵌套的结构体、指针、interface)的最大个数,不限制 Decoder 读取的消息条数
+	MaxBlobBytes   int // []byte 和 [N]byte 成员的最大字节数
+}
+
+// DefaultDecoderLimits 注册表默认使用的解码限制
+var DefaultDecoderLimits = DecoderLimits{
+	MaxBytes:       64 << 20,
+	MaxSliceLen:    1 << 20,
+	MaxDepth:       64,
+	MaxStringBytes: 1 << 20,
+	MaxObjects:     1 << 20,
+	MaxBlobBytes:   16 << 20,
+}
+
+// maxNestingDepth 不受 DecoderLimits 影响的嵌套深度上限,保证解码恶意数据时不会栈溢出
+const maxNestingDepth = 10000
+
+// ErrLimitExceeded 解码的数据超出了 DecoderLimits 的限制
+type ErrLimitExceeded struct {
+	Limit string // 超出的限制项
+	Value int    // 数据中的值
+	Max   int    // 限制的最大值
+}
+
+func (e *ErrLimitExceeded) Error() string {
+	return fmt.Sprintf("decoder limit exceeded: %s %d > %d", e.Limit, e.Value, e.Max)
+}
+
+// SetDecoderLimits 设置之后创建的 ProtocolReader 和 Decoder 使用的解码限制
+func (reg *Registry) SetDecoderLimits(limits DecoderLimits) {
+	reg.mu.Lock()
+	reg.limits = limits
+	reg.mu.Unlock()
+}
+
+// DecoderLimits 返回 reg 的解码限制
+func (reg *Registry) DecoderLimits() DecoderLimits {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.limits
+}
+
+// SetLimits 设置 r 的解码限制
+func (r *ProtocolReader) SetLimits(limits DecoderLimits) { r.limits = limits }
+
+// SetLimits 设置 d 的解码限制
+func (d *Decoder) SetLimits(limits DecoderLimits) { d.limits = limits }
+
+// checkLimit value 超过 max 时记录错误并返回 false
+func (r *ProtocolReader) checkLimit(limit string, value, max int) bool {
+	if max > 0 && value > max {
+		return r.fail(&ErrLimitExceeded{Limit: limit, Value: value, Max: max})
+	}
+	return true
+}
+
+// enter 开始解析一个带数据头的对象,检查消息长度、嵌套深度和对象个数,成功后需要调用 leave
+func (r *ProtocolReader) enter(head *ProtocolDataHeader) bool {
+	if r.depth == 0 {
+		r.objects = 0
+		if !r.checkLimit("message bytes", lenInt(head.dataLength), r.limits.MaxBytes) {
+			return false
+		}
+	}
+	r.objects++
+	maxDepth := r.limits.MaxDepth
+	if maxDepth <= 0 || maxDepth > maxNestingDepth {
+		maxDepth = maxNestingDepth
+	}
+	if !r.checkLimit("nesting depth", r.depth+1, maxDepth) ||
+		!r.checkLimit("object count", r.objects, r.limits.MaxObjects) {
+		return false
+	}
+	r.depth++
+	return true
+}
+
+func (r *ProtocolReader) leave() { r.depth-- }
+
+// checkArrayLen 检查数组、切片或map的元素个数,每个元素至少占用 minSize 个字节
+func (r *ProtocolReader) checkArrayLen(n uint32, minSize int) bool {
+	if !r.checkLimit("slice length", lenInt(n), r.limits.MaxSliceLen) {
+		return false
+	}
+	if uint64(n)*uint64(minSize) > uint64(r.Len()) {
+		return r.fail(ErrTruncated)
+	}
+	return true
+}
+
+// checkFieldLen 检查数组、切片成员的元素个数, []byte 和 [N]byte 按字节数检查,同时检查 tag 中的 max
+func (r *ProtocolReader) checkFieldLen(field *TRegFieldOffsetData, n uint32) bool {
+	if !field.blob {
+		return r.checkArrayLen(n, minElemSize(field.arrayKind, field.arraySize, field.LongString))
+	}
+	return r.checkBlobLen(n, field.MaxLen)
+}
+
+// checkBlobLen 检查 []byte 和 [N]byte 的字节数, max 为 tag 中的 max
+func (r *ProtocolReader) checkBlobLen(n uint32, max int) bool {
+	if !r.checkLimit("blob bytes", lenInt(n), r.limits.MaxBlobBytes) || !r.checkLimit("max tag", lenInt(n), max) {
+		return false
+	}
+	if uint64(n) > uint64(r.Len()) {
+		return r.fail(ErrTruncated)
+	}
+	return true
+}
+
+// minElemSize 元素编码后的最小字节数, long 为成员的 long tag
+func minElemSize(kind reflect.Kind, size int, long bool) int {
+	switch {
+	case isVarInt(kind):
+		return 8
+	case isPod(kind):
+		return size
+	case kind == reflect.String && long:
+		return longStringLenSize
+	case kind == reflect.String:
+		return stringLenSize
+	case kind == reflect.Slice, kind == reflect.Array:
+		return arrayLenSize
+	}
+	return 6 // 空数据头
+}
@@ -0,0 +1,141 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TLimitNode 可以任意嵌套的结构,用于构造恶意数据
+type TLimitNode struct {
+	Name string
+	List []int32
+	Next *TLimitNode
+	Any  interface{}
+}
+
+func newLimitNodeList(depth int) *TLimitNode {
+	var node *TLimitNode
+	for i := 0; i < depth; i++ {
+		node = &TLimitNode{Next: node}
+	}
+	return node
+}
+
+func TestDecoderLimits(t *testing.T) {
+	const ClassID_LimitNode = ClassID_Test + 4
+	reg := NewRegistry()
+	for _, msg := range []struct {
+		classId uint32
+		msg     IMsg
+	}{{ClassID_LimitNode, (*TLimitNode)(nil)}, {ClassID_SlotData, (*TSlotData)(nil)}, {ClassID_MapInfo, (*TMapInfo)(nil)}} {
+		if err := reg.Register(msg.classId, msg.msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	marshal := func(msg IMsg) []byte {
+		data, err := reg.Marshal(msg)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+		return data
+	}
+	// 数据中的切片长度改为 n,但不附带元素
+	hugeSlice := func(n uint32) []byte {
+		data := marshal(&TLimitNode{List: []int32{1}})
+		binary.LittleEndian.PutUint32(data[8:], n) // 数据头(6) + Name(2)
+		return data
+	}
+
+	tests := []struct {
+		name   string
+		limits DecoderLimits
+		data   []byte
+		limit  string // 为空时期望解析成功
+	}{
+		{"default", DefaultDecoderLimits, marshal(newLimitNodeList(10)), ""},
+		{"unlimited", DecoderLimits{}, marshal(newLimitNodeList(100)), ""},
+		{"depth", DecoderLimits{MaxDepth: 5}, marshal(newLimitNodeList(6)), "nesting depth"},
+		{"depth ok", DecoderLimits{MaxDepth: 5}, marshal(newLimitNodeList(5)), ""},
+		{"depth cap", DecoderLimits{}, marshal(newLimitNodeList(maxNestingDepth + 1)), "nesting depth"},
+		{"depth cap ok", DecoderLimits{MaxDepth: maxNestingDepth * 2}, marshal(newLimitNodeList(maxNestingDepth)), ""},
+		{"interface depth", DecoderLimits{MaxDepth: 2}, marshal(&TLimitNode{Any: &TLimitNode{Any: &TLimitNode{}}}), "nesting depth"},
+		{"bytes", DecoderLimits{MaxBytes: 16}, marshal(&TLimitNode{Name: strings.Repeat("x", 16)}), "message bytes"},
+		{"string", DecoderLimits{MaxStringBytes: 3}, marshal(&TLimitNode{Name: "abcd"}), "string bytes"},
+		{"slice", DecoderLimits{MaxSliceLen: 3}, marshal(&TLimitNode{List: []int32{1, 2, 3, 4}}), "slice length"},
+		{"objects", DecoderLimits{MaxObjects: 3}, marshal(&TLimitNode{Any: &TSlotData{}, Next: &TLimitNode{Next: &TLimitNode{}}}), "object count"},
+		{"generated slice", DecoderLimits{MaxSliceLen: 1}, marshal(newMapInfoTestObj()), "slice length"},
+		{"generated string", DecoderLimits{MaxStringBytes: 1}, marshal(newMapInfoTestObj()), "string bytes"},
+		{"hostile slice", DefaultDecoderLimits, hugeSlice(0xFFFFFFFF), "slice length"},
+		{"hostile slice no limit", DecoderLimits{}, hugeSlice(0xFFFFFFFF), "-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := reg.NewProtocolReader(tt.data)
+			r.SetLimits(tt.limits)
+			_, err := r.readAny()
+			var limitErr *ErrLimitExceeded
+			switch {
+			case tt.limit == "":
+				if err != nil {
+					t.Errorf("readAny() error = %v", err)
+				}
+			case tt.limit == "-":
+				if err == nil || errors.As(err, &limitErr) {
+					t.Errorf("readAny() error = %v, want data error", err)
+				}
+			case !errors.As(err, &limitErr) || limitErr.Limit != tt.limit:
+				t.Errorf("readAny() error = %v, want %s limit exceeded", err, tt.limit)
+			}
+		})
+	}
+
+	reg.SetDecoderLimits(DecoderLimits{MaxDepth: 3})
+	if _, err := reg.Unmarshal(marshal(newLimitNodeList(4))); err == nil {
+		t.Errorf("Unmarshal() expected error with registry limits")
+	}
+	if err := reg.UnmarshalInto(marshal(newLimitNodeList(3)), &TLimitNode{}); err != nil {
+		t.Errorf("UnmarshalInto() error = %v", err)
+	}
+}
+
+func TestDecoderMaxBytes(t *testing.T) {
+	// 数据头声明了 1G 的数据长度,Decoder 必须在读取数据之前返回错误
+	var head bytes.Buffer
+	binary.Write(&head, binary.LittleEndian, uint16(cSignFlag|3))
+	binary.Write(&head, binary.LittleEndian, []uint32{ClassID_SlotData, 1 << 30})
+	d := NewDecoder(bytes.NewReader(head.Bytes()))
+	d.SetLimits(DecoderLimits{MaxBytes: 1 << 20})
+	_, err := d.Decode()
+	var limitErr *ErrLimitExceeded
+	if !errors.As(err, &limitErr) || limitErr.Limit != "message bytes" {
+		t.Errorf("Decode() error = %v, want message bytes limit exceeded", err)
+	}
+
+	// 不限制长度时按读到的数据扩大缓冲区,数据不足时返回 io.ErrUnexpectedEOF
+	d = NewDecoder(io.MultiReader(bytes.NewReader(head.Bytes()), bytes.NewReader(make([]byte, 100))))
+	d.SetLimits(DecoderLimits{})
+	if _, err := d.Decode(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Decode() unlimited error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if n := cap(d.buf); n > 1<<20 {
+		t.Errorf("Decode() unlimited allocated %d bytes", n)
+	}
+
+	// 32 位平台上 int 放不下的长度
+	head.Truncate(6)
+	binary.Write(&head, binary.LittleEndian, uint32(0xFFFFFFF0))
+	d = NewDecoder(&head)
+	d.SetLimits(DecoderLimits{})
+	want := error(io.ErrUnexpectedEOF)
+	if strconv.IntSize == 32 {
+		want = ErrIntRange
+	}
+	if _, err := d.Decode(); !errors.Is(err, want) {
+		t.Errorf("Decode() huge length error = %v, want %v", err, want)
+	}
+}
@@ -0,0 +1,91 @@
+package protocol
+
+import "sync"
+
+const (
+	defaultSizeHint = 64       // 没有编码过的协议预分配的缓冲区大小
+	maxPooledBuffer = 64 << 10 // 超过此大小的缓冲区不放回池中,避免长期占用内存
+)
+
+var writerPool = sync.Pool{New: func() interface{} { return &ProtocolWritter{buf: make([]byte, 0, 1024)} }}
+var readerPool = sync.Pool{New: func() interface{} { return new(ProtocolReader) }}
+
+// AcquireWriter 从池中取出使用默认注册表的 ProtocolWritter,用完后调用 ReleaseWriter 放回
+func AcquireWriter() *ProtocolWritter {
+	return defaultRegistry.AcquireWriter()
+}
+
+// AcquireWriter 从池中取出只写入 reg 中注册的协议的 ProtocolWritter
+func (reg *Registry) AcquireWriter() *ProtocolWritter {
+	w := writerPool.Get().(*ProtocolWritter)
+	w.reg = reg
+	return w
+}
+
+// ReleaseWriter 把 w 放回池中,之后不能再使用 w 和 w.Bytes() 返回的数据
+func ReleaseWriter(w *ProtocolWritter) {
+	if w == nil || cap(w.buf) > maxPooledBuffer {
+		return
+	}
+	w.buf = w.buf[:0]
+	w.reg, w.depth, w.reflectOnly, w.unsized = nil, 0, false, false
+	w.clearSizes()
+	writerPool.Put(w)
+}
+
+// AcquireReader 从池中取出解析 data 的 ProtocolReader,使用默认注册表,用完后调用 ReleaseReader 放回
+func AcquireReader(data []byte) *ProtocolReader {
+	return defaultRegistry.AcquireReader(data)
+}
+
+// AcquireReader 从池中取出只解析 reg 中注册的协议的 ProtocolReader
+func (reg *Registry) AcquireReader(data []byte) *ProtocolReader {
+	r := readerPool.Get().(*ProtocolReader)
+	r.buf, r.reg, r.limits = data, reg, reg.DecoderLimits()
+	return r
+}
+
+// ReleaseReader 把 r 放回池中,之后不能再使用 r, 已解码的对象不受影响
+func ReleaseReader(r *ProtocolReader) {
+	if r == nil {
+		return
+	}
+	*r = ProtocolReader{} // 不再引用输入数据
+	readerPool.Put(r)
+}
+
+// AppendMarshal 把 v 序列化后追加到 dst 中,返回追加后的切片, v 必须在默认注册表中注册
+func AppendMarshal(dst []byte, v interface{}) ([]byte, error) {
+	return defaultRegistry.AppendMarshal(dst, v)
+}
+
+// AppendMarshal 把 v 序列化后追加到 dst 中, dst 的容量不足时按协议上次编码的长度扩容
+// 出错时返回原来的 dst
+func (reg *Registry) AppendMarshal(dst []byte, v interface{}) ([]byte, error) {
+	rtti, _ := reg.FromObj(v)
+	hint := defaultSizeHint
+	if rtti != nil {
+		if n := int(rtti.sizeHint.Load()); n > 0 {
+			hint = n
+		}
+	}
+	start := len(dst)
+	buf := dst
+	if cap(buf)-start < hint {
+		buf = make([]byte, start, start+hint)
+		copy(buf, dst)
+	}
+	w := reg.AcquireWriter()
+	pooled := w.buf
+	w.buf = buf
+	err := w.WriteAny(v)
+	buf, w.buf = w.buf, pooled
+	ReleaseWriter(w)
+	if err != nil {
+		return dst, err
+	}
+	if rtti != nil {
+		rtti.sizeHint.Store(int64(len(buf) - start))
+	}
+	return buf, nil
+}
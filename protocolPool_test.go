@@ -0,0 +1,75 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAppendMarshal(t *testing.T) {
+	testobj := newMapInfoTestObj()
+	prefix := []byte{1, 2, 3}
+	data, err := AppendMarshal(prefix, testobj)
+	if err != nil {
+		t.Fatalf("AppendMarshal() error = %v", err)
+	}
+	if !bytes.Equal(data[:3], prefix) || !bytes.Equal(data[3:], mapInfoTestData) {
+		t.Errorf("AppendMarshal() = %v, want %v + %v", data, prefix, mapInfoTestData)
+	}
+	// 记录上次编码的长度, Marshal 只分配一次刚好够用的缓冲区
+	rtti, _ := GetRegRttiDataFromObj(testobj)
+	if n := rtti.sizeHint.Load(); n != int64(len(mapInfoTestData)) {
+		t.Errorf("sizeHint = %d, want %d", n, len(mapInfoTestData))
+	}
+	if data, err := Marshal(testobj); err != nil || cap(data) != len(mapInfoTestData) {
+		t.Errorf("Marshal() cap = %d, want %d (%v)", cap(data), len(mapInfoTestData), err)
+	}
+	buf := make([]byte, 0, 1024)
+	if allocs := testing.AllocsPerRun(100, func() { AppendMarshal(buf[:0], testobj) }); allocs > 0 {
+		t.Errorf("AppendMarshal() allocs = %v, want 0", allocs)
+	}
+
+	type unregistered struct{ A int32 }
+	if got, err := AppendMarshal(prefix, &unregistered{}); !errors.Is(err, ErrNotRegistered) || !bytes.Equal(got, prefix) {
+		t.Errorf("AppendMarshal() = %v, %v, want prefix and ErrNotRegistered", got, err)
+	}
+}
+
+func TestPool(t *testing.T) {
+	reg := NewRegistry()
+	reg.MustRegister(ClassID_SlotData, (*TSlotData)(nil))
+	w := reg.AcquireWriter()
+	if err := w.WriteAny(&TSlotData{Idx: 1}); err != nil {
+		t.Fatalf("WriteAny() error = %v", err)
+	}
+	data := append([]byte(nil), w.Bytes()...)
+	ReleaseWriter(w)
+	w = AcquireWriter()
+	if w.Len() != 0 || w.reg != defaultRegistry {
+		t.Errorf("AcquireWriter() = len %d, reg %p", w.Len(), w.reg)
+	}
+	ReleaseWriter(w)
+
+	r := reg.AcquireReader(data)
+	r.SetAliasBlobs(true)
+	got, err := r.readAny()
+	if err != nil || !reflect.DeepEqual(got, &TSlotData{Idx: 1}) {
+		t.Errorf("readAny() = %v, %v", got, err)
+	}
+	ReleaseReader(r)
+	r = AcquireReader(nil)
+	if r.buf != nil || r.off != 0 || r.Error != nil || r.aliasBlobs || r.reg != defaultRegistry || r.limits != DefaultDecoderLimits {
+		t.Errorf("AcquireReader() = %+v", r)
+	}
+	ReleaseReader(r)
+}
+
+func BenchmarkAppendMarshal(b *testing.B) {
+	testobj := newMapInfoTestObj()
+	buf := make([]byte, 0, 1024)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _ = AppendMarshal(buf[:0], testobj)
+	}
+}
@@ -3,6 +3,7 @@ package protocol
 import (
 	"github.com/pkg/errors"
 	"reflect"
+	"strconv"
 	"unsafe"
 )
 
@@ -11,23 +12,43 @@ type ProtocolDataHeader struct {
 	headerLength uint16
 	classId      uint32
 	dataLength   uint32
+	startPos     int
 }
 
 type ProtocolReader struct {
 	buf   []byte
 	off   int
 	Error error
+	reg   *Registry // 只能解析在 reg 中注册的协议
+
+	limits       DecoderLimits
+	depth        int  // 当前的嵌套深度
+	objects      int  // 当前消息中已解析的对象个数
+	reflectOnly  bool // 不使用生成的代码,见 locateError
+	aliasBlobs   bool // []byte 成员引用输入数据,见 SetAliasBlobs
+	aliasStrings bool // 字符串和数值切片引用输入数据,见 SetZeroCopy
 }
 
 // Parse creates an ProtocolReader instance from io.Reader
 func NewProtocolReader(data []byte) *ProtocolReader {
+	return defaultRegistry.NewProtocolReader(data)
+}
+
+// NewProtocolReader 创建只解析 reg 中注册的协议的 ProtocolReader
+func (reg *Registry) NewProtocolReader(data []byte) *ProtocolReader {
 	return &ProtocolReader{
-		buf:   data,
-		off:   0,
-		Error: nil,
+		buf:    data,
+		off:    0,
+		Error:  nil,
+		reg:    reg,
+		limits: reg.DecoderLimits(),
 	}
 }
 
+// SetAliasBlobs 设置解码 []byte 成员时是否直接引用输入数据而不复制
+// 开启后解码结果与输入数据共用内存,输入数据在结果使用期间不能修改或复用
+func (r *ProtocolReader) SetAliasBlobs(alias bool) { r.aliasBlobs = alias }
+
 // Len returns the number of bytes of the unread portion of the buffer;
 func (reader *ProtocolReader) Len() int { return len(reader.buf) - reader.off }
 
@@ -37,6 +58,7 @@ func (r *ProtocolReader) ReadDataHead(DataHeader *ProtocolDataHeader) {
 	DataHeader.headerLength = 0
 	DataHeader.classId = 0xFFFFFFFF
 	DataHeader.dataLength = 0
+	DataHeader.startPos = r.off
 	if r.Len() < 6 {
 		return
 	}
@@ -82,32 +104,141 @@ func (r *ProtocolReader) ReadDataHead(DataHeader *ProtocolDataHeader) {
 	}
 }
 
+// readFullHead 读取数据头,数据头无效或数据长度超出剩余的数据时记录错误并返回 false
+func (r *ProtocolReader) readFullHead(head *ProtocolDataHeader) bool {
+	r.ReadDataHead(head)
+	if head.isValid && uint32(len(r.buf)-head.startPos) >= head.dataLength {
+		return true
+	}
+	e := &ErrDecode{Offset: head.startPos, Err: ErrTruncated}
+	if data := r.buf[head.startPos:]; len(data) >= 2 && (uint16(data[0])|uint16(data[1])<<8)&cSignFlagMask != cSignFlag {
+		e.Err = ErrBadSign
+	} else if r.off-head.startPos == int(head.headerLength) { // 数据头完整
+		e.ClassId = head.classId
+		if head.dataLength < uint32(head.headerLength) {
+			e.Err = ErrBadHeader
+		}
+	}
+	return r.fail(e)
+}
+
+// fail 记录解析错误并返回 false,只记录第一个错误
+func (r *ProtocolReader) fail(err error) bool {
+	if r.Error == nil {
+		if _, ok := err.(*ErrDecode); !ok {
+			err = &ErrDecode{Offset: r.off, Err: err}
+		}
+		r.Error = err
+	}
+	return false
+}
+
+// failAt 记录在 head 处出错的解析错误并返回 false
+func (r *ProtocolReader) failAt(head *ProtocolDataHeader, err error) bool {
+	return r.fail(&ErrDecode{Offset: head.startPos, ClassId: head.classId, Err: err})
+}
+
+// addPath 解析 rtti 的成员 field 失败时,把成员加入错误路径
+func (r *ProtocolReader) addPath(rtti *TRegRttiData, field *TRegFieldOffsetData) {
+	e, ok := r.Error.(*ErrDecode)
+	if !ok { // 自定义的 UnmarshalProtocol 可能直接设置 r.Error
+		e = &ErrDecode{Offset: r.off, Err: r.Error}
+		if e.Err == nil {
+			e.Err = errors.New("解析失败")
+		}
+		r.Error = e
+	}
+	if field != nil {
+		e.Path = prependPath(e.Path, field.Name)
+	}
+	e.ClassId = rtti.ClassId
+	if r.depth == 1 { // 最外层的协议
+		e.Path = prependPath(e.Path, rtti.rType.Name())
+	}
+}
+
+// locateError 生成的代码不记录出错的成员,解析失败时用反射方式重新解析一遍,定位出错的成员
+func (r *ProtocolReader) locateError(head ProtocolDataHeader, rtti *TRegRttiData, depth, objects int) {
+	err := r.Error
+	r.off, r.depth, r.objects, r.Error = head.startPos+int(head.headerLength), depth, objects, nil
+	r.reflectOnly = true
+	r.readVal(head, reflect.New(rtti.rType).UnsafePointer(), rtti)
+	r.reflectOnly = false
+	if r.Error == nil {
+		r.Error = err
+	}
+}
+
 // readAny decode binary into interface{}
 func (r *ProtocolReader) readAny() (interface{}, error) {
 	var dataHead ProtocolDataHeader
-	starPos := r.off
-	r.ReadDataHead(&dataHead)
-	if !dataHead.isValid {
-		return nil, errors.New("读取数据头错误")
+	if !r.readFullHead(&dataHead) {
+		return nil, r.Error
 	}
-	if uint32(len(r.buf)-starPos) < dataHead.dataLength {
-		return nil, errors.New("读取数据头错误")
+	if dataHead.dataLength == uint32(dataHead.headerLength) { // nil 写入的是空数据头
+		return nil, nil
+	}
+	if rtti, ok := builtinClasses[dataHead.classId]; ok {
+		if obj, ok := r.readBuiltin(&dataHead, rtti); ok {
+			return obj, nil
+		}
+		return nil, r.parseError()
 	}
 	var rttiData *TRegRttiData
 	var ok bool
-	if rttiData, ok = GetRegRttiDataByClassId(dataHead.classId); !ok {
-		return nil, errors.New("object isn't register")
-	}
-	if dataHead.dataLength == uint32(dataHead.headerLength) {
-		return nil, nil
+	if rttiData, ok = r.reg.ByClassId(dataHead.classId); !ok {
+		r.failAt(&dataHead, &ErrUnknownClass{ClassId: dataHead.classId})
+		return nil, r.Error
 	}
 	val := reflect.New(rttiData.rType)
 	if _, ok := r.readVal(dataHead, unsafe.Pointer(val.Pointer()), rttiData); ok {
 		return val.Interface(), nil
 	} else {
-		return nil, errors.New("解析失败")
+		return nil, r.parseError()
 	}
 }
+
+// parseError 返回解析过程中记录的错误,没有记录时返回通用的解析失败
+func (r *ProtocolReader) parseError() error {
+	if r.Error == nil {
+		r.fail(errors.New("解析失败"))
+	}
+	return r.Error
+}
+
+// readInto decode binary into an exist object, dst must be a pointer to registered struct
+func (r *ProtocolReader) readInto(dst IMsg) error {
+	if dst == nil {
+		return errors.New("dst is nil")
+	}
+	val := reflect.ValueOf(dst)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return errors.New("dst must be a non-nil pointer")
+	}
+	rttiData, ok := r.reg.FromObj(dst)
+	if !ok {
+		return ErrNotRegistered
+	}
+	var dataHead ProtocolDataHeader
+	if !r.readFullHead(&dataHead) {
+		return r.Error
+	}
+	if dataHead.classId != rttiData.ClassId {
+		r.failAt(&dataHead, &ErrClassMismatch{Want: rttiData.ClassId, Got: dataHead.classId})
+		return r.Error
+	}
+	// 复用的对象需要先清空,避免残留上一次的数据
+	val.Elem().Set(reflect.Zero(rttiData.rType))
+	if dataHead.dataLength == uint32(dataHead.headerLength) {
+		return nil
+	}
+	if _, ok := r.readVal(dataHead, unsafe.Pointer(val.Pointer()), rttiData); !ok {
+		return r.parseError()
+	}
+	return nil
+}
+
+// readMemory 读取 len 个字节到 ptr 指向的内存,数据不足时只读取剩余的数据,返回读取的字节数
 func (r *ProtocolReader) readMemory(ptr unsafe.Pointer, len int) (n int) {
 	if r.Len() < len {
 		len = r.Len()
@@ -115,56 +246,68 @@ func (r *ProtocolReader) readMemory(ptr unsafe.Pointer, len int) (n int) {
 	if len <= 0 {
 		return 0
 	}
-
-	for i := 0; i < len; i += 64 {
-		ss := (*[64]byte)(unsafe.Pointer(uintptr(ptr) + uintptr(i)*64))
-		copy(ss[:], r.buf[r.off+i*64:r.off+len])
-	}
-	//sliceHeader := reflect.SliceHeader{Data: uintptr(ptr), Len: len, Cap: len}
-	//copy(*(*[]byte)(unsafe.Pointer(&sliceHeader)), r.buf[r.off:r.off+len])
+	copy(unsafe.Slice((*byte)(ptr), len), r.buf[r.off:r.off+len])
 	r.off += len
 	return len
 }
 func (r *ProtocolReader) readUint16() (ret uint16, ok bool) {
-	ok = false
-	_ = r.buf[r.off+1] // bounds check hint to compiler; see golang.org/issue/14808
+	if r.Len() < 2 {
+		return 0, false
+	}
 	ret = uint16(r.buf[r.off]) | uint16(r.buf[r.off+1])<<8
 	r.off += 2
-	ok = true
-	return
+	return ret, true
 }
 func (r *ProtocolReader) readUint32() (ret uint32, ok bool) {
-	ok = false
-	_ = r.buf[r.off+3] // bounds check hint to compiler; see golang.org/issue/14808
+	if r.Len() < 4 {
+		return 0, false
+	}
 	ret = uint32(r.buf[r.off]) | uint32(r.buf[r.off+1])<<8 | uint32(r.buf[r.off+2])<<16 | uint32(r.buf[r.off+3])<<24
 	r.off += 4
-	ok = true
-	return
+	return ret, true
 }
-func (r *ProtocolReader) readString() (s string, rn int, ok bool) {
-	l := uint16(0)
-	if l, ok = r.readUint16(); ok {
-		rn = stringLenSize
-		if l > 0 && r.Len() >= int(l) {
-			s = string(r.buf[r.off : r.off+int(l)])
-			rn += int(l)
-			r.off += int(l)
-		} else {
-			ok = false
-		}
+
+// readString 读取字符串, long 为 true 时长度为 uint32,返回读取的字节数
+func (r *ProtocolReader) readString(long bool) (s string, rn int, ok bool) {
+	var l uint32
+	if long {
+		l, ok = r.readUint32()
+		rn = longStringLenSize
+	} else {
+		var l16 uint16
+		l16, ok = r.readUint16()
+		l, rn = uint32(l16), stringLenSize
+	}
+	if !ok {
+		return "", 0, r.fail(ErrTruncated)
+	}
+	if !r.checkLimit("string bytes", lenInt(l), r.limits.MaxStringBytes) {
+		return "", rn, false
+	}
+	if uint64(r.Len()) < uint64(l) {
+		return "", rn, r.fail(ErrTruncated)
 	}
-	return
+	if b := r.buf[r.off : r.off+int(l)]; r.aliasStrings {
+		s = aliasString(b)
+	} else {
+		s = string(b)
+	}
+	r.off += int(l)
+	return s, rn + int(l), true
 }
 func (r *ProtocolReader) readStruct(rttiType reflect.Type, ptr unsafe.Pointer) bool {
 	var arrHead ProtocolDataHeader
-	r.ReadDataHead(&arrHead)
-	if !arrHead.isValid {
+	if !r.readFullHead(&arrHead) {
 		return false
 	}
-	if filedRttiData, ok := GetRegRttiDataByClassId(arrHead.classId); !ok {
-		return false
+	if arrHead.dataLength == uint32(arrHead.headerLength) { // 成员由指针改为结构体时,旧数据中的 nil 指针解析为零值
+		reflect.NewAt(rttiType, ptr).Elem().Set(reflect.Zero(rttiType))
+		return true
+	}
+	if filedRttiData, ok := r.reg.ByClassId(arrHead.classId); !ok {
+		return r.failAt(&arrHead, &ErrUnknownClass{ClassId: arrHead.classId})
 	} else if filedRttiData.rType != rttiType {
-		return false
+		return r.failAt(&arrHead, &ErrTypeMismatch{ClassId: arrHead.classId, Want: rttiType})
 	} else {
 		if _, ok := r.readVal(arrHead, ptr, filedRttiData); !ok {
 			return false
@@ -175,24 +318,165 @@ func (r *ProtocolReader) readStruct(rttiType reflect.Type, ptr unsafe.Pointer) b
 func (r *ProtocolReader) readPointer(tyhash uintptr, ptr unsafe.Pointer) bool {
 
 	var fieldHead ProtocolDataHeader
-	r.ReadDataHead(&fieldHead)
-	if !fieldHead.isValid {
+	if !r.readFullHead(&fieldHead) {
 		return false
 	}
-	if filedRttiData, ok := G_DataClass[tyhash]; !ok {
-		return false
+	if fieldHead.dataLength == uint32(fieldHead.headerLength) { // nil 指针写入的是空数据头,classId 为0
+		*(*unsafe.Pointer)(ptr) = nil
+		return true
+	}
+	if filedRttiData, ok := r.reg.byTypeHash(tyhash); !ok {
+		return r.failAt(&fieldHead, ErrNotRegistered)
 	} else if filedRttiData.ClassId != fieldHead.classId { // 因为是指针,这里通过rttiField.arrayType获取反射信息 而不是classid
-		return false
+		return r.failAt(&fieldHead, &ErrTypeMismatch{ClassId: fieldHead.classId, Want: filedRttiData.rType})
 	} else if fieldHead.dataLength > uint32(fieldHead.headerLength) { //只有非nil才处理
 		if *(*unsafe.Pointer)(ptr) == nil {
-			val := reflect.New(filedRttiData.rType)
-			*(*uintptr)(ptr) = val.Pointer()
+			*(*unsafe.Pointer)(ptr) = reflect.New(filedRttiData.rType).UnsafePointer()
 		}
 		ptr = *(*unsafe.Pointer)(ptr)
-		r.readVal(fieldHead, ptr, filedRttiData)
+		if _, ok := r.readVal(fieldHead, ptr, filedRttiData); !ok {
+			return false
+		}
 	}
 	return true
 }
+
+// readElem 读取数组、切片或map中的单个元素,ptr 指向元素本身, long 为成员的 long tag
+func (r *ProtocolReader) readElem(kind reflect.Kind, tp reflect.Type, ptr unsafe.Pointer, long bool) bool {
+	if isMemPod(kind) {
+		size := int(tp.Size())
+		return r.readPod(ptr, size, 1) == size || r.fail(ErrTruncated)
+	}
+	switch kind {
+	case reflect.Int, reflect.Uint, reflect.Uintptr: // 本机不是 64 位
+		return r.readVarInt(kind, ptr)
+	case reflect.String:
+		var ok bool
+		*(*string)(ptr), _, ok = r.readString(long)
+		return ok
+	case reflect.Struct:
+		return r.readStruct(tp, ptr)
+	case reflect.Interface:
+		obj, err := r.readAny()
+		if err != nil {
+			return false
+		}
+		*(*interface{})(ptr) = obj
+		return true
+	case reflect.Ptr:
+		return r.readPointer(uintptr(PtrOf(tp)), ptr)
+	case reflect.Slice, reflect.Array:
+		return r.readArray(tp, ptr, long)
+	}
+	return false
+}
+
+// readArray 读取嵌套的数组或切片: 元素个数(uint32) + 元素, ptr 指向数组或切片本身
+// []byte 和 [N]byte 按字节数检查长度
+func (r *ProtocolReader) readArray(tp reflect.Type, ptr unsafe.Pointer, long bool) bool {
+	n, ok := r.readUint32()
+	if !ok {
+		return r.fail(ErrTruncated)
+	}
+	et := tp.Elem()
+	if et.Kind() == reflect.Uint8 {
+		ok = r.checkBlobLen(n, 0)
+	} else {
+		ok = r.checkArrayLen(n, minElemSize(et.Kind(), int(et.Size()), long))
+	}
+	return ok && r.readElems(tp, ptr, n, long)
+}
+
+// readElems 读取数组或切片的 n 个元素,元素个数已经检查过, ptr 指向数组或切片本身
+// 数据比数组长时丢弃多余的元素
+func (r *ProtocolReader) readElems(tp reflect.Type, ptr unsafe.Pointer, n uint32, long bool) bool {
+	et := tp.Elem()
+	kind, size := et.Kind(), int(et.Size())
+	count := int(n) // 读入 ptr 的元素个数
+	if tp.Kind() == reflect.Slice {
+		if n == 0 {
+			return true
+		}
+		slice := reflect.NewAt(tp, ptr).Elem()
+		if kind == reflect.Uint8 && r.aliasBlobs { // 引用输入数据,不复制
+			slice.SetBytes(r.buf[r.off : r.off+count : r.off+count])
+			r.off += count
+			return true
+		}
+		if r.aliasPod(ptr, et, count) {
+			return true
+		}
+		slice.Set(reflect.MakeSlice(tp, count, count))
+		ptr = slice.UnsafePointer()
+	} else if count > tp.Len() {
+		count = tp.Len()
+	}
+	if isMemPod(kind) {
+		if r.readPod(ptr, size, count) != count*size {
+			return r.fail(ErrTruncated)
+		}
+		r.off += (int(n) - count) * size // 跳过多余的元素,长度已经检查过
+		return true
+	}
+	var discard reflect.Value // 多余的元素读取后丢弃
+	for i := 0; i < int(n); i++ {
+		p := unsafe.Add(ptr, i*size)
+		if i >= count {
+			if !discard.IsValid() {
+				discard = reflect.New(et)
+			}
+			discard.Elem().Set(reflect.Zero(et))
+			p = discard.UnsafePointer()
+		}
+		if !r.readElem(kind, et, p, long) {
+			if e, ok := r.Error.(*ErrDecode); ok {
+				e.Path = prependPath(e.Path, "["+strconv.Itoa(i)+"]")
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// readMap 读取map: 元素个数(uint32) + key,value 对
+func (r *ProtocolReader) readMap(m reflect.Value, rttiField *TRegFieldOffsetData) bool {
+	count, ok := r.readUint32()
+	if !ok {
+		return r.fail(ErrTruncated)
+	}
+	if count == 0 {
+		m.Set(reflect.Zero(rttiField.rType))
+		return true
+	}
+	if !r.checkArrayLen(count, 1) {
+		return false
+	}
+	// 每个元素至少占用一个字节,避免按错误的个数预分配过大的内存
+	hint := int(count)
+	if hint > r.Len() {
+		hint = r.Len()
+	}
+	mp := reflect.MakeMapWithSize(rttiField.rType, hint)
+	key := reflect.New(rttiField.mapKeyType)
+	val := reflect.New(rttiField.arrayType)
+	for i := uint32(0); i < count; i++ {
+		key.Elem().Set(reflect.Zero(rttiField.mapKeyType))
+		val.Elem().Set(reflect.Zero(rttiField.arrayType))
+		if !r.readElem(rttiField.mapKeyKind, rttiField.mapKeyType, unsafe.Pointer(key.Pointer()), rttiField.LongString) {
+			return false
+		}
+		if !r.readElem(rttiField.arrayKind, rttiField.arrayType, unsafe.Pointer(val.Pointer()), rttiField.LongString) {
+			if e, ok := r.Error.(*ErrDecode); ok {
+				e.Path = prependPath(e.Path, mapKeyPath(key.Elem()))
+			}
+			return false
+		}
+		mp.SetMapIndex(key.Elem(), val.Elem())
+	}
+	m.Set(mp)
+	return true
+}
+
 func (r *ProtocolReader) readVal(dataHead ProtocolDataHeader, ptr unsafe.Pointer, rttiData *TRegRttiData) (n int, ok bool) {
 	if rttiData == nil {
 		return 0, false
@@ -200,16 +484,37 @@ func (r *ProtocolReader) readVal(dataHead ProtocolDataHeader, ptr unsafe.Pointer
 	datalen := int(dataHead.dataLength)
 	readLen := int(dataHead.headerLength)
 	startPos := r.off - readLen
+	if rttiData.unmarshaler && !r.reflectOnly { // 优先使用 goprotocol-gen 生成的代码,生成的代码会重新读取数据头
+		r.off = startPos
+		depth, objects := r.depth, r.objects
+		if err := reflect.NewAt(rttiData.rType, ptr).Interface().(IProtocolUnmarshaler).UnmarshalProtocol(r); err != nil {
+			r.fail(err)
+			r.locateError(dataHead, rttiData, depth, objects)
+			return r.off - startPos, false
+		}
+		return r.off - startPos, true
+	}
+	if !r.enter(&dataHead) {
+		return 0, false
+	}
+	defer r.leave()
+	var field *TRegFieldOffsetData // 正在解析的成员,用于记录出错的位置
+	defer func() {
+		if !ok {
+			r.addPath(rttiData, field)
+		}
+	}()
 	ok = false
 	for idx := 0; idx < len(rttiData.FieldData); {
 		if readLen >= datalen {
 			break
 		}
 		rttiField := &rttiData.FieldData[idx]
+		field = rttiField
 		fieldPtr := unsafe.Pointer(uintptr(ptr) + rttiField.offset)
 		if rttiField.podMergeCount <= 1 { // 未合并的字段
 			if rttiField.isPod() {
-				if rn := r.readMemory(fieldPtr, rttiField.podSize); rn != rttiField.podSize {
+				if rn := r.readPod(fieldPtr, rttiField.podSize, 1); rn != rttiField.podSize {
 					readLen += rn
 					break
 				} else {
@@ -218,171 +523,35 @@ func (r *ProtocolReader) readVal(dataHead ProtocolDataHeader, ptr unsafe.Pointer
 			} else {
 				switch rttiField.Kind {
 				case reflect.String:
-					if readLen+stringLenSize > datalen {
+					if readLen+rttiField.stringLenSize() > datalen {
 						break
 					}
 					slen := 0
-					if *(*string)(fieldPtr), slen, ok = r.readString(); !ok {
+					if *(*string)(fieldPtr), slen, ok = r.readString(rttiField.LongString); !ok {
 						return r.off - startPos, false
 					} else {
 						readLen += slen
 					}
-				case reflect.Array:
+				case reflect.Array, reflect.Slice:
 					if readLen+arrayLenSize > datalen {
 						break
 					}
 					arrlen, ok := r.readUint32()
 					if !ok {
-						return r.off - startPos, false
+						return r.off - startPos, r.fail(ErrTruncated)
 					}
-					readLen += arrayLenSize
-					fieldPtr = PtrOf((*emptyInterface)(fieldPtr))
-					if isPod(rttiField.arrayKind) { // 元素是pod类型,可以直接写入
-						arrDataLen := int(arrlen) * rttiField.arraySize
-						if arrDataLen <= rttiField.podSize { // 固定长度的数组不可用溢出
-							if r.readMemory(fieldPtr, arrDataLen) != arrDataLen {
-								return r.off - startPos, false
-							} else {
-								readLen += arrDataLen
-							}
-						} else {
-							newIdx := r.off + arrDataLen
-							if r.readMemory(fieldPtr, rttiField.podSize) != rttiField.podSize {
-								return r.off - startPos, false
-							}
-							r.off = newIdx
-							readLen = r.off - startPos
-						}
-					} else {
-						switch rttiField.arrayKind {
-						case reflect.String:
-							for i := 0; i < int(arrlen); i++ {
-								if i < int(rttiField.arrayLen) {
-									slen := 0
-									if *(*string)(unsafe.Pointer(uintptr(fieldPtr) + uintptr(i*rttiField.arraySize))), slen, ok = r.readString(); !ok {
-										return r.off - startPos, false
-									} else {
-										readLen += slen
-									}
-								} else { //超长直接跳过
-									slen := 0
-									if _, slen, ok = r.readString(); !ok {
-										return r.off - startPos, false
-									} else {
-										readLen += slen
-									}
-								}
-							}
-						case reflect.Struct:
-							for i := uint32(0); i < arrlen; i++ {
-								if i < rttiField.arrayLen {
-									if ok := r.readStruct(rttiField.arrayType, unsafe.Pointer(uintptr(fieldPtr)+uintptr(int(i)*rttiField.arraySize))); !ok {
-										return r.off - startPos, false
-									} else {
-										readLen = r.off - startPos
-									}
-								} else {
-									if _, err := r.readAny(); err != nil {
-										return r.off - startPos, false
-									} else {
-										readLen = r.off - startPos
-									}
-								}
-							}
-						case reflect.Interface:
-							for i := uint32(0); i < arrlen; i++ {
-								if obj, err := r.readAny(); err != nil {
-									return r.off - startPos, false
-								} else {
-									if i < rttiField.arrayLen {
-										*(*interface{})(unsafe.Pointer(uintptr(fieldPtr) + uintptr(int(i)*rttiField.arraySize))) = obj
-									}
-									readLen = r.off - startPos
-								}
-							}
-						case reflect.Ptr:
-							for i := uint32(0); i < arrlen; i++ {
-								if i < rttiField.arrayLen {
-									if ok := r.readPointer(uintptr(PtrOf(rttiField.arrayType)), unsafe.Pointer(uintptr(fieldPtr)+uintptr(int(i)*rttiField.arraySize))); !ok {
-										return r.off - startPos, false
-									} else {
-										readLen = r.off - startPos
-									}
-								} else {
-									if _, err := r.readAny(); err != nil {
-										return r.off - startPos, false
-									} else {
-										readLen = r.off - startPos
-									}
-								}
-							}
-						}
-
+					if !r.checkFieldLen(rttiField, arrlen) || !r.readElems(rttiField.rType, fieldPtr, arrlen, rttiField.LongString) {
+						return r.off - startPos, false
 					}
-				case reflect.Slice:
+					readLen = r.off - startPos
+				case reflect.Map:
 					if readLen+arrayLenSize > datalen {
 						break
 					}
-					arrlen := uint32(0)
-					if arrlen, ok = r.readUint32(); !ok {
+					if !r.readMap(reflect.NewAt(rttiField.rType, fieldPtr).Elem(), rttiField) {
 						return r.off - startPos, false
 					}
-					readLen += arrayLenSize
-					if arrlen == 0 {
-						idx++
-						continue
-					}
-					slicePtr := (*reflect.SliceHeader)(fieldPtr)
-					fieldData := reflect.MakeSlice(rttiField.rType, int(arrlen), int(arrlen)).Interface()
-					tp := (*reflect.SliceHeader)(PtrOf(fieldData))
-					*slicePtr = *(*reflect.SliceHeader)(tp)
-					fieldPtr = unsafe.Pointer(slicePtr.Data)
-					if isPod(rttiField.arrayKind) { // 元素是pod类型,可以直接写入
-						arrDataLen := slicePtr.Len * rttiField.arraySize
-						if r.readMemory(fieldPtr, arrDataLen) != arrDataLen {
-							return r.off - startPos, false
-						} else {
-							readLen += arrDataLen
-						}
-					} else {
-						switch rttiField.arrayKind {
-						case reflect.String:
-							for i := 0; i < int(arrlen); i++ {
-								slen := 0
-								if *(*string)(unsafe.Pointer(uintptr(fieldPtr) + uintptr(int(i)*rttiField.arraySize))), slen, ok = r.readString(); !ok {
-									return r.off - startPos, false
-								} else {
-									readLen += slen
-								}
-							}
-						case reflect.Struct:
-							for i := 0; i < int(arrlen); i++ {
-								if ok := r.readStruct(rttiField.arrayType, unsafe.Pointer(uintptr(fieldPtr)+uintptr(int(i)*rttiField.arraySize))); !ok {
-									return r.off - startPos, false
-								} else {
-									readLen = r.off - startPos
-								}
-							}
-						case reflect.Interface:
-							for i := 0; i < int(arrlen); i++ {
-								if obj, err := r.readAny(); err != nil {
-									return r.off - startPos, false
-								} else {
-									*(*interface{})(unsafe.Pointer(uintptr(fieldPtr) + uintptr(int(i)*rttiField.arraySize))) = obj
-									readLen = r.off - startPos
-								}
-							}
-						case reflect.Ptr:
-							for i := 0; i < int(arrlen); i++ {
-								if ok := r.readPointer(uintptr(PtrOf(rttiField.arrayType)), unsafe.Pointer(uintptr(fieldPtr)+uintptr(int(i)*rttiField.arraySize))); !ok {
-									return r.off - startPos, false
-								} else {
-									readLen = r.off - startPos
-								}
-							}
-						}
-
-					}
+					readLen = r.off - startPos
 				case reflect.Struct:
 					if ok := r.readStruct(rttiField.rType, fieldPtr); !ok {
 						return r.off - startPos, false
@@ -402,11 +571,16 @@ func (r *ProtocolReader) readVal(dataHead ProtocolDataHeader, ptr unsafe.Pointer
 					} else {
 						readLen = r.off - startPos
 					}
+				case reflect.Int, reflect.Uint, reflect.Uintptr: // 本机不是 64 位
+					if !r.readVarInt(rttiField.Kind, fieldPtr) {
+						return r.off - startPos, false
+					}
+					readLen = r.off - startPos
 				}
 			}
 			idx++
 		} else { // 合并的 pod 类型，直接取 podSize
-			if rn := r.readMemory(fieldPtr, rttiField.podSize); rn != rttiField.podSize {
+			if rn := r.readPodRun(fieldPtr, rttiData.FieldData[idx:idx+rttiField.podMergeCount]); rn != rttiField.podSize {
 				readLen += rn
 				break
 			} else {
@@ -0,0 +1,118 @@
+package protocol
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Registry 协议注册表, classId 与结构体类型一一对应,可以并发使用
+type Registry struct {
+	mu      sync.RWMutex
+	classes map[uint32]*TRegRttiData
+	types   map[uintptr]*TRegRttiData // key 为结构体类型和对应指针类型的 hash
+	limits  DecoderLimits
+	intMode TIntMode
+}
+
+// NewRegistry 创建空的注册表
+func NewRegistry() *Registry {
+	return &Registry{classes: make(map[uint32]*TRegRttiData), types: make(map[uintptr]*TRegRttiData), limits: DefaultDecoderLimits}
+}
+
+var defaultRegistry = &Registry{classes: G_ClassId, types: G_DataClass, limits: DefaultDecoderLimits}
+
+// DefaultRegistry 包级函数使用的默认注册表
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register 注册结构体, msg 为结构体或结构体指针
+// 同一个类型重复注册同一个 classId 时忽略, classId 或类型已被注册、classId 是内置类型保留的、成员类型不支持时返回错误
+func (reg *Registry) Register(classId uint32, msg IMsg) error {
+	tp := reflect.TypeOf(msg)
+	if tp == nil {
+		return errors.New("register nil")
+	}
+	if tp.Kind() == reflect.Ptr {
+		tp = tp.Elem()
+	}
+	if tp.Kind() != reflect.Struct {
+		return fmt.Errorf("register %s: only struct is supported", tp)
+	}
+	rType := uintptr(PtrOf(tp))                    // 类型hash
+	pType := uintptr(PtrOf(reflect.PointerTo(tp))) // 对应指针hash
+
+	if err := checkClassId(classId); err != nil {
+		return fmt.Errorf("register %s: %v", tp, err)
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if rtti, ok := reg.classes[classId]; ok {
+		if rtti.rType == tp {
+			return nil
+		}
+		return fmt.Errorf("register %s: classId %d is already registered by %s", tp, classId, rtti.rType)
+	}
+	if rtti, ok := reg.types[rType]; ok {
+		return fmt.Errorf("register %s: already registered with classId %d", tp, rtti.ClassId)
+	}
+	rtti, err := newRegRttiData(classId, tp, reg.intMode)
+	if err != nil {
+		return fmt.Errorf("register %s: %v", tp, err)
+	}
+	reg.classes[classId] = rtti
+	reg.types[rType] = rtti
+	reg.types[pType] = rtti
+	return nil
+}
+
+// MustRegister 与 Register 相同,注册失败时 panic,用于 init 中注册固定的协议
+func (reg *Registry) MustRegister(classId uint32, msg IMsg) {
+	if err := reg.Register(classId, msg); err != nil {
+		panic(err)
+	}
+}
+
+// ByClassId 按 classId 查找注册信息
+func (reg *Registry) ByClassId(classId uint32) (*TRegRttiData, bool) {
+	reg.mu.RLock()
+	rtti, ok := reg.classes[classId]
+	reg.mu.RUnlock()
+	return rtti, ok
+}
+
+// FromType 按结构体类型或结构体指针类型查找注册信息
+func (reg *Registry) FromType(tp reflect.Type) (*TRegRttiData, bool) {
+	return reg.byTypeHash(uintptr(PtrOf(tp)))
+}
+
+// FromObj 按对象的类型查找注册信息
+func (reg *Registry) FromObj(msg IMsg) (*TRegRttiData, bool) {
+	tp := reflect.TypeOf(msg)
+	if tp == nil {
+		return nil, false
+	}
+	return reg.FromType(tp)
+}
+
+func (reg *Registry) byTypeHash(hash uintptr) (*TRegRttiData, bool) {
+	reg.mu.RLock()
+	rtti, ok := reg.types[hash]
+	reg.mu.RUnlock()
+	return rtti, ok
+}
+
+// Classes 返回所有注册信息,按 classId 排序
+func (reg *Registry) Classes() []*TRegRttiData {
+	reg.mu.RLock()
+	classes := make([]*TRegRttiData, 0, len(reg.classes))
+	for _, rtti := range reg.classes {
+		classes = append(classes, rtti)
+	}
+	reg.mu.RUnlock()
+	sort.Slice(classes, func(i, j int) bool { return classes[i].ClassId < classes[j].ClassId })
+	return classes
+}
@@ -0,0 +1,196 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.Register(ClassID_SlotData, (*TSlotData)(nil)); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := reg.Register(ClassID_SlotData, TSlotData{}); err != nil {
+		t.Errorf("Register() same type again error = %v", err)
+	}
+	if rtti, ok := reg.ByClassId(ClassID_SlotData); !ok || rtti.ClassId != ClassID_SlotData {
+		t.Errorf("ByClassId() = %v, %v", rtti, ok)
+	}
+	if rtti, ok := reg.FromObj(&TSlotData{}); !ok || rtti.ClassId != ClassID_SlotData {
+		t.Errorf("FromObj() = %v, %v", rtti, ok)
+	}
+	if _, ok := reg.FromObj(&TMapInfo{}); ok {
+		t.Errorf("FromObj() found unregistered type")
+	}
+
+	type unsupported struct {
+		P unsafe.Pointer
+	}
+	tests := []struct {
+		name    string
+		classId uint32
+		msg     IMsg
+	}{
+		{"duplicate id", ClassID_SlotData, (*TMapInfo)(nil)},
+		{"duplicate type", ClassID_SlotData + 100, (*TSlotData)(nil)},
+		{"nil", 1, nil},
+		{"not struct", 2, new(int32)},
+		{"unsafe.Pointer", 3, (*unsupported)(nil)},
+		{"*interface{}", 4, (*struct{ P *interface{} })(nil)},
+		{"func", 5, (*struct{ F func() })(nil)},
+		{"chan", 6, (*struct{ C chan int })(nil)},
+		{"complex", 7, (*struct{ C complex128 })(nil)},
+		{"*int", 8, (*struct{ P *int32 })(nil)},
+		{"slice of map", 9, (*struct{ S [][]map[int32]int32 })(nil)},
+		{"map key", 10, (*struct{ M map[TSlotData]int32 })(nil)},
+		{"map value", 11, (*struct{ M map[int32]map[int32]int32 })(nil)},
+		{"error", 12, (*struct{ E error })(nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := reg.Register(tt.classId, tt.msg); err == nil {
+				t.Errorf("Register() expected error")
+			}
+		})
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("MustRegister() duplicate id did not panic")
+			}
+		}()
+		reg.MustRegister(ClassID_SlotData, (*TMapInfo)(nil))
+	}()
+	if n := len(reg.Classes()); n != 1 {
+		t.Errorf("Classes() = %d classes, want 1", n)
+	}
+}
+
+func TestRegistryConcurrent(t *testing.T) {
+	reg := NewRegistry()
+	msgs := []IMsg{(*TSlotData)(nil), (*TMapInfo)(nil), (*TCodeGenTest)(nil)}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j, msg := range msgs {
+				if err := reg.Register(MaxBuiltinClassId+uint32(j+1), msg); err != nil {
+					t.Error(err)
+				}
+				reg.FromObj(msgs[(i+j)%len(msgs)])
+				reg.ByClassId(uint32(i))
+				reg.Classes()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if n := len(reg.Classes()); n != len(msgs) {
+		t.Errorf("Classes() = %d classes, want %d", n, len(msgs))
+	}
+}
+
+func TestRegistryNamespace(t *testing.T) {
+	client, server := NewRegistry(), NewRegistry()
+	client.MustRegister(CM_BASE+1, (*TSlotData)(nil))
+	server.MustRegister(SM_BASE+1, (*TSlotData)(nil))
+	server.MustRegister(SM_BASE+2, (*TMapInfo)(nil))
+	server.MustRegister(SM_BASE+3, (*TCodeGenTest)(nil))
+
+	slot := &TSlotData{1, 2, true, 3, 4}
+	data, err := client.Marshal(slot)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if got := binary.LittleEndian.Uint32(data[2:]); got != CM_BASE+1 {
+		t.Errorf("Marshal() classId = %d, want %d", got, CM_BASE+1)
+	}
+	if got, err := client.Unmarshal(data); err != nil || !reflect.DeepEqual(got, slot) {
+		t.Errorf("Unmarshal() = %v, %v", got, err)
+	}
+	if _, err := client.Marshal(newMapInfoTestObj()); err == nil {
+		t.Errorf("Marshal() of unregistered type expected error")
+	}
+
+	// 客户端不能解析服务器之间的协议,包括 interface 成员中存放的协议
+	mapInfo, err := server.Marshal(newMapInfoTestObj())
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if _, err := client.Unmarshal(mapInfo); err == nil {
+		t.Errorf("Unmarshal() of server class expected error")
+	}
+	if got, err := server.Unmarshal(mapInfo); err != nil || !reflect.DeepEqual(got, newMapInfoTestObj()) {
+		t.Errorf("Unmarshal() = %v, %v", got, err)
+	}
+	if _, err := Unmarshal(mapInfo); err == nil {
+		t.Errorf("default registry Unmarshal() of server classId expected error")
+	}
+
+	var buf bytes.Buffer
+	if err := server.NewEncoder(&buf).Encode(&TCodeGenTest{Any: newMapInfoTestObj()}); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	frame := append([]byte(nil), buf.Bytes()...)
+	if _, err := server.NewDecoder(bytes.NewReader(frame)).Decode(); err != nil {
+		t.Errorf("Decode() error = %v", err)
+	}
+	gateway := NewRegistry() // 与服务器使用相同的 classId,但没有注册 TMapInfo
+	gateway.MustRegister(SM_BASE+1, (*TSlotData)(nil))
+	gateway.MustRegister(SM_BASE+3, (*TCodeGenTest)(nil))
+	if _, err := gateway.NewDecoder(bytes.NewReader(frame)).Decode(); err == nil {
+		t.Errorf("Decode() of server class in interface member expected error")
+	}
+
+	// 生成客户端代码只使用各自注册表中的协议
+	var cs bytes.Buffer
+	if err := server.GenerateCSharp(&cs, TCodeGenOptions{}); err != nil || !strings.Contains(cs.String(), fmt.Sprintf("ClassID_MapInfo = %d;", SM_BASE+2)) {
+		t.Errorf("GenerateCSharp() error = %v, want server classIds", err)
+	}
+	if err := client.GenerateCSharp(&cs, TCodeGenOptions{ClassIds: []uint32{SM_BASE + 1}}); err == nil {
+		t.Errorf("GenerateCSharp() of server classId expected error")
+	}
+	var ts bytes.Buffer
+	if err := server.GenerateTypeScript(&ts, TCodeGenOptions{ClassIds: []uint32{SM_BASE + 1}}); err != nil || !strings.Contains(ts.String(), fmt.Sprintf("ClassID_SlotData = %d;", SM_BASE+1)) {
+		t.Errorf("GenerateTypeScript() error = %v, want server classIds", err)
+	}
+	partial := NewRegistry() // TMapInfo 的成员 TSlotData 没有注册
+	partial.MustRegister(SM_BASE+2, (*TMapInfo)(nil))
+	if err := partial.GenerateCSharp(&cs, TCodeGenOptions{}); err == nil {
+		t.Errorf("GenerateCSharp() with unregistered member type expected error")
+	}
+	if err := partial.GenerateTypeScript(&ts, TCodeGenOptions{}); err == nil {
+		t.Errorf("GenerateTypeScript() with unregistered member type expected error")
+	}
+
+	// 导出结构描述只使用各自注册表中的协议
+	var schema bytes.Buffer
+	if err := server.ExportSchema(&schema); err != nil || !strings.Contains(schema.String(), fmt.Sprintf("class TMapInfo = %d {", SM_BASE+2)) {
+		t.Fatalf("ExportSchema() = %q, %v", schema.String(), err)
+	}
+	if err := partial.ExportSchema(io.Discard); err == nil {
+		t.Errorf("ExportSchema() with unregistered member type expected error")
+	}
+
+	// 检查兼容性时与各自注册表中的协议比较
+	old, err := ParseSchema(&schema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	if violations, err := server.CheckCompatibility(old); err != nil || len(violations) != 0 {
+		t.Errorf("CheckCompatibility() = %v, %v, want none", violations, err)
+	}
+	if violations, err := client.CheckCompatibility(old); err != nil || len(violations) != 3 || violations[0].Reason != "class removed" {
+		t.Errorf("CheckCompatibility() = %v, %v, want 3 classes removed", violations, err)
+	}
+	if _, err := partial.CheckCompatibility(old); err == nil {
+		t.Errorf("CheckCompatibility() with unregistered member type expected error")
+	}
+}
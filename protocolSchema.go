@@ -0,0 +1,356 @@
+// 协议结构描述(schema),用于与其他语言和工具约定数据格式
+//
+// 文本格式:
+//
+//	class TSlotData = 1000011 {
+//		Idx int32
+//		SlotType uint16
+//		Extra int32 since=2
+//		flags uint8 private
+//	}
+//
+// 成员类型: 数值类型(bool int8 ... float64), string, longstring, 协议名, *协议名, interface, []T, [N]T, map[K]V
+// longstring 为带 long tag 的成员中的字符串,长度使用 uint32
+// private 表示 Go 的私有成员,与成员名的大小写无关(protocol tag 可以修改成员名)
+package protocol
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TSchema 已注册协议的结构描述
+type TSchema struct {
+	Classes []*TSchemaClass // 按 classId 排序
+}
+
+// TSchemaClass 一个协议的结构描述
+type TSchemaClass struct {
+	ClassId uint32
+	Name    string
+	Fields  []TSchemaField // 按序列化顺序排列
+}
+
+// TSchemaField 协议成员
+type TSchemaField struct {
+	Name     string
+	Type     *TSchemaType
+	Since    int  // 成员加入协议的版本
+	Exported bool // Go 的成员是否公有
+}
+
+// TSchemaType 成员在协议中的编码类型
+type TSchemaType struct {
+	Kind  reflect.Kind // 数值类型、String、Struct、Ptr、Interface、Slice、Array 或 Map
+	Long  bool         // String 的长度使用 uint32
+	Len   int          // 数组长度
+	Class string       // Struct 和 Ptr 对应的协议名
+	Key   *TSchemaType // map 的 key
+	Elem  *TSchemaType // 数组、切片的元素, map 的 value
+}
+
+func (t *TSchemaType) String() string {
+	switch t.Kind {
+	case reflect.Struct:
+		return t.Class
+	case reflect.Ptr:
+		return "*" + t.Class
+	case reflect.Interface:
+		return "interface"
+	case reflect.Slice:
+		return "[]" + t.Elem.String()
+	case reflect.Array:
+		return "[" + strconv.Itoa(t.Len) + "]" + t.Elem.String()
+	case reflect.Map:
+		return "map[" + t.Key.String() + "]" + t.Elem.String()
+	case reflect.String:
+		if t.Long {
+			return "longstring"
+		}
+	}
+	return t.Kind.String()
+}
+
+// Class 按 classId 查找协议
+func (s *TSchema) Class(classId uint32) *TSchemaClass {
+	for _, c := range s.Classes {
+		if c.ClassId == classId {
+			return c
+		}
+	}
+	return nil
+}
+
+// ClassByName 按协议名查找协议
+func (s *TSchema) ClassByName(name string) *TSchemaClass {
+	for _, c := range s.Classes {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// CurrentSchema 返回当前所有已注册协议的结构描述
+func CurrentSchema() (*TSchema, error) {
+	return defaultRegistry.Schema()
+}
+
+// Schema 返回 reg 中所有协议的结构描述
+func (reg *Registry) Schema() (*TSchema, error) {
+	s := &TSchema{}
+	for _, rtti := range reg.Classes() {
+		c := &TSchemaClass{ClassId: rtti.ClassId, Name: rtti.rType.Name()}
+		for i := range rtti.FieldData {
+			field := &rtti.FieldData[i]
+			tp, err := reg.schemaType(field.rType, field.LongString)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", c.Name, field.Name, err)
+			}
+			c.Fields = append(c.Fields, TSchemaField{Name: field.Name, Type: tp, Since: field.Since, Exported: field.Exported})
+		}
+		s.Classes = append(s.Classes, c)
+	}
+	return s, nil
+}
+
+// schemaType 成员类型的结构描述, long 为成员的 long tag
+func (reg *Registry) schemaType(tp reflect.Type, long bool) (*TSchemaType, error) {
+	kind := tp.Kind()
+	if isPod(kind) || kind == reflect.String || kind == reflect.Interface {
+		return &TSchemaType{Kind: kind, Long: long && kind == reflect.String}, nil
+	}
+	switch kind {
+	case reflect.Struct, reflect.Ptr:
+		rtti, ok := reg.FromType(tp)
+		if !ok {
+			return nil, fmt.Errorf("%s isn't register", tp)
+		}
+		return &TSchemaType{Kind: kind, Class: rtti.rType.Name()}, nil
+	case reflect.Slice, reflect.Array:
+		elem, err := reg.schemaType(tp.Elem(), long)
+		if err != nil {
+			return nil, err
+		}
+		t := &TSchemaType{Kind: kind, Elem: elem}
+		if kind == reflect.Array {
+			t.Len = tp.Len()
+		}
+		return t, nil
+	case reflect.Map:
+		key, err := reg.schemaType(tp.Key(), long)
+		if err != nil {
+			return nil, err
+		}
+		elem, err := reg.schemaType(tp.Elem(), long)
+		if err != nil {
+			return nil, err
+		}
+		return &TSchemaType{Kind: kind, Key: key, Elem: elem}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", tp)
+}
+
+// ExportSchema 以文本格式导出所有已注册协议的结构描述
+func ExportSchema(w io.Writer) error {
+	return defaultRegistry.ExportSchema(w)
+}
+
+// ExportSchema 以文本格式导出 reg 中所有协议的结构描述
+func (reg *Registry) ExportSchema(w io.Writer) error {
+	s, err := reg.Schema()
+	if err != nil {
+		return err
+	}
+	return s.Export(w)
+}
+
+// Export 以文本格式输出结构描述, ParseSchema 可以解析输出的内容
+func (s *TSchema) Export(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for i, c := range s.Classes {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		fmt.Fprintf(bw, "class %s = %d {\n", c.Name, c.ClassId)
+		for _, field := range c.Fields {
+			fmt.Fprintf(bw, "\t%s %s", field.Name, field.Type)
+			if !field.Exported {
+				bw.WriteString(" private")
+			}
+			if field.Since > 0 {
+				fmt.Fprintf(bw, " since=%d", field.Since)
+			}
+			bw.WriteString("\n")
+		}
+		bw.WriteString("}\n")
+	}
+	return bw.Flush()
+}
+
+var schemaPodKinds = func() map[string]reflect.Kind {
+	kinds := map[string]reflect.Kind{reflect.String.String(): reflect.String}
+	for kd := reflect.Bool; kd <= reflect.Float64; kd++ {
+		kinds[kd.String()] = kd
+	}
+	return kinds
+}()
+
+// ParseSchema 解析 ExportSchema 输出的文本, // 开头的行为注释
+func ParseSchema(r io.Reader) (*TSchema, error) {
+	s := &TSchema{}
+	var class *TSchemaClass
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		fail := func(format string, args ...interface{}) (*TSchema, error) {
+			return nil, fmt.Errorf("schema line %d: %s", lineNo, fmt.Sprintf(format, args...))
+		}
+		switch {
+		case class == nil:
+			var name string
+			var classId uint32
+			if n, _ := fmt.Sscanf(line, "class %s = %d {", &name, &classId); n != 2 || !isSchemaIdent(name) || !strings.HasSuffix(line, "{") {
+				return fail("expected class declaration, got %q", line)
+			}
+			if s.Class(classId) != nil || s.ClassByName(name) != nil {
+				return fail("duplicate class %s = %d", name, classId)
+			}
+			class = &TSchemaClass{ClassId: classId, Name: name}
+		case line == "}":
+			s.Classes = append(s.Classes, class)
+			class = nil
+		default:
+			parts := strings.Fields(line)
+			if len(parts) < 2 || len(parts) > 4 || !isSchemaIdent(parts[0]) {
+				return fail("expected field declaration, got %q", line)
+			}
+			tp, err := parseSchemaType(parts[1])
+			if err != nil {
+				return fail("%v", err)
+			}
+			field := TSchemaField{Name: parts[0], Type: tp, Exported: true}
+			options := parts[2:]
+			if len(options) > 0 && options[0] == "private" {
+				field.Exported = false
+				options = options[1:]
+			}
+			if len(options) > 1 {
+				return fail("expected field declaration, got %q", line)
+			}
+			if len(options) == 1 {
+				v, ok := strings.CutPrefix(options[0], "since=")
+				if field.Since, err = strconv.Atoi(v); !ok || err != nil || field.Since < 0 {
+					return fail("bad option %q", options[0])
+				}
+			}
+			class.Fields = append(class.Fields, field)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if class != nil {
+		return nil, fmt.Errorf("schema: class %s isn't closed", class.Name)
+	}
+	for _, c := range s.Classes {
+		for _, field := range c.Fields {
+			if err := s.checkClassRef(field.Type); err != nil {
+				return nil, fmt.Errorf("schema: %s.%s: %v", c.Name, field.Name, err)
+			}
+		}
+	}
+	sort.Slice(s.Classes, func(i, j int) bool { return s.Classes[i].ClassId < s.Classes[j].ClassId })
+	return s, nil
+}
+
+func parseSchemaType(str string) (*TSchemaType, error) {
+	if kd, ok := schemaPodKinds[str]; ok {
+		return &TSchemaType{Kind: kd}, nil
+	}
+	switch {
+	case str == "longstring":
+		return &TSchemaType{Kind: reflect.String, Long: true}, nil
+	case str == "interface":
+		return &TSchemaType{Kind: reflect.Interface}, nil
+	case strings.HasPrefix(str, "[]"):
+		elem, err := parseSchemaType(str[2:])
+		if err != nil {
+			return nil, err
+		}
+		return &TSchemaType{Kind: reflect.Slice, Elem: elem}, nil
+	case strings.HasPrefix(str, "["):
+		end := strings.IndexByte(str, ']')
+		if end < 0 {
+			break
+		}
+		n, err := strconv.Atoi(str[1:end])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("bad array length in %q", str)
+		}
+		elem, err := parseSchemaType(str[end+1:])
+		if err != nil {
+			return nil, err
+		}
+		return &TSchemaType{Kind: reflect.Array, Len: n, Elem: elem}, nil
+	case strings.HasPrefix(str, "map["):
+		end := strings.IndexByte(str, ']') // key 只能是数值或字符串,不会包含 ]
+		if end < 0 {
+			break
+		}
+		key, err := parseSchemaType(str[4:end])
+		if err != nil {
+			return nil, err
+		}
+		elem, err := parseSchemaType(str[end+1:])
+		if err != nil {
+			return nil, err
+		}
+		return &TSchemaType{Kind: reflect.Map, Key: key, Elem: elem}, nil
+	case strings.HasPrefix(str, "*"):
+		if isSchemaIdent(str[1:]) {
+			return &TSchemaType{Kind: reflect.Ptr, Class: str[1:]}, nil
+		}
+	case isSchemaIdent(str):
+		return &TSchemaType{Kind: reflect.Struct, Class: str}, nil
+	}
+	return nil, fmt.Errorf("bad type %q", str)
+}
+
+func isSchemaIdent(str string) bool {
+	if str == "" {
+		return false
+	}
+	for i, c := range str {
+		if c != '_' && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && (i == 0 || !('0' <= c && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkClassRef 检查成员引用的协议是否存在
+func (s *TSchema) checkClassRef(t *TSchemaType) error {
+	switch t.Kind {
+	case reflect.Struct, reflect.Ptr:
+		if s.ClassByName(t.Class) == nil {
+			return fmt.Errorf("unknown class %s", t.Class)
+		}
+	case reflect.Slice, reflect.Array:
+		return s.checkClassRef(t.Elem)
+	case reflect.Map:
+		if err := s.checkClassRef(t.Key); err != nil {
+			return err
+		}
+		return s.checkClassRef(t.Elem)
+	}
+	return nil
+}
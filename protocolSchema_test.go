@@ -0,0 +1,84 @@
+package protocol
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExportSchema(t *testing.T) {
+	MustRegisterDataClass(ClassID_CodeGenTest, (*TCodeGenTest)(nil))
+	var buf bytes.Buffer
+	if err := ExportSchema(&buf); err != nil {
+		t.Fatalf("ExportSchema() error = %v", err)
+	}
+	want := `class TMapInfo = 1000012 {
+	Idx int32
+	Name string
+	RefreshPoint uint16
+	SlotList []TSlotData
+	MaxCount int32
+	MaxLineUpCount int32
+	MaxClerk int32
+	MaxCook int32
+	CookExp int32
+	OrderExp int32
+	DeliveryExp int32
+	PointRefreshTime int32
+}
+`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("ExportSchema() = %s, want contains %s", buf.String(), want)
+	}
+
+	s, err := ParseSchema(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+	cur, _ := CurrentSchema()
+	if !reflect.DeepEqual(s, cur) {
+		t.Errorf("ParseSchema() = %+v, want %+v", s, cur)
+	}
+	c := s.Class(ClassID_CodeGenTest)
+	if c == nil || c.Name != "TCodeGenTest" {
+		t.Fatalf("Class(%d) = %+v", ClassID_CodeGenTest, c)
+	}
+	got := make(map[string]string)
+	for _, field := range c.Fields {
+		got[field.Name] = field.Type.String()
+	}
+	for name, tp := range map[string]string{"P": "*TSlotData", "Any": "interface", "Arr": "[3]int16", "MI": "map[int32]*TSlotData", "H": "int", "_": "int32"} {
+		if got[name] != tp {
+			t.Errorf("field %s type = %q, want %q", name, got[name], tp)
+		}
+	}
+}
+
+func TestParseSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr bool
+	}{
+		{"comment", "// 注释\nclass A = 1 {\n\tX [2]map[string]*A\n}\n", false},
+		{"empty", "", false},
+		{"longstring", "class A = 1 {\n\tX map[longstring][]longstring\n}\n", false},
+		{"private", "class A = 1 {\n\tx int32 private since=2\n}\n", false},
+		{"bad option", "class A = 1 {\n\tx int32 since=2 private\n}\n", true},
+		{"not closed", "class A = 1 {\n\tX int32\n", true},
+		{"bad class", "class A {\n}\n", true},
+		{"bad field", "class A = 1 {\n\tX\n}\n", true},
+		{"bad type", "class A = 1 {\n\tX [x]int32\n}\n", true},
+		{"unknown class", "class A = 1 {\n\tX []B\n}\n", true},
+		{"duplicate", "class A = 1 {\n}\nclass B = 1 {\n}\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSchema(strings.NewReader(tt.src))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
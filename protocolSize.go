@@ -0,0 +1,264 @@
+package protocol
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+const emptyHeadSize = 6 // 空数据头(nil 指针、nil interface)的长度
+
+// Size 返回 v 序列化后的字节数,与 Marshal 返回的数据长度相同, v 必须在默认注册表中注册
+func Size(v IMsg) (int, error) {
+	return defaultRegistry.Size(v)
+}
+
+// Size 根据 reg 中的注册信息计算 v 序列化后的字节数,不写入任何数据
+// v 不能序列化时返回与 Marshal 相同类型的错误(不带成员路径)
+func (reg *Registry) Size(v IMsg) (int, error) {
+	s := sizer{reg: reg}
+	return s.anySize(v)
+}
+
+// sizer 计算序列化后的长度
+// sizes 不为 nil 时按写入的顺序记下每个数据头的长度,供写入时选择数据长度字段, map 按 key 排序后计算
+type sizer struct {
+	reg   *Registry
+	sizes *[]sizeEntry
+}
+
+// record 为数据头占一个位置,计算出长度后由 setSize 填入
+func (s *sizer) record(rtti *TRegRttiData) int {
+	if s.sizes == nil {
+		return -1
+	}
+	*s.sizes = append(*s.sizes, sizeEntry{rtti: rtti})
+	return len(*s.sizes) - 1
+}
+
+func (s *sizer) setSize(idx, n int) int {
+	if idx >= 0 {
+		(*s.sizes)[idx].size = n
+	}
+	return n
+}
+
+// dataSize 数据头加上 n 个字节的数据后的长度, classId 或数据长度超过 0xFFFF 时使用 uint32
+func dataSize(classId uint32, n int) int {
+	n += emptyHeadSize
+	if classId > 0xFFFF {
+		n += 2
+	}
+	if n > 0xFFFF {
+		n += 2
+	}
+	return n
+}
+
+// fixedSize 类型序列化后的长度与值无关时返回该长度,否则返回 -1
+func fixedSize(tp reflect.Type) int {
+	switch kind := tp.Kind(); {
+	case isVarInt(kind):
+		return 8
+	case isPod(kind):
+		return int(tp.Size())
+	case kind == reflect.Array:
+		if n := fixedSize(tp.Elem()); n >= 0 {
+			return arrayLenSize + tp.Len()*n
+		}
+	}
+	return -1
+}
+
+// fixedBodySize 所有成员都是定长时返回成员序列化后的总长度,否则返回 -1
+func fixedBodySize(fields []TRegFieldOffsetData) int {
+	n := 0
+	for i := range fields {
+		m := fixedSize(fields[i].rType)
+		if m < 0 {
+			return -1
+		}
+		n += m
+	}
+	return n
+}
+
+// anySize 与 WriteAny 对应
+func (s *sizer) anySize(obj interface{}) (int, error) {
+	if obj == nil {
+		return emptyHeadSize, nil
+	}
+	if rtti, ok := s.reg.FromObj(obj); ok {
+		return s.structSize(PtrOf(obj), rtti)
+	}
+	rtti, ok := builtinTypes[reflect.TypeOf(obj)]
+	if !ok {
+		return 0, fmt.Errorf("%w: %T", ErrNotRegistered, obj)
+	}
+	return s.builtinSize(PtrOf(obj), rtti)
+}
+
+// builtinSize 与 writeBuiltin 对应, ptr 指向内置类型的值
+func (s *sizer) builtinSize(ptr unsafe.Pointer, rtti *TRegRttiData) (int, error) {
+	idx := s.record(rtti)
+	n, err := s.elemSize(rtti.rType.Kind(), rtti.rType, ptr, true)
+	if err != nil {
+		return 0, err
+	}
+	return s.setSize(idx, dataSize(rtti.ClassId, n)), nil
+}
+
+// structSize 与 writeStruct 对应, ptr 为 nil 时是空数据头的长度
+func (s *sizer) structSize(ptr unsafe.Pointer, rtti *TRegRttiData) (int, error) {
+	if ptr == nil {
+		return emptyHeadSize, nil
+	}
+	idx := s.record(rtti)
+	if rtti.bodySize >= 0 {
+		return s.setSize(idx, dataSize(rtti.ClassId, rtti.bodySize)), nil
+	}
+	n := 0
+	for i := 0; i < len(rtti.FieldData); {
+		field := &rtti.FieldData[i]
+		if field.podMergeCount > 1 {
+			n += field.podSize
+			i += field.podMergeCount
+			continue
+		}
+		m, err := s.fieldSize(unsafe.Add(ptr, field.offset), field)
+		if err != nil {
+			return 0, err
+		}
+		n += m
+		i++
+	}
+	return s.setSize(idx, dataSize(rtti.ClassId, n)), nil
+}
+
+// fieldSize 未合并的成员序列化后的长度
+func (s *sizer) fieldSize(ptr unsafe.Pointer, field *TRegFieldOffsetData) (int, error) {
+	switch field.Kind {
+	case reflect.Slice:
+		if n := reflect.NewAt(field.rType, ptr).Elem().Len(); field.MaxLen > 0 && n > field.MaxLen {
+			return 0, fmt.Errorf("%w: %d bytes > max=%d", ErrBlobTooLarge, n, field.MaxLen)
+		}
+	case reflect.Map:
+		return s.mapSize(reflect.NewAt(field.rType, ptr).Elem(), field)
+	}
+	return s.elemSize(field.Kind, field.rType, ptr, field.LongString)
+}
+
+// elemSize 与 writeElem 对应, ptr 指向值本身, long 为成员的 long tag
+func (s *sizer) elemSize(kind reflect.Kind, tp reflect.Type, ptr unsafe.Pointer, long bool) (int, error) {
+	switch {
+	case isVarInt(kind):
+		return 8, nil
+	case isPod(kind):
+		return int(tp.Size()), nil
+	}
+	switch kind {
+	case reflect.String:
+		return stringSize(len(*(*string)(ptr)), long)
+	case reflect.Struct:
+		rtti, ok := s.reg.FromType(tp)
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", ErrNotRegistered, tp)
+		}
+		return s.structSize(ptr, rtti)
+	case reflect.Ptr:
+		rtti, ok := s.reg.FromType(tp)
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", ErrNotRegistered, tp)
+		}
+		return s.structSize(*(*unsafe.Pointer)(ptr), rtti)
+	case reflect.Interface:
+		return s.anySize(*(*interface{})(ptr))
+	case reflect.Slice, reflect.Array:
+		return s.arraySize(tp, ptr, long)
+	}
+	return 0, fmt.Errorf("unsupported element type %s", tp)
+}
+
+// stringSize 长度为 n 的字符串序列化后的长度
+func stringSize(n int, long bool) (int, error) {
+	if long {
+		if uint64(n) > math.MaxUint32 {
+			return 0, fmt.Errorf("%w: %d bytes > %d", ErrStringTooLong, n, uint64(math.MaxUint32))
+		}
+		return longStringLenSize + n, nil
+	}
+	if n > math.MaxUint16 {
+		return 0, fmt.Errorf("%w: %d bytes > %d, use protocol tag option long", ErrStringTooLong, n, math.MaxUint16)
+	}
+	return stringLenSize + n, nil
+}
+
+// arraySize 与 writeArray 对应: 元素个数(uint32) + 元素
+func (s *sizer) arraySize(tp reflect.Type, ptr unsafe.Pointer, long bool) (int, error) {
+	arr := reflect.NewAt(tp, ptr).Elem()
+	if tp.Kind() == reflect.Slice {
+		ptr = arr.UnsafePointer()
+	}
+	count, et := arr.Len(), tp.Elem()
+	if m := fixedSize(et); m >= 0 {
+		return arrayLenSize + count*m, nil
+	}
+	n, size := arrayLenSize, int(et.Size())
+	for i := 0; i < count; i++ {
+		m, err := s.elemSize(et.Kind(), et, unsafe.Add(ptr, i*size), long)
+		if err != nil {
+			return 0, err
+		}
+		n += m
+	}
+	return n, nil
+}
+
+// mapSize 与 writeMap 对应: 元素个数(uint32) + key,value 对
+func (s *sizer) mapSize(m reflect.Value, field *TRegFieldOffsetData) (int, error) {
+	keySize, valSize := fixedSize(field.mapKeyType), fixedSize(field.arrayType)
+	if keySize >= 0 && valSize >= 0 {
+		return arrayLenSize + m.Len()*(keySize+valSize), nil
+	}
+	// map中的元素不可寻址,先复制到临时变量中再计算
+	var val reflect.Value
+	if valSize < 0 {
+		val = reflect.New(field.arrayType).Elem()
+	}
+	entrySize := func(k, v reflect.Value) (int, error) {
+		n := keySize
+		if keySize < 0 { // key 只能是数值类型或 string
+			var err error
+			if n, err = stringSize(k.Len(), field.LongString); err != nil {
+				return 0, err
+			}
+		}
+		if valSize >= 0 {
+			return n + valSize, nil
+		}
+		val.Set(v)
+		e, err := s.elemSize(field.arrayKind, field.arrayType, unsafe.Pointer(val.UnsafeAddr()), field.LongString)
+		return n + e, err
+	}
+	n := arrayLenSize
+	if s.sizes != nil && valSize < 0 { // 按写入的顺序记下 value 中的数据头
+		entries := sortedMapEntries(m)
+		for i := range entries.keys {
+			e, err := entrySize(entries.keys[i], entries.vals[i])
+			if err != nil {
+				return 0, err
+			}
+			n += e
+		}
+		return n, nil
+	}
+	for iter := m.MapRange(); iter.Next(); {
+		e, err := entrySize(iter.Key(), iter.Value())
+		if err != nil {
+			return 0, err
+		}
+		n += e
+	}
+	return n, nil
+}
@@ -0,0 +1,163 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSize(t *testing.T) {
+	const ClassID_TestSize = ClassID_Test + 12
+	type TestSizeMsg struct {
+		Name  string
+		Log   string `protocol:",long"`
+		Any   interface{}
+		Slot  *TSlotData
+		Items map[string][]int32
+		Grid  [][]TSlotData
+	}
+	type TestBigIdMsg struct{ A int32 }
+	reg := NewRegistry()
+	reg.MustRegister(ClassID_SlotData, (*TSlotData)(nil))
+	reg.MustRegister(ClassID_MapInfo, (*TMapInfo)(nil))
+	reg.MustRegister(ClassID_CodeGenTest, (*TCodeGenTest)(nil))
+	if err := reg.Register(ClassID_TestSize, (*TestSizeMsg)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Register(0x10000, (*TestBigIdMsg)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	long := strings.Repeat("x", 0x10000)
+	for _, tt := range []struct {
+		name    string
+		msg     IMsg
+		bigData bool // 数据长度使用 uint32
+	}{
+		{"nil", nil, false},
+		{"pod", &TSlotData{Idx: 1}, false},
+		{"generated", newMapInfoTestObj(), false},
+		{"kinds", newCodeGenTestObj(), false},
+		{"builtin", []string{"a", "bc"}, false},
+		{"fields", &TestSizeMsg{Name: "a", Any: int32(1), Slot: &TSlotData{}, Items: map[string][]int32{"a": {1}, "b": nil}, Grid: [][]TSlotData{{{}}, nil}}, false},
+		{"nil members", &TestSizeMsg{}, false},
+		{"big classId", &TestBigIdMsg{}, false},
+		{"big", &TestSizeMsg{Log: long}, true},
+		{"big member", &TestSizeMsg{Any: &TestSizeMsg{Log: long}}, true},
+		{"big builtin", long, true},
+		{"big interface", &TestSizeMsg{Any: &TestBigIdMsg{}, Grid: make([][]TSlotData, 0x4000)}, true},
+		{"limit", &TestSizeMsg{Log: long[:0xFFFF-6-2-4-6-6-4-4]}, false},
+		{"limit+1", &TestSizeMsg{Log: long[:0xFFFF-6-2-4-6-6-4-4+1]}, true},
+	} {
+		data, err := reg.Marshal(tt.msg)
+		if err != nil {
+			t.Fatalf("%s: Marshal() error = %v", tt.name, err)
+		}
+		if n, err := reg.Size(tt.msg); n != len(data) || err != nil {
+			t.Errorf("%s: Size() = %d, %v, want %d", tt.name, n, err, len(data))
+		}
+		if big := binary.LittleEndian.Uint16(data)&2 != 0; big != tt.bigData {
+			t.Errorf("%s: long data length = %v, want %v", tt.name, big, tt.bigData)
+		}
+		if _, err := reg.Unmarshal(data); err != nil {
+			t.Errorf("%s: Unmarshal() error = %v", tt.name, err)
+		}
+	}
+
+	type unregistered struct{ A int32 }
+	for _, tt := range []struct {
+		name string
+		msg  IMsg
+		want error
+	}{
+		{"unregistered", &unregistered{}, ErrNotRegistered},
+		{"unregistered member", &TestSizeMsg{Any: &unregistered{}}, ErrNotRegistered},
+		{"string too long", &TestSizeMsg{Items: map[string][]int32{long: nil}}, ErrStringTooLong},
+	} {
+		if _, err := reg.Size(tt.msg); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Size() error = %v, want %v", tt.name, err, tt.want)
+		}
+		if _, err := reg.Marshal(tt.msg); !errors.Is(err, tt.want) {
+			t.Errorf("%s: Marshal() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+// TestSizePlan 写入时最外层的协议计算一次长度,嵌套的数据头按写入顺序取出长度
+func TestSizePlan(t *testing.T) {
+	const ClassID_TestSizePlan = ClassID_Test + 17
+	type TestSizePlanMsg struct {
+		Vals map[int32]interface{}
+		Next *TestSizePlanMsg
+	}
+	reg := NewRegistry()
+	reg.MustRegister(ClassID_SlotData, (*TSlotData)(nil))
+	reg.MustRegister(ClassID_MapInfo, (*TMapInfo)(nil))
+	if err := reg.Register(ClassID_TestSizePlan, (*TestSizePlanMsg)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	long := strings.Repeat("x", 0x10000)
+	vals := make(map[int32]interface{})
+	for i := int32(0); i < 32; i++ { // map 的遍历顺序随机,类型相同、长短不同的 value 交替出现
+		vals[i] = []string{long[:i]}
+		if i%4 == 0 {
+			vals[i] = []string{long}
+		}
+	}
+	vals[32] = newMapInfoTestObj()
+	msg := &TestSizePlanMsg{Vals: vals, Next: &TestSizePlanMsg{Next: &TestSizePlanMsg{Vals: vals}}}
+	w := reg.NewProtocolWritter(0)
+	w.BeginStruct(msg) // 没有调用 EndStruct, Reset 之后不影响后面的写入
+	w.Reset()
+	for i := 0; i < 3; i++ {
+		if err := w.WriteAny(msg); err != nil {
+			t.Fatalf("WriteAny() error = %v", err)
+		}
+		if n, err := reg.Size(msg); n != w.Len() || err != nil {
+			t.Errorf("Size() = %d, %v, want %d", n, err, w.Len())
+		}
+		got, err := reg.Unmarshal(w.Bytes())
+		if err != nil || !reflect.DeepEqual(got, msg) {
+			t.Errorf("Unmarshal() = %v", err)
+		}
+		w.Reset()
+	}
+}
+
+// TestWriteDataHead 不知道长度时只有定长的协议使用 uint16 的数据长度
+func TestWriteDataHead(t *testing.T) {
+	slot, _ := GetRegRttiDataFromType(reflect.TypeOf(TSlotData{}))
+	mapInfo, _ := GetRegRttiDataFromType(reflect.TypeOf(TMapInfo{}))
+	for _, tt := range []struct {
+		rtti *TRegRttiData
+		size int // WriteDataHeadSized 的长度, -1 时使用 WriteDataHead
+		want []byte
+	}{
+		{slot, -1, []byte{0x99, 0x6D, 0x4B, 0x42, 0x0F, 0x00, 0, 0}},
+		{mapInfo, -1, []byte{0x9B, 0x6D, 0x4C, 0x42, 0x0F, 0x00, 0, 0, 0, 0}},
+		{mapInfo, 0xFFFF, []byte{0x99, 0x6D, 0x4C, 0x42, 0x0F, 0x00, 0, 0}},
+		{mapInfo, 0x10000, []byte{0x9B, 0x6D, 0x4C, 0x42, 0x0F, 0x00, 0, 0, 0, 0}},
+	} {
+		w := NewProtocolWritter(0)
+		var head ProtocolDataHeaderWritter
+		if tt.size < 0 {
+			w.WriteDataHead(tt.rtti, &head)
+		} else {
+			w.WriteDataHeadSized(tt.rtti, tt.size, &head)
+		}
+		if !bytes.Equal(w.Bytes(), tt.want) {
+			t.Errorf("WriteDataHead(%d, %d) = % x, want % x", tt.rtti.ClassId, tt.size, w.Bytes(), tt.want)
+		}
+	}
+}
+
+func BenchmarkSize(b *testing.B) {
+	testobj := newCodeGenTestObj()
+	MustRegisterDataClass(ClassID_CodeGenTest, (*TCodeGenTest)(nil))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		Size(testobj)
+	}
+}
@@ -0,0 +1,161 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Encoder 将消息逐条写入 io.Writer
+// 每条消息自带数据头(标记+classId+数据长度),可以直接作为 TCP 连接或日志文件中的一帧
+type Encoder struct {
+	w       io.Writer
+	writter *ProtocolWritter
+}
+
+// NewEncoder create new Encoder instance which writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return defaultRegistry.NewEncoder(w)
+}
+
+// NewEncoder 创建只写入 reg 中注册的协议的 Encoder
+func (reg *Registry) NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w:       w,
+		writter: reg.NewProtocolWritter(0),
+	}
+}
+
+// Encode 序列化 v 并将完整的一帧写入底层 io.Writer
+func (e *Encoder) Encode(v IMsg) error {
+	e.writter.Reset()
+	if err := e.writter.WriteAny(v); err != nil {
+		return err
+	}
+	_, err := e.w.Write(e.writter.Bytes())
+	return err
+}
+
+// Decoder 从 io.Reader 中逐条读取消息
+// 根据数据头中的数据长度每次只读取一条完整的消息,不会多读后续消息的数据
+type Decoder struct {
+	r      io.Reader
+	buf    []byte
+	reg    *Registry
+	limits DecoderLimits
+}
+
+// NewDecoder create new Decoder instance which reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return defaultRegistry.NewDecoder(r)
+}
+
+// NewDecoder 创建只解析 reg 中注册的协议的 Decoder
+func (reg *Registry) NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r:      r,
+		buf:    make([]byte, 0, 1024),
+		reg:    reg,
+		limits: reg.DecoderLimits(),
+	}
+}
+
+// dataHeadLength 根据标记位计算数据头长度
+func dataHeadLength(sign uint16) int {
+	l := 6
+	if sign&1 == 1 {
+		l += 2
+	}
+	if sign&2 == 2 {
+		l += 2
+	}
+	return l
+}
+
+// readChunk 读取消息时缓冲区每次至少扩大的字节数
+const readChunk = 64 << 10
+
+// readFull 读取 n 个字节追加到 d.buf 中,数据不足时返回 io.ErrUnexpectedEOF
+// 缓冲区按已经读到的数据逐步扩大,数据头中的长度再大也不会一次分配过多的内存
+func (d *Decoder) readFull(n int) error {
+	for n > 0 {
+		m, step := len(d.buf), n
+		if cap(d.buf)-m < step {
+			grow := cap(d.buf)
+			if grow < readChunk {
+				grow = readChunk
+			}
+			if step > cap(d.buf)+grow-m {
+				step = cap(d.buf) + grow - m
+			}
+			buf := make([]byte, m, cap(d.buf)+grow)
+			copy(buf, d.buf)
+			d.buf = buf
+		}
+		d.buf = d.buf[:m+step]
+		if _, err := io.ReadFull(d.r, d.buf[m:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		n -= step
+	}
+	return nil
+}
+
+// ReadFrame 读取下一条消息的完整二进制数据(包含数据头)
+// 在消息边界上遇到流结束时返回 io.EOF,消息不完整时返回 io.ErrUnexpectedEOF
+// 返回的切片在下一次调用 ReadFrame/Decode/DecodeInto 之前有效
+func (d *Decoder) ReadFrame() ([]byte, error) {
+	d.buf = d.buf[:2]
+	if _, err := io.ReadFull(d.r, d.buf); err != nil { // 一个字节都没有读到时为 io.EOF
+		return nil, err
+	}
+	sign := binary.LittleEndian.Uint16(d.buf)
+	if sign&cSignFlagMask != cSignFlag {
+		return nil, &ErrDecode{Err: ErrBadSign}
+	}
+	if err := d.readFull(dataHeadLength(sign) - 2); err != nil {
+		return nil, err
+	}
+	var dataHead ProtocolDataHeader
+	d.reg.NewProtocolReader(d.buf).ReadDataHead(&dataHead)
+	if !dataHead.isValid {
+		return nil, &ErrDecode{ClassId: dataHead.classId, Err: ErrBadHeader}
+	}
+	if max := d.limits.MaxBytes; max > 0 && lenInt(dataHead.dataLength) > max { // 读取数据前检查,避免按恶意的长度分配内存
+		return nil, &ErrDecode{ClassId: dataHead.classId, Err: &ErrLimitExceeded{Limit: "message bytes", Value: lenInt(dataHead.dataLength), Max: max}}
+	}
+	length := lenInt(dataHead.dataLength)
+	if uint64(length) != uint64(dataHead.dataLength) { // 32 位平台上放不进 []byte 的长度
+		return nil, &ErrDecode{ClassId: dataHead.classId, Err: ErrIntRange}
+	}
+	if err := d.readFull(length - len(d.buf)); err != nil {
+		return nil, err
+	}
+	return d.buf, nil
+}
+
+// Decode 读取并解码下一条消息
+func (d *Decoder) Decode() (interface{}, error) {
+	frame, err := d.ReadFrame()
+	if err != nil {
+		return nil, err
+	}
+	return d.reader(frame).readAny()
+}
+
+// DecodeInto 读取下一条消息并解码到 dst 中,参见 UnmarshalInto
+func (d *Decoder) DecodeInto(dst IMsg) error {
+	frame, err := d.ReadFrame()
+	if err != nil {
+		return err
+	}
+	return d.reader(frame).readInto(dst)
+}
+
+func (d *Decoder) reader(frame []byte) *ProtocolReader {
+	r := d.reg.NewProtocolReader(frame)
+	r.limits = d.limits
+	return r
+}
@@ -0,0 +1,48 @@
+package protocol
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+func TestEncoderDecoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	msgs := []IMsg{newMapInfoTestObj(), &TSlotData{6, 6, true, 6, 6}, newMapInfoTestObj()}
+	for _, msg := range msgs {
+		if err := enc.Encode(msg); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+	}
+	if !bytes.Equal(buf.Bytes()[:len(mapInfoTestData)], mapInfoTestData) {
+		t.Fatalf("Encode() = %v, want %v", buf.Bytes()[:len(mapInfoTestData)], mapInfoTestData)
+	}
+
+	// 每次只读一个字节,模拟网络上的分段读取
+	dec := NewDecoder(iotest.OneByteReader(bytes.NewReader(buf.Bytes())))
+	for i, want := range msgs {
+		got, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("Decode() #%d error = %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Decode() #%d = %+v, want %+v", i, got, want)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("Decode() at end error = %v, want io.EOF", err)
+	}
+}
+
+func TestDecoderTruncated(t *testing.T) {
+	for _, n := range []int{1, 5, len(mapInfoTestData) - 1} {
+		dec := NewDecoder(bytes.NewReader(mapInfoTestData[:n]))
+		var dst TMapInfo
+		if err := dec.DecodeInto(&dst); err != io.ErrUnexpectedEOF {
+			t.Errorf("DecodeInto() with %d bytes error = %v, want io.ErrUnexpectedEOF", n, err)
+		}
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"math"
 	"reflect"
+	"sort"
+	"strconv"
 	"unsafe"
 )
 
@@ -15,21 +19,38 @@ type ProtocolDataHeaderWritter struct {
 	shortClassId bool
 	headerLength uint16
 	startPos     int
+	rtti         *TRegRttiData
+	ownSizes     bool // BeginStruct 计算了嵌套的数据头的长度, EndStruct 时清除
 }
 
 // 序列化写入
 type ProtocolWritter struct {
 	buf []byte
+	reg *Registry // 只能写入在 reg 中注册的协议
+
+	depth       int  // 当前的嵌套深度
+	reflectOnly bool // 不使用生成的代码,见 locateError
+	unsized     bool // 不计算长度,数据长度都使用 uint32,见 locateSizeError
+
+	sizes    []sizeEntry  // 写入最外层的协议前计算出的所有数据头的长度,见 dataHeadSize
+	sizeIdx  int          // 下一个数据头的长度在 sizes 中的位置
+	sizesBuf [8]sizeEntry // 嵌套不多的协议使用的 sizes,不需要另外分配内存
 }
 
 // NewProtocolWritter create new ProtocolWritter instance.
 // bufSize is the initial cap for the internal buffer in bytes.
 func NewProtocolWritter(bufSize int) *ProtocolWritter {
+	return defaultRegistry.NewProtocolWritter(bufSize)
+}
+
+// NewProtocolWritter 创建只写入 reg 中注册的协议的 ProtocolWritter
+func (reg *Registry) NewProtocolWritter(bufSize int) *ProtocolWritter {
 	if bufSize <= 0 {
 		bufSize = 1024
 	}
 	return &ProtocolWritter{
 		buf: make([]byte, 0, bufSize),
+		reg: reg,
 	}
 }
 
@@ -43,6 +64,7 @@ func (b *ProtocolWritter) Len() int { return len(b.buf) }
 func (b *ProtocolWritter) Cap() int { return cap(b.buf) }
 func (b *ProtocolWritter) Reset() {
 	b.buf = b.buf[:0]
+	b.clearSizes()
 }
 
 func (b *ProtocolWritter) tryGrowByReslice(n int) (int, bool) {
@@ -90,8 +112,8 @@ func (b *ProtocolWritter) Write(p []byte) (n int, err error) {
 	return copy(b.buf[m:], p), nil
 }
 
-// writeUint16 write a Uint16
-func (b *ProtocolWritter) writeUint16(v uint16) {
+// WriteUint16 write a Uint16
+func (b *ProtocolWritter) WriteUint16(v uint16) {
 	m, ok := b.tryGrowByReslice(2)
 	if !ok {
 		m = b.grow(2)
@@ -99,7 +121,7 @@ func (b *ProtocolWritter) writeUint16(v uint16) {
 	b.buf[m] = byte(v)
 	b.buf[m+1] = byte(v >> 8)
 }
-func (b *ProtocolWritter) writeUint32(v uint32) {
+func (b *ProtocolWritter) WriteUint32(v uint32) {
 	m, ok := b.tryGrowByReslice(4)
 	if !ok {
 		m = b.grow(4)
@@ -129,8 +151,23 @@ func (b *ProtocolWritter) UpdateDataLength(datlen uint32, head *ProtocolDataHead
 		return 4
 	}
 }
+
+// WriteDataHead 写入数据头,数据长度先写 0,由 UpdateDataLength 回填
+// 所有成员都是定长时按注册信息计算出的长度选择数据长度字段,否则数据长度使用 uint32
+// 知道序列化后的长度时使用 WriteDataHeadSized
 func (b *ProtocolWritter) WriteDataHead(rtti *TRegRttiData, head *ProtocolDataHeaderWritter) {
+	size := math.MaxInt32
+	if rtti != nil && rtti.bodySize >= 0 {
+		size = dataSize(rtti.ClassId, rtti.bodySize)
+	}
+	b.WriteDataHeadSized(rtti, size, head)
+}
+
+// WriteDataHeadSized 写入数据头,数据长度先写 0,由 UpdateDataLength 回填
+// size 为包含数据头的序列化后的长度(即 Size 的结果),超过 0xFFFF 时数据长度使用 uint32
+func (b *ProtocolWritter) WriteDataHeadSized(rtti *TRegRttiData, size int, head *ProtocolDataHeaderWritter) {
 	head.isValid = false
+	head.rtti = rtti
 	if rtti == nil {
 		return
 	}
@@ -138,7 +175,7 @@ func (b *ProtocolWritter) WriteDataHead(rtti *TRegRttiData, head *ProtocolDataHe
 	cId := rtti.ClassId
 
 	head.shortClassId = cId <= 0xFFFF
-	head.shortLenMode = !rtti.BigData
+	head.shortLenMode = size <= 0xFFFF
 	head.headerLength = 6
 	head.startPos = b.Len()
 	lenIdx := 4
@@ -151,193 +188,454 @@ func (b *ProtocolWritter) WriteDataHead(rtti *TRegRttiData, head *ProtocolDataHe
 		sign = sign | 2
 		head.headerLength += 2
 	}
-	b.writeUint16(sign)
+	b.WriteUint16(sign)
 	if head.shortClassId {
-		b.writeUint16(uint16(cId))
+		b.WriteUint16(uint16(cId))
 	} else {
-		b.writeUint32(cId)
+		b.WriteUint32(cId)
 	}
 	if head.shortLenMode {
-		b.writeUint16(0)
+		b.WriteUint16(0)
 	} else {
-		b.writeUint32(0)
+		b.WriteUint32(0)
 	}
 	head.isValid = true
 }
 
 // WriteString write the length of string into the buffer,
 // then it write string data  into the buffer.
-func (b *ProtocolWritter) writeString(s string) {
-	l := uint16(len(s))
-	b.writeUint16(l)
-	if l > 0 {
+// 长度使用 uint16,超过 65535 字节时返回 ErrStringTooLong,不写入任何数据
+func (b *ProtocolWritter) WriteString(s string) error {
+	if len(s) > math.MaxUint16 {
+		return fmt.Errorf("%w: %d bytes > %d, use protocol tag option long", ErrStringTooLong, len(s), math.MaxUint16)
+	}
+	b.WriteUint16(uint16(len(s)))
+	if len(s) > 0 {
 		b.Write([]byte(s))
 	}
+	return nil
+}
+
+// WriteLongString 写入长字符串: 长度(uint32) + utf8内容,用于带 long tag 的成员
+func (b *ProtocolWritter) WriteLongString(s string) error {
+	if uint64(len(s)) > math.MaxUint32 {
+		return fmt.Errorf("%w: %d bytes > %d", ErrStringTooLong, len(s), uint64(math.MaxUint32))
+	}
+	b.WriteUint32(uint32(len(s)))
+	if len(s) > 0 {
+		b.Write([]byte(s))
+	}
+	return nil
+}
+
+// writeString 按成员的 long tag 选择字符串的长度字段
+func (b *ProtocolWritter) writeString(s string, long bool) error {
+	if long {
+		return b.WriteLongString(s)
+	}
+	return b.WriteString(s)
 }
 
 func (b *ProtocolWritter) writeMemory(ptr unsafe.Pointer, len int) {
 	if len <= 0 {
 		return
 	}
-	sliceHeader := reflect.SliceHeader{Data: uintptr(ptr), Len: len, Cap: len}
-	b.Write(*(*[]byte)(unsafe.Pointer(&sliceHeader)))
+	b.Write(unsafe.Slice((*byte)(ptr), len))
 }
 
-func (b *ProtocolWritter) writeAny(obj interface{}) (err error) {
-	if rtti, ok := GetRegRttiDataFromObj(obj); ok {
+// WriteAny 写入一个已注册的对象或内置类型的值, obj 为 nil 时写入空数据头
+func (b *ProtocolWritter) WriteAny(obj interface{}) (err error) {
+	if obj == nil {
+		b.WriteEmptyHeader()
+	} else if rtti, ok := b.reg.FromObj(obj); ok {
 		_, err = b.writeStruct(PtrOf(obj), rtti)
-	} else {
-		err = errors.New("object isn't register")
+	} else if ok, err = b.writeBuiltin(obj); !ok {
+		err = fmt.Errorf("%w: %T", ErrNotRegistered, obj)
 	}
 	return
 }
+
+// writeElem 写入数组、切片或map中的单个元素,ptr 指向元素本身, long 为成员的 long tag
+func (b *ProtocolWritter) writeElem(kind reflect.Kind, tp reflect.Type, ptr unsafe.Pointer, long bool) error {
+	if isMemPod(kind) {
+		b.writePod(ptr, int(tp.Size()), 1)
+		return nil
+	}
+	switch kind {
+	case reflect.Int, reflect.Uint, reflect.Uintptr: // 本机不是 64 位
+		b.writeVarInt(kind, ptr)
+	case reflect.String:
+		return b.writeString(*(*string)(ptr), long)
+	case reflect.Struct:
+		filedRttiData, ok := b.reg.FromType(tp)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrNotRegistered, tp)
+		}
+		if _, err := b.writeStruct(ptr, filedRttiData); err != nil {
+			return err
+		}
+	case reflect.Interface:
+		return b.WriteAny(*(*interface{})(ptr))
+	case reflect.Slice, reflect.Array:
+		return b.writeArray(tp, ptr, long)
+	case reflect.Ptr:
+		filedRttiData, ok := b.reg.FromType(tp)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrNotRegistered, tp)
+		}
+		if ptr = *(*unsafe.Pointer)(ptr); ptr == nil {
+			b.WriteEmptyHeader()
+		} else if _, err := b.writeStruct(ptr, filedRttiData); err != nil {
+			return err
+		}
+	default:
+		return errors.New("unsupported element type " + tp.String())
+	}
+	return nil
+}
+
+// writeArray 写入嵌套的数组或切片: 元素个数(uint32) + 元素, ptr 指向数组或切片本身
+func (b *ProtocolWritter) writeArray(tp reflect.Type, ptr unsafe.Pointer, long bool) error {
+	arr := reflect.NewAt(tp, ptr).Elem()
+	if tp.Kind() == reflect.Slice {
+		ptr = arr.UnsafePointer()
+	}
+	n, et := arr.Len(), tp.Elem()
+	size := int(et.Size())
+	b.WriteUint32(uint32(n))
+	if isMemPod(et.Kind()) {
+		b.writePod(ptr, size, n)
+		return nil
+	}
+	for i := 0; i < n; i++ {
+		if err := b.writeElem(et.Kind(), et, unsafe.Add(ptr, i*size), long); err != nil {
+			e, ok := err.(*ErrEncode)
+			if !ok {
+				e = &ErrEncode{Err: err}
+			}
+			e.Path = prependPath(e.Path, "["+strconv.Itoa(i)+"]")
+			return e
+		}
+	}
+	return nil
+}
+
+// writeMap 写入map: 元素个数(uint32) + 按key升序排列的 key,value 对
+// key排序保证相同内容的map序列化结果一致
+func (b *ProtocolWritter) writeMap(m reflect.Value, rttiField *TRegFieldOffsetData) error {
+	if !isPod(rttiField.mapKeyKind) && rttiField.mapKeyKind != reflect.String {
+		return errors.New("unsupported map key type " + rttiField.mapKeyType.String())
+	}
+	b.WriteUint32(uint32(m.Len()))
+	if m.Len() == 0 {
+		return nil
+	}
+	entries := sortedMapEntries(m)
+	// map中的元素不可寻址,先复制到临时变量中再写入
+	key := reflect.New(rttiField.mapKeyType).Elem()
+	val := reflect.New(rttiField.arrayType).Elem()
+	for i := range entries.keys {
+		key.Set(entries.keys[i])
+		if err := b.writeElem(rttiField.mapKeyKind, rttiField.mapKeyType, unsafe.Pointer(key.UnsafeAddr()), rttiField.LongString); err != nil {
+			return err
+		}
+		val.Set(entries.vals[i])
+		if err := b.writeElem(rttiField.arrayKind, rttiField.arrayType, unsafe.Pointer(val.UnsafeAddr()), rttiField.LongString); err != nil {
+			e, ok := err.(*ErrEncode)
+			if !ok {
+				e = &ErrEncode{Err: err}
+			}
+			e.Path = prependPath(e.Path, mapKeyPath(key))
+			return e
+		}
+	}
+	return nil
+}
+
+// mapEntries 按key升序排列map的元素,key只能是数值类型或string
+type mapEntries struct {
+	keys []reflect.Value
+	vals []reflect.Value
+}
+
+// sortedMapEntries 返回按key升序排列的map元素
+func sortedMapEntries(m reflect.Value) *mapEntries {
+	entries := &mapEntries{keys: make([]reflect.Value, 0, m.Len()), vals: make([]reflect.Value, 0, m.Len())}
+	for iter := m.MapRange(); iter.Next(); {
+		entries.keys = append(entries.keys, iter.Key())
+		entries.vals = append(entries.vals, iter.Value())
+	}
+	sort.Sort(entries)
+	return entries
+}
+
+func (e *mapEntries) Len() int { return len(e.keys) }
+func (e *mapEntries) Swap(i, j int) {
+	e.keys[i], e.keys[j] = e.keys[j], e.keys[i]
+	e.vals[i], e.vals[j] = e.vals[j], e.vals[i]
+}
+func (e *mapEntries) Less(i, j int) bool {
+	a, b := e.keys[i], e.keys[j]
+	switch a.Kind() {
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		fa, fb := a.Float(), b.Float()
+		return fa < fb || (math.IsNaN(fa) && !math.IsNaN(fb))
+	case reflect.String:
+		return a.String() < b.String()
+	}
+	return false
+}
+
 func (b *ProtocolWritter) WriteEmptyHeader() {
-	b.writeUint16(cSignFlag)
-	b.writeUint16(0)
-	b.writeUint16(6)
+	b.WriteUint16(cSignFlag)
+	b.WriteUint16(0)
+	b.WriteUint16(6)
 }
 
-//writeStruct Write a Strcut to bytes
+// writeStruct Write a Strcut to bytes
+// 写入失败时回滚到结构体的起始位置,返回带成员路径的 *ErrEncode
 func (b *ProtocolWritter) writeStruct(ptr unsafe.Pointer, rttiData *TRegRttiData) (n int, err error) {
 	if rttiData == nil {
 		return 0, errors.New("rttiData is nil")
 	}
+	if ptr == nil { // nil 指针写入空数据头
+		b.WriteEmptyHeader()
+		return b.Len(), nil
+	}
+	start := b.Len()
+	if rttiData.marshaler && !b.reflectOnly { // 优先使用 goprotocol-gen 生成的代码
+		planned, sizeIdx := b.sizeIdx < len(b.sizes), b.sizeIdx
+		if err := reflect.NewAt(rttiData.rType, ptr).Interface().(IProtocolMarshaler).MarshalProtocol(b); err != nil {
+			if planned { // 重新写入时从同一个位置取出长度
+				b.sizeIdx = sizeIdx
+			} else {
+				b.clearSizes()
+			}
+			return 0, b.locateError(err, start, ptr, rttiData)
+		}
+		return b.Len(), nil
+	}
+	size, owner, err := b.dataHeadSize(ptr, rttiData)
+	if err != nil {
+		return 0, b.locateSizeError(err, start, ptr, rttiData)
+	}
+	if owner {
+		defer b.clearSizes()
+	}
+	b.depth++
+	defer func() { b.depth-- }()
+	var field *TRegFieldOffsetData // 正在写入的成员,用于记录出错的位置
+	elem := -1
+	defer func() {
+		if err != nil {
+			b.buf = b.buf[:start]
+			err = b.addPath(err, rttiData, field, elem)
+		}
+	}()
 	headWritter := ProtocolDataHeaderWritter{}
-	b.WriteDataHead(rttiData, &headWritter)
+	b.WriteDataHeadSized(rttiData, size, &headWritter)
 	if !headWritter.isValid {
 		return 0, errors.New("write protocol head error")
 	}
-	if ptr == nil {
-		return 0, nil
-	}
 	for idx := 0; idx < len(rttiData.FieldData); {
 		rttiField := &rttiData.FieldData[idx]
+		field, elem = rttiField, -1
 		fieldPtr := unsafe.Pointer(uintptr(ptr) + rttiField.offset)
 		if rttiField.podMergeCount <= 1 { // 未合并的字段
 			if rttiField.isPod() {
-				b.writeMemory(fieldPtr, rttiField.podSize)
+				b.writePod(fieldPtr, rttiField.podSize, 1)
 
 			} else {
 				switch rttiField.Kind {
 				case reflect.String:
-					b.writeString(*(*string)(fieldPtr))
+					if err := b.writeString(*(*string)(fieldPtr), rttiField.LongString); err != nil {
+						return 0, err
+					}
 				case reflect.Array:
-					b.writeUint32(rttiField.arrayLen)
-					fieldPtr = PtrOf((*emptyInterface)(fieldPtr))
-					if isPod(rttiField.arrayKind) { // 元素是pod类型,可以直接写入
-						b.writeMemory(fieldPtr, rttiField.podSize)
+					b.WriteUint32(rttiField.arrayLen)
+					if isMemPod(rttiField.arrayKind) { // 元素是pod类型,可以直接写入
+						b.writePod(fieldPtr, rttiField.arraySize, int(rttiField.arrayLen))
 					} else {
-						switch rttiField.arrayKind {
-						case reflect.String:
-							for i := 0; i < int(rttiField.arrayLen); i++ {
-								b.writeString(*(*string)(unsafe.Pointer(uintptr(fieldPtr) + uintptr(i*rttiField.arraySize))))
-							}
-						case reflect.Struct:
-							if filedRttiData, ok := GetRegRttiDataFromType(rttiField.arrayType); ok {
-								for i := 0; i < int(rttiField.arrayLen); i++ {
-									b.writeStruct(unsafe.Pointer(uintptr(fieldPtr)+uintptr(i*rttiField.arraySize)), filedRttiData)
-								}
-							}
-						case reflect.Interface:
-							for i := 0; i < int(rttiField.arrayLen); i++ {
-								b.writeAny(*(*interface{})(unsafe.Pointer(uintptr(fieldPtr) + uintptr(i*rttiField.arraySize))))
-							}
-						case reflect.Ptr:
-							if filedRttiData, ok := GetRegRttiDataFromType(rttiField.arrayType); ok {
-								for i := 0; i < int(rttiField.arrayLen); i++ {
-									arrPtr := unsafe.Pointer(uintptr(fieldPtr) + uintptr(i*rttiField.arraySize))
-									arrPtr = *(*unsafe.Pointer)(arrPtr)
-									if arrPtr == nil {
-										b.WriteEmptyHeader()
-									} else {
-										b.writeStruct(arrPtr, filedRttiData)
-									}
-								}
+						for i := 0; i < int(rttiField.arrayLen); i++ {
+							elem = i
+							if err := b.writeElem(rttiField.arrayKind, rttiField.arrayType, unsafe.Add(fieldPtr, i*rttiField.arraySize), rttiField.LongString); err != nil {
+								return 0, err
 							}
 						}
 					}
 				case reflect.Slice:
-					slicePtr := (*reflect.SliceHeader)(fieldPtr)
-					b.writeUint32(uint32(slicePtr.Len))
-					if slicePtr.Len == 0 {
+					slice := reflect.NewAt(rttiField.rType, fieldPtr).Elem()
+					if rttiField.MaxLen > 0 && slice.Len() > rttiField.MaxLen {
+						return 0, fmt.Errorf("%w: %d bytes > max=%d", ErrBlobTooLarge, slice.Len(), rttiField.MaxLen)
+					}
+					b.WriteUint32(uint32(slice.Len()))
+					if slice.Len() == 0 {
 						idx++
 						continue
 					}
-					fieldPtr := unsafe.Pointer(slicePtr.Data)
-					if isPod(rttiField.arrayKind) { // 元素是pod类型,可以直接写入
-						fieldPtr = PtrOf((*emptyInterface)(fieldPtr))
-						b.writeMemory(fieldPtr, slicePtr.Len*rttiField.arraySize)
+					fieldPtr := slice.UnsafePointer()
+					if isMemPod(rttiField.arrayKind) { // 元素是pod类型,可以直接写入
+						b.writePod(fieldPtr, rttiField.arraySize, slice.Len())
 					} else {
-						switch rttiField.arrayKind {
-						case reflect.String:
-							for i := 0; i < slicePtr.Len; i++ {
-								b.writeString(*(*string)(unsafe.Pointer(uintptr(fieldPtr) + uintptr(i*rttiField.arraySize))))
-							}
-						case reflect.Struct:
-							if filedRttiData, ok := GetRegRttiDataFromType(rttiField.arrayType); ok {
-								for i := 0; i < slicePtr.Len; i++ {
-									b.writeStruct(unsafe.Pointer(uintptr(fieldPtr)+uintptr(i*rttiField.arraySize)), filedRttiData)
-								}
-							}
-						case reflect.Interface:
-							for i := 0; i < slicePtr.Len; i++ {
-								b.writeAny(*(*interface{})(unsafe.Pointer(uintptr(fieldPtr) + uintptr(i*rttiField.arraySize))))
-							}
-						case reflect.Ptr:
-							if filedRttiData, ok := GetRegRttiDataFromType(rttiField.arrayType); ok {
-								for i := 0; i < slicePtr.Len; i++ {
-									arrPtr := unsafe.Pointer(uintptr(fieldPtr) + uintptr(i*rttiField.arraySize))
-									arrPtr = *(*unsafe.Pointer)(arrPtr)
-									if arrPtr == nil {
-										b.WriteEmptyHeader()
-									} else {
-										b.writeStruct(arrPtr, filedRttiData)
-									}
-								}
+						for i := 0; i < slice.Len(); i++ {
+							elem = i
+							if err := b.writeElem(rttiField.arrayKind, rttiField.arrayType, unsafe.Add(fieldPtr, i*rttiField.arraySize), rttiField.LongString); err != nil {
+								return 0, err
 							}
 						}
-
 					}
-				case reflect.Struct:
-					if filedRttiData, ok := G_DataClass[rttiField.typeHash]; ok {
-						b.writeStruct(fieldPtr, filedRttiData)
+				case reflect.Map:
+					if err := b.writeMap(reflect.NewAt(rttiField.rType, fieldPtr).Elem(), rttiField); err != nil {
+						return 0, err
 					}
-				case reflect.Interface:
-					b.writeAny(*(*interface{})(fieldPtr))
-				case reflect.Ptr:
-					if filedRttiData, ok := G_DataClass[rttiField.typeHash]; ok {
-						fieldPtr = *(*unsafe.Pointer)(fieldPtr)
-						if fieldPtr == nil {
-							b.WriteEmptyHeader()
-						} else {
-							b.writeStruct(fieldPtr, filedRttiData)
-						}
+				case reflect.Struct, reflect.Interface, reflect.Ptr, reflect.Int, reflect.Uint, reflect.Uintptr:
+					if err := b.writeElem(rttiField.Kind, rttiField.rType, fieldPtr, false); err != nil {
+						return 0, err
 					}
+				default:
+					return 0, errors.New("unsupported type " + rttiField.rType.String())
 				}
 			}
 			idx++
 		} else { // 合并的 pod 类型，直接取 podSize
-			b.writeMemory(fieldPtr, rttiField.podSize)
+			b.writePodRun(fieldPtr, rttiData.FieldData[idx:idx+rttiField.podMergeCount])
 			idx += rttiField.podMergeCount
 		}
 	}
-	b.UpdateDataLength(uint32(b.Len()-headWritter.startPos), &headWritter)
+	if err := b.EndStruct(&headWritter); err != nil {
+		return 0, err
+	}
+	return b.Len(), nil
 
-	if !headWritter.isValid { // 非bigData长度却超了
-		if !rttiData.BigData {
-			rttiData.BigData = true
-			tmp := make([]byte, b.Len()-headWritter.startPos-int(headWritter.headerLength))
-			copy(tmp, b.buf[headWritter.startPos+int(headWritter.headerLength):])
+}
 
-			b.buf = b.buf[:headWritter.startPos]
-			b.WriteDataHead(rttiData, &headWritter)
-			b.buf = append(b.buf[:headWritter.startPos], tmp...)
-			b.UpdateDataLength(uint32(b.Len()-headWritter.startPos), &headWritter)
+// addPath 写入 rtti 的成员 field 失败时,把成员加入错误路径, elem 为数组、切片的下标
+func (b *ProtocolWritter) addPath(err error, rtti *TRegRttiData, field *TRegFieldOffsetData, elem int) error {
+	e, ok := err.(*ErrEncode)
+	if !ok {
+		e = &ErrEncode{Err: err}
+	}
+	if field != nil {
+		if elem >= 0 {
+			e.Path = prependPath(e.Path, field.Name+"["+strconv.Itoa(elem)+"]")
 		} else {
-			b.buf = b.buf[:headWritter.startPos]
-			return 0, errors.New("非bigData长度却超了")
+			e.Path = prependPath(e.Path, field.Name)
 		}
 	}
-	return b.Len(), nil
+	e.ClassId = rtti.ClassId
+	if b.depth == 1 { // 最外层的协议
+		e.Path = prependPath(e.Path, rtti.rType.Name())
+	}
+	return e
+}
+
+// locateError 生成的代码不记录出错的成员,写入失败时用反射方式重新写入一遍,定位出错的成员
+func (b *ProtocolWritter) locateError(err error, start int, ptr unsafe.Pointer, rtti *TRegRttiData) error {
+	b.buf = b.buf[:start]
+	b.reflectOnly = true
+	_, located := b.writeStruct(ptr, rtti)
+	b.reflectOnly = false
+	b.buf = b.buf[:start]
+	if located != nil {
+		return located
+	}
+	return err
+}
+
+// BeginStruct 写入 msg 的数据头,写完所有成员后需要调用 EndStruct 回填数据长度
+// 供 goprotocol-gen 生成的 MarshalProtocol 使用
+func (b *ProtocolWritter) BeginStruct(msg IMsg) (head ProtocolDataHeaderWritter, err error) {
+	rttiData, ok := b.reg.FromObj(msg)
+	if !ok {
+		return head, fmt.Errorf("%w: %T", ErrNotRegistered, msg)
+	}
+	size, owner, err := b.dataHeadSize(PtrOf(msg), rttiData)
+	if err != nil {
+		return head, err
+	}
+	b.WriteDataHeadSized(rttiData, size, &head)
+	if !head.isValid {
+		if owner {
+			b.clearSizes()
+		}
+		return head, errors.New("write protocol head error")
+	}
+	head.ownSizes = owner
+	return head, nil
+}
+
+// sizeEntry 写入前计算出的一个数据头中的数据长度
+type sizeEntry struct {
+	rtti *TRegRttiData
+	size int
+}
+
+// dataHeadSize 返回 ptr 指向的协议或内置类型的值序列化后的长度,用于选择数据长度字段
+// 最外层的协议用 Size 的方式计算一次,同时按写入顺序记下所有嵌套的数据头的长度,嵌套的协议依次取出记下的长度
+// owner 为 true 时由调用方计算,写完后需要调用 clearSizes
+func (b *ProtocolWritter) dataHeadSize(ptr unsafe.Pointer, rtti *TRegRttiData) (size int, owner bool, err error) {
+	if b.unsized {
+		return math.MaxInt32, false, nil
+	}
+	if b.sizeIdx < len(b.sizes) {
+		if e := b.sizes[b.sizeIdx]; e.rtti == rtti {
+			b.sizeIdx++
+			return e.size, false, nil
+		}
+	}
+	// 没有计算过,或者写入的顺序与计算时不同(如生成的代码出错后没有调用 EndStruct),重新计算
+	if b.sizes == nil {
+		b.sizes = b.sizesBuf[:0]
+	}
+	b.sizes = b.sizes[:0]
+	s := sizer{reg: b.reg, sizes: &b.sizes}
+	if builtinTypes[rtti.rType] == rtti {
+		_, err = s.builtinSize(ptr, rtti)
+	} else {
+		_, err = s.structSize(ptr, rtti)
+	}
+	if err != nil {
+		b.clearSizes()
+		return 0, false, err
+	}
+	b.sizeIdx = 1
+	return b.sizes[0].size, true, nil
+}
+
+// clearSizes 清除 dataHeadSize 计算出的长度
+func (b *ProtocolWritter) clearSizes() {
+	b.sizes = b.sizes[:0]
+	b.sizeIdx = 0
+}
+
+// locateSizeError 计算长度出错时不带成员路径,不计算长度重新写入一遍,定位出错的成员
+func (b *ProtocolWritter) locateSizeError(err error, start int, ptr unsafe.Pointer, rtti *TRegRttiData) error {
+	unsized := b.unsized
+	b.unsized = true
+	_, located := b.writeStruct(ptr, rtti)
+	b.unsized = unsized
+	b.buf = b.buf[:start]
+	if located != nil {
+		return located
+	}
+	return err
+}
 
+// EndStruct 回填 BeginStruct 写入的数据头中的数据长度
+func (b *ProtocolWritter) EndStruct(head *ProtocolDataHeaderWritter) error {
+	if head.ownSizes {
+		b.clearSizes()
+	}
+	n := b.Len() - head.startPos
+	if b.UpdateDataLength(uint32(n), head); !head.isValid {
+		b.buf = b.buf[:head.startPos]
+		return fmt.Errorf("data length %d does not fit in the short data header", n)
+	}
+	return nil
 }
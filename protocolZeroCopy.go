@@ -0,0 +1,115 @@
+package protocol
+
+import (
+	"reflect"
+	"strings"
+	"unsafe"
+)
+
+// SetZeroCopy 设置解码时是否直接引用输入数据: 字符串、[]byte 和数值切片不再复制,开启时同时开启 SetAliasBlobs
+// 开启后解码结果与输入数据共用内存:
+//   - 输入数据在结果使用期间不能修改或复用,否则字符串的内容会跟着改变
+//   - 修改解码出的数值切片会修改输入数据
+//   - 结果需要在输入数据复用之后继续使用时,先用 Detach 复制一份
+//
+// 需要转换字节序、地址没有按元素类型对齐的数值切片和 []bool 仍然复制
+// 生成的代码中只有字符串和 []byte 引用输入数据
+func (r *ProtocolReader) SetZeroCopy(on bool) { r.aliasStrings, r.aliasBlobs = on, on }
+
+// sliceHeader 切片的内存结构
+type sliceHeader struct {
+	data unsafe.Pointer
+	len  int
+	cap  int
+}
+
+// aliasString 返回与 b 共用内存的字符串
+func aliasString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return unsafe.String(&b[0], len(b))
+}
+
+// aliasPod 开启 SetZeroCopy 时让 ptr 指向的切片直接引用输入数据中的 n 个元素,成功后跳过这些数据
+// 不能引用时返回 false,由调用方复制, []byte 由 aliasBlobs 控制
+func (r *ProtocolReader) aliasPod(ptr unsafe.Pointer, et reflect.Type, n int) bool {
+	kind, size := et.Kind(), int(et.Size())
+	if !r.aliasStrings || swapBytes || !isMemPod(kind) || kind == reflect.Bool || kind == reflect.Uint8 || n <= 0 || n*size > r.Len() {
+		return false
+	}
+	data := unsafe.Pointer(&r.buf[r.off])
+	if uintptr(data)%uintptr(et.Align()) != 0 {
+		return false
+	}
+	*(*sliceHeader)(ptr) = sliceHeader{data: data, len: n, cap: n}
+	r.off += n * size
+	return true
+}
+
+// Detach 复制 v 中的字符串和切片,使 v 不再引用 SetZeroCopy 解码时的输入数据,返回复制后的 v
+// v 为指针时直接修改指向的对象,返回的还是 v;其他值(如 interface 中的字符串)返回复制出的新值
+func Detach(v IMsg) IMsg {
+	if v == nil {
+		return nil
+	}
+	return detachCopy(reflect.ValueOf(v)).Interface()
+}
+
+// detachCopy 把 v 复制到新的变量中,复制其中的字符串和切片后返回
+func detachCopy(v reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	detach(c)
+	return c
+}
+
+// detach 复制 v 中的字符串和切片, v 必须可以寻址
+func detach(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(strings.Clone(v.String()))
+	case reflect.Ptr:
+		if !v.IsNil() {
+			detach(v.Elem())
+		}
+	case reflect.Interface:
+		if !v.IsNil() {
+			v.Set(detachCopy(v.Elem()))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Field(i)
+			if !f.CanSet() { // 私有成员也会被解码
+				f = reflect.NewAt(f.Type(), unsafe.Pointer(f.UnsafeAddr())).Elem()
+			}
+			detach(f)
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			detach(v.Index(i))
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			return
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(c, v)
+		v.Set(c)
+		if isPod(v.Type().Elem().Kind()) {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			detach(v.Index(i))
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return
+		}
+		m := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			m.SetMapIndex(detachCopy(iter.Key()), detachCopy(iter.Value()))
+		}
+		v.Set(m)
+	}
+}
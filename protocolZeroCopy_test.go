@@ -0,0 +1,137 @@
+package protocol
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+// inBuf p 是否指向 buf 中的数据
+func inBuf(p unsafe.Pointer, buf []byte) bool {
+	start := uintptr(unsafe.Pointer(unsafe.SliceData(buf)))
+	return uintptr(p) >= start && uintptr(p) < start+uintptr(len(buf))
+}
+
+func TestZeroCopy(t *testing.T) {
+	const ClassID_TestZeroCopy = ClassID_Test + 13
+	type TestZeroCopyMsg struct {
+		Name  string
+		Vals  []int32
+		Flags []bool
+		Grid  [][]float64
+		Tags  []string
+		Docs  map[string]string
+		Any   interface{}
+		Data  []byte
+		Slot  *TSlotData
+	}
+	reg := NewRegistry()
+	reg.MustRegister(ClassID_SlotData, (*TSlotData)(nil))
+	reg.MustRegister(ClassID_MapInfo, (*TMapInfo)(nil))
+	if err := reg.Register(ClassID_TestZeroCopy, (*TestZeroCopyMsg)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	msg := &TestZeroCopyMsg{
+		Name: "宝山路", Vals: []int32{1, -2, 3}, Flags: []bool{true, false}, Grid: [][]float64{{1.5}, nil, {2.5, 3.5}},
+		Tags: []string{"a", ""}, Docs: map[string]string{"k": "v"}, Any: []string{"x"}, Data: []byte("payload"), Slot: &TSlotData{Idx: 1},
+	}
+	data, err := reg.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	for _, zeroCopy := range []bool{false, true} {
+		aliased := 0
+		for shift := 0; shift < 8; shift++ { // 数值切片只有按元素类型对齐时才能引用
+			buf := make([]byte, shift+len(data))[shift:]
+			copy(buf, data)
+			r := reg.NewProtocolReader(buf)
+			r.SetZeroCopy(zeroCopy)
+			got := &TestZeroCopyMsg{}
+			if err := r.ReadStruct(got); err != nil {
+				t.Fatalf("ReadStruct() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, msg) {
+				t.Fatalf("ReadStruct() = %+v, want %+v", got, msg)
+			}
+			strs := []string{got.Name, got.Tags[0], got.Any.([]string)[0]}
+			for k, v := range got.Docs {
+				strs = append(strs, k, v)
+			}
+			for _, s := range strs {
+				if inBuf(unsafe.Pointer(unsafe.StringData(s)), buf) != zeroCopy {
+					t.Errorf("zeroCopy = %v, string %q shares input = %v", zeroCopy, s, !zeroCopy)
+				}
+			}
+			if inBuf(unsafe.Pointer(&got.Data[0]), buf) != zeroCopy || inBuf(unsafe.Pointer(&got.Flags[0]), buf) {
+				t.Errorf("zeroCopy = %v, Data or Flags shares input", zeroCopy)
+			}
+			if inBuf(unsafe.Pointer(&got.Vals[0]), buf) {
+				aliased |= 1
+				if cap(got.Vals) != len(got.Vals) {
+					t.Errorf("Vals cap = %d, want %d", cap(got.Vals), len(got.Vals))
+				}
+			}
+			if inBuf(unsafe.Pointer(&got.Grid[2][0]), buf) {
+				aliased |= 2
+			}
+
+			// Detach 之后输入数据可以复用
+			Detach(got)
+			clear(buf)
+			if !reflect.DeepEqual(got, msg) {
+				t.Fatalf("Detach() = %+v, want %+v", got, msg)
+			}
+		}
+		if want := map[bool]int{false: 0, true: 3}[zeroCopy]; aliased != want {
+			t.Errorf("zeroCopy = %v, Vals and Grid share input = %b, want %b", zeroCopy, aliased, want)
+		}
+	}
+
+	// 生成的代码
+	buf := append([]byte(nil), mapInfoTestData...)
+	r := reg.NewProtocolReader(buf)
+	r.SetZeroCopy(true)
+	info := &TMapInfo{}
+	if err := r.ReadStruct(info); err != nil || !inBuf(unsafe.Pointer(unsafe.StringData(info.Name)), buf) {
+		t.Errorf("ReadStruct() = %+v, %v, want Name sharing input", info, err)
+	}
+	r = reg.NewProtocolReader(buf[:20])
+	r.SetZeroCopy(true)
+	if err := r.ReadStruct(&TMapInfo{}); !errors.Is(err, ErrTruncated) {
+		t.Errorf("ReadStruct() error = %v, want ErrTruncated", err)
+	}
+
+	// 大端平台上数值切片需要转换字节序,不能引用
+	withSwapBytes(func() {
+		r := reg.NewProtocolReader(data)
+		r.SetZeroCopy(true)
+		got := &TestZeroCopyMsg{}
+		if r.ReadStruct(got); len(got.Vals) != 3 || inBuf(unsafe.Pointer(&got.Vals[0]), data) {
+			t.Errorf("swapBytes: Vals = %v shares input", got.Vals)
+		}
+	})
+
+	if s := []byte("abc"); Detach(aliasString(s)) != "abc" {
+		t.Errorf("Detach() of string")
+	} else if got := Detach(aliasString(s)).(string); inBuf(unsafe.Pointer(unsafe.StringData(got)), s) {
+		t.Errorf("Detach() returned string sharing input")
+	}
+	if Detach(nil) != nil {
+		t.Errorf("Detach(nil) != nil")
+	}
+}
+
+func BenchmarkUnmarshalZeroCopy(b *testing.B) {
+	for _, zeroCopy := range []bool{false, true} {
+		b.Run(map[bool]string{false: "copy", true: "alias"}[zeroCopy], func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r := AcquireReader(mapInfoTestData)
+				r.SetZeroCopy(zeroCopy)
+				r.ReadAny()
+				ReleaseReader(r)
+			}
+		})
+	}
+}
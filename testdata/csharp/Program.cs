@@ -0,0 +1,24 @@
+// 从标准输入逐行读取 Go 序列化的数据(十六进制),反序列化后重新序列化输出
+using System;
+using System.Text;
+using GoProtocol;
+
+public static class Program
+{
+    public static int Main()
+    {
+        string line;
+        while ((line = Console.ReadLine()) != null)
+        {
+            line = line.Trim();
+            if (line.Length == 0) continue;
+            byte[] data = Convert.FromHexString(line);
+            ProtocolReader r = new ProtocolReader(data);
+            IProtocolMessage m = ProtocolFactory.ReadAny(r);
+            ProtocolWriter w = new ProtocolWriter();
+            w.WriteMessage(m);
+            Console.WriteLine(Convert.ToHexString(w.ToArray()).ToLowerInvariant());
+        }
+        return 0;
+    }
+}
@@ -0,0 +1,903 @@
+// Code generated by goprotocol. DO NOT EDIT.
+
+using System;
+using System.Collections.Generic;
+using System.Text;
+
+namespace GoProtocol
+{
+    public class ProtocolException : Exception
+    {
+        public ProtocolException(string message) : base(message) { }
+    }
+
+    public interface IProtocolMessage
+    {
+        uint ProtocolClassId { get; }
+        void Serialize(ProtocolWriter w);
+        void Deserialize(ProtocolReader r);
+    }
+
+    // 数据头: 标记(uint16) + classId(uint16/uint32) + 数据长度(uint16/uint32,包含数据头)
+    public struct ProtocolHeader
+    {
+        public int Start;
+        public int HeaderLength;
+        public uint ClassId;
+        public int DataLength;
+
+        public bool IsNil { get { return DataLength == HeaderLength; } }
+    }
+
+    public class ProtocolWriter
+    {
+        public const ushort SignFlag = 0x6D98;
+
+        private byte[] buf = new byte[256];
+        private int len;
+
+        public int Length { get { return len; } }
+
+        public byte[] ToArray()
+        {
+            byte[] data = new byte[len];
+            Buffer.BlockCopy(buf, 0, data, 0, len);
+            return data;
+        }
+
+        private void Grow(int n)
+        {
+            if (len + n > buf.Length)
+                Array.Resize(ref buf, Math.Max(buf.Length * 2, len + n));
+        }
+
+        public void WriteBool(bool v) { WriteUInt8(v ? (byte)1 : (byte)0); }
+        public void WriteInt8(sbyte v) { WriteUInt8((byte)v); }
+        public void WriteUInt8(byte v)
+        {
+            Grow(1);
+            buf[len++] = v;
+        }
+        public void WriteInt16(short v) { WriteUInt16((ushort)v); }
+        public void WriteUInt16(ushort v)
+        {
+            Grow(2);
+            buf[len++] = (byte)v;
+            buf[len++] = (byte)(v >> 8);
+        }
+        public void WriteInt32(int v) { WriteUInt32((uint)v); }
+        public void WriteUInt32(uint v)
+        {
+            Grow(4);
+            buf[len++] = (byte)v;
+            buf[len++] = (byte)(v >> 8);
+            buf[len++] = (byte)(v >> 16);
+            buf[len++] = (byte)(v >> 24);
+        }
+        public void WriteInt64(long v) { WriteUInt64((ulong)v); }
+        public void WriteUInt64(ulong v)
+        {
+            WriteUInt32((uint)v);
+            WriteUInt32((uint)(v >> 32));
+        }
+        public void WriteFloat32(float v)
+        {
+            byte[] b = BitConverter.GetBytes(v);
+            if (!BitConverter.IsLittleEndian) Array.Reverse(b);
+            WriteBytes(b);
+        }
+        public void WriteFloat64(double v) { WriteInt64(BitConverter.DoubleToInt64Bits(v)); }
+
+        public void WriteBytes(byte[] b)
+        {
+            Grow(b.Length);
+            Buffer.BlockCopy(b, 0, buf, len, b.Length);
+            len += b.Length;
+        }
+
+        // 字符串: utf8长度(uint16) + utf8内容
+        public void WriteString(string s)
+        {
+            byte[] b = Encoding.UTF8.GetBytes(s ?? "");
+            if (b.Length > 0xFFFF) throw new ProtocolException("string is too long");
+            WriteUInt16((ushort)b.Length);
+            WriteBytes(b);
+        }
+
+        // 长字符串: utf8长度(uint32) + utf8内容,对应带 long tag 的成员
+        public void WriteLongString(string s)
+        {
+            byte[] b = Encoding.UTF8.GetBytes(s ?? "");
+            WriteUInt32((uint)b.Length);
+            WriteBytes(b);
+        }
+
+        public void WriteArrayLen(int n) { WriteUInt32((uint)n); }
+
+        // nil 指针和 interface 写入空数据头
+        public void WriteEmptyHeader()
+        {
+            WriteUInt16(SignFlag);
+            WriteUInt16(0);
+            WriteUInt16(6);
+        }
+
+        public void WriteMessage(IProtocolMessage m)
+        {
+            if (m == null) WriteEmptyHeader();
+            else m.Serialize(this);
+        }
+
+        // BeginStruct 写入数据头,返回数据头的位置,写完成员后调用 EndStruct 回填数据长度
+        public int BeginStruct(uint classId)
+        {
+            int start = len;
+            if (classId > 0xFFFF)
+            {
+                WriteUInt16((ushort)(SignFlag | 1));
+                WriteUInt32(classId);
+            }
+            else
+            {
+                WriteUInt16(SignFlag);
+                WriteUInt16((ushort)classId);
+            }
+            WriteUInt16(0);
+            return start;
+        }
+
+        public void EndStruct(int start)
+        {
+            ushort sign = (ushort)(buf[start] | buf[start + 1] << 8);
+            int lenPos = start + ((sign & 1) != 0 ? 6 : 4);
+            int dataLength = len - start;
+            if (dataLength > 0xFFFF) // 数据长度改为 uint32
+            {
+                Grow(2);
+                Buffer.BlockCopy(buf, lenPos + 2, buf, lenPos + 4, len - lenPos - 2);
+                len += 2;
+                dataLength += 2;
+                sign |= 2;
+                buf[start] = (byte)sign;
+                buf[start + 1] = (byte)(sign >> 8);
+                buf[lenPos] = (byte)dataLength;
+                buf[lenPos + 1] = (byte)(dataLength >> 8);
+                buf[lenPos + 2] = (byte)(dataLength >> 16);
+                buf[lenPos + 3] = (byte)(dataLength >> 24);
+            }
+            else
+            {
+                buf[lenPos] = (byte)dataLength;
+                buf[lenPos + 1] = (byte)(dataLength >> 8);
+            }
+        }
+
+        // SortedKeys 按 key 升序排列, 字符串按 utf8 编码比较, 与 Go 保持一致
+        public static List<K> SortedKeys<K, V>(Dictionary<K, V> m)
+        {
+            List<K> keys = new List<K>(m.Keys);
+            if (typeof(K) == typeof(string))
+                keys.Sort((a, b) => Utf8Compare((string)(object)a, (string)(object)b));
+            else
+                keys.Sort();
+            return keys;
+        }
+
+        private static int Utf8Compare(string a, string b)
+        {
+            byte[] x = Encoding.UTF8.GetBytes(a);
+            byte[] y = Encoding.UTF8.GetBytes(b);
+            for (int i = 0; i < x.Length && i < y.Length; i++)
+            {
+                if (x[i] != y[i]) return x[i] < y[i] ? -1 : 1;
+            }
+            return x.Length.CompareTo(y.Length);
+        }
+    }
+
+    public class ProtocolReader
+    {
+        private readonly byte[] buf;
+        private int off;
+        private readonly int end;
+
+        public ProtocolReader(byte[] data) : this(data, 0, data.Length) { }
+        public ProtocolReader(byte[] data, int offset, int count)
+        {
+            buf = data;
+            off = offset;
+            end = offset + count;
+        }
+
+        public int Position { get { return off; } }
+        public int Remaining { get { return end - off; } }
+
+        private int Next(int n)
+        {
+            if (n < 0 || Remaining < n) throw new ProtocolException("data is truncated");
+            int pos = off;
+            off += n;
+            return pos;
+        }
+
+        public bool ReadBool() { return ReadUInt8() != 0; }
+        public sbyte ReadInt8() { return (sbyte)ReadUInt8(); }
+        public byte ReadUInt8() { return buf[Next(1)]; }
+        public short ReadInt16() { return (short)ReadUInt16(); }
+        public ushort ReadUInt16()
+        {
+            int p = Next(2);
+            return (ushort)(buf[p] | buf[p + 1] << 8);
+        }
+        public int ReadInt32() { return (int)ReadUInt32(); }
+        public uint ReadUInt32()
+        {
+            int p = Next(4);
+            return (uint)(buf[p] | buf[p + 1] << 8 | buf[p + 2] << 16 | buf[p + 3] << 24);
+        }
+        public long ReadInt64() { return (long)ReadUInt64(); }
+        public ulong ReadUInt64()
+        {
+            ulong lo = ReadUInt32();
+            ulong hi = ReadUInt32();
+            return lo | hi << 32;
+        }
+        public float ReadFloat32()
+        {
+            byte[] b = new byte[4];
+            Buffer.BlockCopy(buf, Next(4), b, 0, 4);
+            if (!BitConverter.IsLittleEndian) Array.Reverse(b);
+            return BitConverter.ToSingle(b, 0);
+        }
+        public double ReadFloat64() { return BitConverter.Int64BitsToDouble(ReadInt64()); }
+
+        public string ReadString()
+        {
+            int n = ReadUInt16();
+            return Encoding.UTF8.GetString(buf, Next(n), n);
+        }
+        public string ReadLongString()
+        {
+            int n = (int)ReadUInt32(); // 超过 int.MaxValue 时为负数, Next 视为数据错误
+            return Encoding.UTF8.GetString(buf, Next(n), n);
+        }
+
+        // ReadArrayLen 每个元素至少占用一个字节,超过剩余数据长度时视为数据错误
+        public int ReadArrayLen()
+        {
+            uint n = ReadUInt32();
+            if (n > (uint)Remaining) throw new ProtocolException("array length out of range");
+            return (int)n;
+        }
+
+        public ProtocolHeader PeekHeader()
+        {
+            int pos = off;
+            ProtocolHeader h = ReadHeader();
+            off = pos;
+            return h;
+        }
+
+        public ProtocolHeader ReadHeader()
+        {
+            ProtocolHeader h = new ProtocolHeader();
+            h.Start = off;
+            ushort sign = ReadUInt16();
+            if ((sign & 0xFFFC) != ProtocolWriter.SignFlag) throw new ProtocolException("bad sign");
+            h.HeaderLength = 6;
+            if ((sign & 1) != 0)
+            {
+                h.HeaderLength += 2;
+                h.ClassId = ReadUInt32();
+            }
+            else
+            {
+                h.ClassId = ReadUInt16();
+            }
+            if ((sign & 2) != 0)
+            {
+                h.HeaderLength += 2;
+                h.DataLength = (int)ReadUInt32();
+            }
+            else
+            {
+                h.DataLength = ReadUInt16();
+            }
+            if (h.DataLength < h.HeaderLength || h.DataLength > end - h.Start) throw new ProtocolException("bad data length");
+            return h;
+        }
+
+        // ReadNil 如果是 nil 写入的空数据头,跳过它并返回 true
+        public bool ReadNil()
+        {
+            ProtocolHeader h = PeekHeader();
+            if (!h.IsNil) return false;
+            off = h.Start + h.DataLength;
+            return true;
+        }
+
+        public ProtocolHeader BeginStruct(uint classId)
+        {
+            ProtocolHeader h = ReadHeader();
+            if (!h.IsNil && h.ClassId != classId) throw new ProtocolException("classId mismatch: want " + classId + ", got " + h.ClassId);
+            return h;
+        }
+
+        // More 旧版本的数据成员较少,读取成员前需要检查
+        public bool More(ProtocolHeader h) { return off < h.Start + h.DataLength; }
+
+        // EndStruct 跳过新版本追加的成员
+        public void EndStruct(ProtocolHeader h) { off = h.Start + h.DataLength; }
+
+        public T ReadStruct<T>(T m) where T : IProtocolMessage
+        {
+            m.Deserialize(this);
+            return m;
+        }
+    }
+
+    public static class ProtocolClassIds
+    {
+        public const uint ClassID_CodeGenTest = 1002;
+        public const uint ClassID_SlotData = 1000011;
+        public const uint ClassID_MapInfo = 1000012;
+    }
+
+    public static class ProtocolFactory
+    {
+        public static IProtocolMessage Create(uint classId)
+        {
+            switch (classId)
+            {
+                case ProtocolClassIds.ClassID_CodeGenTest: return new TCodeGenTest();
+                case ProtocolClassIds.ClassID_SlotData: return new TSlotData();
+                case ProtocolClassIds.ClassID_MapInfo: return new TMapInfo();
+                default: return null;
+            }
+        }
+
+        // ReadAny 读取 interface 成员中存放的协议, nil 返回 null
+        public static IProtocolMessage ReadAny(ProtocolReader r)
+        {
+            ProtocolHeader h = r.PeekHeader();
+            if (h.IsNil) { r.ReadNil(); return null; }
+            IProtocolMessage m = Create(h.ClassId);
+            if (m == null) throw new ProtocolException("unknown classId " + h.ClassId);
+            m.Deserialize(r);
+            return m;
+        }
+    }
+
+    public class TCodeGenTest : IProtocolMessage
+    {
+        public uint ProtocolClassId { get { return ProtocolClassIds.ClassID_CodeGenTest; } }
+
+        public sbyte A;
+        public byte B;
+        public short C;
+        public ulong D;
+        public bool E;
+        public float F;
+        public double G;
+        public long H;
+        public string S = "";
+        public TSlotData Slot = new TSlotData();
+        public TSlotData P;
+        public TSlotData Nil;
+        public IProtocolMessage Any;
+        public List<string> List = new List<string>();
+        public List<TSlotData> Slots = new List<TSlotData>();
+        public short[] Arr = new short[3];
+        public Dictionary<string, int> M = new Dictionary<string, int>();
+        public Dictionary<int, TSlotData> MI = new Dictionary<int, TSlotData>();
+        public Dictionary<double, bool> Empty = new Dictionary<double, bool>();
+        public string Log = "";
+        public List<List<int>> Grid = new List<List<int>>();
+        public float[][] Mat = new float[2][];
+        public Dictionary<int, List<string>> Rows = new Dictionary<int, List<string>>();
+        public int _blank23;
+        public ushort Base;
+
+        public void Serialize(ProtocolWriter w)
+        {
+            int start = w.BeginStruct(ProtocolClassId);
+            w.WriteInt8(A);
+            w.WriteUInt8(B);
+            w.WriteInt16(C);
+            w.WriteUInt64(D);
+            w.WriteBool(E);
+            w.WriteFloat32(F);
+            w.WriteFloat64(G);
+            w.WriteInt64(H);
+            w.WriteString(S);
+            (Slot ?? new TSlotData()).Serialize(w);
+            w.WriteMessage(P);
+            w.WriteMessage(Nil);
+            w.WriteMessage(Any);
+            w.WriteArrayLen(List == null ? 0 : List.Count);
+            if (List != null)
+            {
+                foreach (var e0 in List)
+                {
+                    w.WriteString(e0);
+                }
+            }
+            w.WriteArrayLen(Slots == null ? 0 : Slots.Count);
+            if (Slots != null)
+            {
+                foreach (var e0 in Slots)
+                {
+                    w.WriteMessage(e0);
+                }
+            }
+            w.WriteArrayLen(3);
+            for (int i0 = 0; i0 < 3; i0++)
+            {
+                short e0 = Arr != null && i0 < Arr.Length ? Arr[i0] : default(short);
+                w.WriteInt16(e0);
+            }
+            w.WriteArrayLen(M == null ? 0 : M.Count);
+            if (M != null)
+            {
+                foreach (var k0 in ProtocolWriter.SortedKeys(M))
+                {
+                    w.WriteString(k0);
+                    w.WriteInt32(M[k0]);
+                }
+            }
+            w.WriteArrayLen(MI == null ? 0 : MI.Count);
+            if (MI != null)
+            {
+                foreach (var k0 in ProtocolWriter.SortedKeys(MI))
+                {
+                    w.WriteInt32(k0);
+                    w.WriteMessage(MI[k0]);
+                }
+            }
+            w.WriteArrayLen(Empty == null ? 0 : Empty.Count);
+            if (Empty != null)
+            {
+                foreach (var k0 in ProtocolWriter.SortedKeys(Empty))
+                {
+                    w.WriteFloat64(k0);
+                    w.WriteBool(Empty[k0]);
+                }
+            }
+            w.WriteLongString(Log);
+            w.WriteArrayLen(Grid == null ? 0 : Grid.Count);
+            if (Grid != null)
+            {
+                foreach (var e0 in Grid)
+                {
+                    w.WriteArrayLen(e0 == null ? 0 : e0.Count);
+                    if (e0 != null)
+                    {
+                        foreach (var e1 in e0)
+                        {
+                            w.WriteInt32(e1);
+                        }
+                    }
+                }
+            }
+            w.WriteArrayLen(2);
+            for (int i0 = 0; i0 < 2; i0++)
+            {
+                float[] e0 = Mat != null && i0 < Mat.Length ? Mat[i0] : default(float[]);
+                w.WriteArrayLen(2);
+                for (int i1 = 0; i1 < 2; i1++)
+                {
+                    float e1 = e0 != null && i1 < e0.Length ? e0[i1] : default(float);
+                    w.WriteFloat32(e1);
+                }
+            }
+            w.WriteArrayLen(Rows == null ? 0 : Rows.Count);
+            if (Rows != null)
+            {
+                foreach (var k0 in ProtocolWriter.SortedKeys(Rows))
+                {
+                    w.WriteInt32(k0);
+                    w.WriteArrayLen(Rows[k0] == null ? 0 : Rows[k0].Count);
+                    if (Rows[k0] != null)
+                    {
+                        foreach (var e1 in Rows[k0])
+                        {
+                            w.WriteString(e1);
+                        }
+                    }
+                }
+            }
+            w.WriteInt32(_blank23);
+            w.WriteUInt16(Base);
+            w.EndStruct(start);
+        }
+
+        public void Deserialize(ProtocolReader r)
+        {
+            A = default(sbyte);
+            B = default(byte);
+            C = default(short);
+            D = default(ulong);
+            E = default(bool);
+            F = default(float);
+            G = default(double);
+            H = default(long);
+            S = "";
+            Slot = new TSlotData();
+            P = default(TSlotData);
+            Nil = default(TSlotData);
+            Any = default(IProtocolMessage);
+            List = new List<string>();
+            Slots = new List<TSlotData>();
+            Arr = new short[3];
+            M = new Dictionary<string, int>();
+            MI = new Dictionary<int, TSlotData>();
+            Empty = new Dictionary<double, bool>();
+            Log = "";
+            Grid = new List<List<int>>();
+            Mat = new float[2][];
+            Rows = new Dictionary<int, List<string>>();
+            _blank23 = default(int);
+            Base = default(ushort);
+            ProtocolHeader h = r.BeginStruct(ProtocolClassId);
+            if (r.More(h))
+            {
+                A = r.ReadInt8();
+            }
+            if (r.More(h))
+            {
+                B = r.ReadUInt8();
+            }
+            if (r.More(h))
+            {
+                C = r.ReadInt16();
+            }
+            if (r.More(h))
+            {
+                D = r.ReadUInt64();
+            }
+            if (r.More(h))
+            {
+                E = r.ReadBool();
+            }
+            if (r.More(h))
+            {
+                F = r.ReadFloat32();
+            }
+            if (r.More(h))
+            {
+                G = r.ReadFloat64();
+            }
+            if (r.More(h))
+            {
+                H = r.ReadInt64();
+            }
+            if (r.More(h))
+            {
+                S = r.ReadString();
+            }
+            if (r.More(h))
+            {
+                Slot = r.ReadStruct(new TSlotData());
+            }
+            if (r.More(h))
+            {
+                P = r.ReadNil() ? null : r.ReadStruct(new TSlotData());
+            }
+            if (r.More(h))
+            {
+                Nil = r.ReadNil() ? null : r.ReadStruct(new TSlotData());
+            }
+            if (r.More(h))
+            {
+                Any = ProtocolFactory.ReadAny(r);
+            }
+            if (r.More(h))
+            {
+                int n0 = r.ReadArrayLen();
+                List = new List<string>(n0);
+                for (int i0 = 0; i0 < n0; i0++)
+                {
+                    string e0 = default(string);
+                    e0 = r.ReadString();
+                    List.Add(e0);
+                }
+            }
+            if (r.More(h))
+            {
+                int n0 = r.ReadArrayLen();
+                Slots = new List<TSlotData>(n0);
+                for (int i0 = 0; i0 < n0; i0++)
+                {
+                    TSlotData e0 = default(TSlotData);
+                    e0 = r.ReadNil() ? null : r.ReadStruct(new TSlotData());
+                    Slots.Add(e0);
+                }
+            }
+            if (r.More(h))
+            {
+                int n0 = r.ReadArrayLen();
+                for (int i0 = 0; i0 < n0; i0++)
+                {
+                    short e0 = default(short);
+                    e0 = r.ReadInt16();
+                    if (i0 < 3) Arr[i0] = e0;
+                }
+            }
+            if (r.More(h))
+            {
+                int n0 = r.ReadArrayLen();
+                M = new Dictionary<string, int>(n0);
+                for (int i0 = 0; i0 < n0; i0++)
+                {
+                    string k0 = default(string);
+                    int v0 = default(int);
+                    k0 = r.ReadString();
+                    v0 = r.ReadInt32();
+                    M[k0] = v0;
+                }
+            }
+            if (r.More(h))
+            {
+                int n0 = r.ReadArrayLen();
+                MI = new Dictionary<int, TSlotData>(n0);
+                for (int i0 = 0; i0 < n0; i0++)
+                {
+                    int k0 = default(int);
+                    TSlotData v0 = default(TSlotData);
+                    k0 = r.ReadInt32();
+                    v0 = r.ReadNil() ? null : r.ReadStruct(new TSlotData());
+                    MI[k0] = v0;
+                }
+            }
+            if (r.More(h))
+            {
+                int n0 = r.ReadArrayLen();
+                Empty = new Dictionary<double, bool>(n0);
+                for (int i0 = 0; i0 < n0; i0++)
+                {
+                    double k0 = default(double);
+                    bool v0 = default(bool);
+                    k0 = r.ReadFloat64();
+                    v0 = r.ReadBool();
+                    Empty[k0] = v0;
+                }
+            }
+            if (r.More(h))
+            {
+                Log = r.ReadLongString();
+            }
+            if (r.More(h))
+            {
+                int n0 = r.ReadArrayLen();
+                Grid = new List<List<int>>(n0);
+                for (int i0 = 0; i0 < n0; i0++)
+                {
+                    List<int> e0 = default(List<int>);
+                    int n1 = r.ReadArrayLen();
+                    e0 = new List<int>(n1);
+                    for (int i1 = 0; i1 < n1; i1++)
+                    {
+                        int e1 = default(int);
+                        e1 = r.ReadInt32();
+                        e0.Add(e1);
+                    }
+                    Grid.Add(e0);
+                }
+            }
+            if (r.More(h))
+            {
+                int n0 = r.ReadArrayLen();
+                for (int i0 = 0; i0 < n0; i0++)
+                {
+                    float[] e0 = new float[2];
+                    int n1 = r.ReadArrayLen();
+                    for (int i1 = 0; i1 < n1; i1++)
+                    {
+                        float e1 = default(float);
+                        e1 = r.ReadFloat32();
+                        if (i1 < 2) e0[i1] = e1;
+                    }
+                    if (i0 < 2) Mat[i0] = e0;
+                }
+            }
+            if (r.More(h))
+            {
+                int n0 = r.ReadArrayLen();
+                Rows = new Dictionary<int, List<string>>(n0);
+                for (int i0 = 0; i0 < n0; i0++)
+                {
+                    int k0 = default(int);
+                    List<string> v0 = default(List<string>);
+                    k0 = r.ReadInt32();
+                    int n1 = r.ReadArrayLen();
+                    v0 = new List<string>(n1);
+                    for (int i1 = 0; i1 < n1; i1++)
+                    {
+                        string e1 = default(string);
+                        e1 = r.ReadString();
+                        v0.Add(e1);
+                    }
+                    Rows[k0] = v0;
+                }
+            }
+            if (r.More(h))
+            {
+                _blank23 = r.ReadInt32();
+            }
+            if (r.More(h))
+            {
+                Base = r.ReadUInt16();
+            }
+            r.EndStruct(h);
+        }
+    }
+
+    public class TSlotData : IProtocolMessage
+    {
+        public uint ProtocolClassId { get { return ProtocolClassIds.ClassID_SlotData; } }
+
+        public int Idx;
+        public ushort SlotType;
+        public bool BoSit;
+        public int PlaceItemId;
+        public int SitPersonId;
+
+        public void Serialize(ProtocolWriter w)
+        {
+            int start = w.BeginStruct(ProtocolClassId);
+            w.WriteInt32(Idx);
+            w.WriteUInt16(SlotType);
+            w.WriteBool(BoSit);
+            w.WriteInt32(PlaceItemId);
+            w.WriteInt32(SitPersonId);
+            w.EndStruct(start);
+        }
+
+        public void Deserialize(ProtocolReader r)
+        {
+            Idx = default(int);
+            SlotType = default(ushort);
+            BoSit = default(bool);
+            PlaceItemId = default(int);
+            SitPersonId = default(int);
+            ProtocolHeader h = r.BeginStruct(ProtocolClassId);
+            if (r.More(h))
+            {
+                Idx = r.ReadInt32();
+            }
+            if (r.More(h))
+            {
+                SlotType = r.ReadUInt16();
+            }
+            if (r.More(h))
+            {
+                BoSit = r.ReadBool();
+            }
+            if (r.More(h))
+            {
+                PlaceItemId = r.ReadInt32();
+            }
+            if (r.More(h))
+            {
+                SitPersonId = r.ReadInt32();
+            }
+            r.EndStruct(h);
+        }
+    }
+
+    public class TMapInfo : IProtocolMessage
+    {
+        public uint ProtocolClassId { get { return ProtocolClassIds.ClassID_MapInfo; } }
+
+        public int Idx;
+        public string Name = "";
+        public ushort RefreshPoint;
+        public List<TSlotData> SlotList = new List<TSlotData>();
+        public int MaxCount;
+        public int MaxLineUpCount;
+        public int MaxClerk;
+        public int MaxCook;
+        public int CookExp;
+        public int OrderExp;
+        public int DeliveryExp;
+        public int PointRefreshTime;
+
+        public void Serialize(ProtocolWriter w)
+        {
+            int start = w.BeginStruct(ProtocolClassId);
+            w.WriteInt32(Idx);
+            w.WriteString(Name);
+            w.WriteUInt16(RefreshPoint);
+            w.WriteArrayLen(SlotList == null ? 0 : SlotList.Count);
+            if (SlotList != null)
+            {
+                foreach (var e0 in SlotList)
+                {
+                    (e0 ?? new TSlotData()).Serialize(w);
+                }
+            }
+            w.WriteInt32(MaxCount);
+            w.WriteInt32(MaxLineUpCount);
+            w.WriteInt32(MaxClerk);
+            w.WriteInt32(MaxCook);
+            w.WriteInt32(CookExp);
+            w.WriteInt32(OrderExp);
+            w.WriteInt32(DeliveryExp);
+            w.WriteInt32(PointRefreshTime);
+            w.EndStruct(start);
+        }
+
+        public void Deserialize(ProtocolReader r)
+        {
+            Idx = default(int);
+            Name = "";
+            RefreshPoint = default(ushort);
+            SlotList = new List<TSlotData>();
+            MaxCount = default(int);
+            MaxLineUpCount = default(int);
+            MaxClerk = default(int);
+            MaxCook = default(int);
+            CookExp = default(int);
+            OrderExp = default(int);
+            DeliveryExp = default(int);
+            PointRefreshTime = default(int);
+            ProtocolHeader h = r.BeginStruct(ProtocolClassId);
+            if (r.More(h))
+            {
+                Idx = r.ReadInt32();
+            }
+            if (r.More(h))
+            {
+                Name = r.ReadString();
+            }
+            if (r.More(h))
+            {
+                RefreshPoint = r.ReadUInt16();
+            }
+            if (r.More(h))
+            {
+                int n0 = r.ReadArrayLen();
+                SlotList = new List<TSlotData>(n0);
+                for (int i0 = 0; i0 < n0; i0++)
+                {
+                    TSlotData e0 = default(TSlotData);
+                    e0 = r.ReadStruct(new TSlotData());
+                    SlotList.Add(e0);
+                }
+            }
+            if (r.More(h))
+            {
+                MaxCount = r.ReadInt32();
+            }
+            if (r.More(h))
+            {
+                MaxLineUpCount = r.ReadInt32();
+            }
+            if (r.More(h))
+            {
+                MaxClerk = r.ReadInt32();
+            }
+            if (r.More(h))
+            {
+                MaxCook = r.ReadInt32();
+            }
+            if (r.More(h))
+            {
+                CookExp = r.ReadInt32();
+            }
+            if (r.More(h))
+            {
+                OrderExp = r.ReadInt32();
+            }
+            if (r.More(h))
+            {
+                DeliveryExp = r.ReadInt32();
+            }
+            if (r.More(h))
+            {
+                PointRefreshTime = r.ReadInt32();
+            }
+            r.EndStruct(h);
+        }
+    }
+}
@@ -0,0 +1,818 @@
+// Code generated by goprotocol. DO NOT EDIT.
+
+export class ProtocolError extends Error {}
+
+export interface IProtocolMessage {
+  readonly protocolClassId: number;
+  serialize(w: ProtocolWriter): void;
+  deserialize(r: ProtocolReader): void;
+}
+
+// 数据头: 标记(uint16) + classId(uint16/uint32) + 数据长度(uint16/uint32,包含数据头)
+export interface ProtocolHeader {
+  start: number;
+  headerLength: number;
+  classId: number;
+  dataLength: number;
+  isNil: boolean;
+}
+
+export const SignFlag = 0x6d98;
+
+const textEncoder = new TextEncoder();
+const textDecoder = new TextDecoder("utf-8", { fatal: true });
+
+export class ProtocolWriter {
+  private buf = new Uint8Array(256);
+  private view = new DataView(this.buf.buffer);
+  private len = 0;
+
+  get length(): number {
+    return this.len;
+  }
+
+  toBytes(): Uint8Array {
+    return this.buf.slice(0, this.len);
+  }
+
+  // grow 预留 n 个字节,返回写入位置,扩容后 view 会被替换
+  private grow(n: number): number {
+    if (this.len + n > this.buf.length) {
+      const buf = new Uint8Array(Math.max(this.buf.length * 2, this.len + n));
+      buf.set(this.buf.subarray(0, this.len));
+      this.buf = buf;
+      this.view = new DataView(buf.buffer);
+    }
+    const pos = this.len;
+    this.len += n;
+    return pos;
+  }
+
+  writeBool(v: boolean): void {
+    this.writeUint8(v ? 1 : 0);
+  }
+  writeInt8(v: number): void {
+    const pos = this.grow(1);
+    this.view.setInt8(pos, v);
+  }
+  writeUint8(v: number): void {
+    const pos = this.grow(1);
+    this.view.setUint8(pos, v);
+  }
+  writeInt16(v: number): void {
+    const pos = this.grow(2);
+    this.view.setInt16(pos, v, true);
+  }
+  writeUint16(v: number): void {
+    const pos = this.grow(2);
+    this.view.setUint16(pos, v, true);
+  }
+  writeInt32(v: number): void {
+    const pos = this.grow(4);
+    this.view.setInt32(pos, v, true);
+  }
+  writeUint32(v: number): void {
+    const pos = this.grow(4);
+    this.view.setUint32(pos, v, true);
+  }
+  writeInt64(v: bigint): void {
+    const pos = this.grow(8);
+    this.view.setBigInt64(pos, BigInt.asIntN(64, v), true);
+  }
+  writeUint64(v: bigint): void {
+    const pos = this.grow(8);
+    this.view.setBigUint64(pos, BigInt.asUintN(64, v), true);
+  }
+  writeFloat32(v: number): void {
+    const pos = this.grow(4);
+    this.view.setFloat32(pos, v, true);
+  }
+  writeFloat64(v: number): void {
+    const pos = this.grow(8);
+    this.view.setFloat64(pos, v, true);
+  }
+
+  writeBytes(b: Uint8Array): void {
+    const pos = this.grow(b.length);
+    this.buf.set(b, pos);
+  }
+
+  // 字符串: utf8长度(uint16) + utf8内容
+  writeString(s: string): void {
+    const b = textEncoder.encode(s ?? "");
+    if (b.length > 0xffff) {
+      throw new ProtocolError("string is too long");
+    }
+    this.writeUint16(b.length);
+    this.writeBytes(b);
+  }
+
+  // 长字符串: utf8长度(uint32) + utf8内容,对应带 long tag 的成员
+  writeLongString(s: string): void {
+    const b = textEncoder.encode(s ?? "");
+    this.writeUint32(b.length);
+    this.writeBytes(b);
+  }
+
+  writeArrayLen(n: number): void {
+    this.writeUint32(n);
+  }
+
+  // nil 指针和 interface 写入空数据头
+  writeEmptyHeader(): void {
+    this.writeUint16(SignFlag);
+    this.writeUint16(0);
+    this.writeUint16(6);
+  }
+
+  writeMessage(m: IProtocolMessage | null | undefined): void {
+    if (m === null || m === undefined) {
+      this.writeEmptyHeader();
+    } else {
+      m.serialize(this);
+    }
+  }
+
+  // beginStruct 写入数据头,返回数据头的位置,写完成员后调用 endStruct 回填数据长度
+  beginStruct(classId: number): number {
+    const start = this.len;
+    if (classId > 0xffff) {
+      this.writeUint16(SignFlag | 1);
+      this.writeUint32(classId);
+    } else {
+      this.writeUint16(SignFlag);
+      this.writeUint16(classId);
+    }
+    this.writeUint16(0);
+    return start;
+  }
+
+  endStruct(start: number): void {
+    const sign = this.view.getUint16(start, true);
+    const lenPos = start + ((sign & 1) !== 0 ? 6 : 4);
+    const dataLength = this.len - start;
+    if (dataLength > 0xffff) {
+      // 数据长度改为 uint32
+      const end = this.len;
+      this.grow(2);
+      this.buf.copyWithin(lenPos + 4, lenPos + 2, end);
+      this.view.setUint16(start, sign | 2, true);
+      this.view.setUint32(lenPos, dataLength + 2, true);
+    } else {
+      this.view.setUint16(lenPos, dataLength, true);
+    }
+  }
+}
+
+// sortedKeys 按 key 升序排列, 字符串按 utf8 编码比较, 与 Go 保持一致
+export function sortedKeys<K>(m: Map<K, unknown>): K[] {
+  return Array.from(m.keys()).sort(compareKeys);
+}
+
+function compareKeys(a: unknown, b: unknown): number {
+  if (typeof a === "string" && typeof b === "string") {
+    const x = textEncoder.encode(a);
+    const y = textEncoder.encode(b);
+    for (let i = 0; i < x.length && i < y.length; i++) {
+      if (x[i] !== y[i]) {
+        return x[i] < y[i] ? -1 : 1;
+      }
+    }
+    return x.length - y.length;
+  }
+  if (typeof a === "number" && typeof b === "number") {
+    if (Number.isNaN(a) || Number.isNaN(b)) {
+      return Number.isNaN(a) ? (Number.isNaN(b) ? 0 : -1) : 1;
+    }
+  }
+  const x = a as number | bigint;
+  const y = b as number | bigint;
+  return x < y ? -1 : x > y ? 1 : 0;
+}
+
+export class ProtocolReader {
+  private readonly view: DataView;
+  private off: number;
+  private readonly end: number;
+
+  constructor(private readonly data: Uint8Array) {
+    this.view = new DataView(data.buffer, data.byteOffset, data.byteLength);
+    this.off = 0;
+    this.end = data.length;
+  }
+
+  get position(): number {
+    return this.off;
+  }
+
+  get remaining(): number {
+    return this.end - this.off;
+  }
+
+  private next(n: number): number {
+    if (n < 0 || this.remaining < n) {
+      throw new ProtocolError("data is truncated");
+    }
+    const pos = this.off;
+    this.off += n;
+    return pos;
+  }
+
+  readBool(): boolean {
+    return this.readUint8() !== 0;
+  }
+  readInt8(): number {
+    return this.view.getInt8(this.next(1));
+  }
+  readUint8(): number {
+    return this.view.getUint8(this.next(1));
+  }
+  readInt16(): number {
+    return this.view.getInt16(this.next(2), true);
+  }
+  readUint16(): number {
+    return this.view.getUint16(this.next(2), true);
+  }
+  readInt32(): number {
+    return this.view.getInt32(this.next(4), true);
+  }
+  readUint32(): number {
+    return this.view.getUint32(this.next(4), true);
+  }
+  readInt64(): bigint {
+    return this.view.getBigInt64(this.next(8), true);
+  }
+  readUint64(): bigint {
+    return this.view.getBigUint64(this.next(8), true);
+  }
+  readFloat32(): number {
+    return this.view.getFloat32(this.next(4), true);
+  }
+  readFloat64(): number {
+    return this.view.getFloat64(this.next(8), true);
+  }
+
+  readString(): string {
+    const n = this.readUint16();
+    const pos = this.next(n);
+    return textDecoder.decode(this.data.subarray(pos, pos + n));
+  }
+  readLongString(): string {
+    const n = this.readUint32();
+    const pos = this.next(n);
+    return textDecoder.decode(this.data.subarray(pos, pos + n));
+  }
+
+  // readArrayLen 每个元素至少占用一个字节,超过剩余数据长度时视为数据错误
+  readArrayLen(): number {
+    const n = this.readUint32();
+    if (n > this.remaining) {
+      throw new ProtocolError("array length out of range");
+    }
+    return n;
+  }
+
+  peekHeader(): ProtocolHeader {
+    const pos = this.off;
+    const h = this.readHeader();
+    this.off = pos;
+    return h;
+  }
+
+  readHeader(): ProtocolHeader {
+    const start = this.off;
+    const sign = this.readUint16();
+    if ((sign & 0xfffc) !== SignFlag) {
+      throw new ProtocolError("bad sign");
+    }
+    let headerLength = 6;
+    let classId: number;
+    let dataLength: number;
+    if ((sign & 1) !== 0) {
+      headerLength += 2;
+      classId = this.readUint32();
+    } else {
+      classId = this.readUint16();
+    }
+    if ((sign & 2) !== 0) {
+      headerLength += 2;
+      dataLength = this.readUint32();
+    } else {
+      dataLength = this.readUint16();
+    }
+    if (dataLength < headerLength || dataLength > this.end - start) {
+      throw new ProtocolError("bad data length");
+    }
+    return { start, headerLength, classId, dataLength, isNil: dataLength === headerLength };
+  }
+
+  // readNil 如果是 nil 写入的空数据头,跳过它并返回 true
+  readNil(): boolean {
+    const h = this.peekHeader();
+    if (!h.isNil) {
+      return false;
+    }
+    this.off = h.start + h.dataLength;
+    return true;
+  }
+
+  beginStruct(classId: number): ProtocolHeader {
+    const h = this.readHeader();
+    if (!h.isNil && h.classId !== classId) {
+      throw new ProtocolError("classId mismatch: want " + classId + ", got " + h.classId);
+    }
+    return h;
+  }
+
+  // more 旧版本的数据成员较少,读取成员前需要检查
+  more(h: ProtocolHeader): boolean {
+    return this.off < h.start + h.dataLength;
+  }
+
+  // endStruct 跳过新版本追加的成员
+  endStruct(h: ProtocolHeader): void {
+    this.off = h.start + h.dataLength;
+  }
+
+  readStruct<T extends IProtocolMessage>(m: T): T {
+    m.deserialize(this);
+    return m;
+  }
+}
+
+export const ClassID_CodeGenTest = 1002;
+export const ClassID_SlotData = 1000011;
+export const ClassID_MapInfo = 1000012;
+
+export function createMessage(classId: number): IProtocolMessage | null {
+  switch (classId) {
+    case ClassID_CodeGenTest:
+      return new TCodeGenTest();
+    case ClassID_SlotData:
+      return new TSlotData();
+    case ClassID_MapInfo:
+      return new TMapInfo();
+    default:
+      return null;
+  }
+}
+
+// readAny 读取 interface 成员中存放的协议, nil 返回 null
+export function readAny(r: ProtocolReader): IProtocolMessage | null {
+  const h = r.peekHeader();
+  if (h.isNil) {
+    r.readNil();
+    return null;
+  }
+  const m = createMessage(h.classId);
+  if (m === null) {
+    throw new ProtocolError("unknown classId " + h.classId);
+  }
+  m.deserialize(r);
+  return m;
+}
+
+export class TCodeGenTest implements IProtocolMessage {
+  A: number = 0;
+  B: number = 0;
+  C: number = 0;
+  D: bigint = 0n;
+  E: boolean = false;
+  F: number = 0;
+  G: number = 0;
+  H: bigint = 0n;
+  S: string = "";
+  Slot: TSlotData = new TSlotData();
+  P: TSlotData | null = null;
+  Nil: TSlotData | null = null;
+  Any: IProtocolMessage | null = null;
+  List: Array<string> = [];
+  Slots: Array<TSlotData | null> = [];
+  Arr: Array<number> = Array.from({ length: 3 }, () => 0);
+  M: Map<string, number> = new Map();
+  MI: Map<number, TSlotData | null> = new Map();
+  Empty: Map<number, boolean> = new Map();
+  Log: string = "";
+  Grid: Array<Array<number>> = [];
+  Mat: Array<Array<number>> = Array.from({ length: 2 }, () => Array.from({ length: 2 }, () => 0));
+  Rows: Map<number, Array<string>> = new Map();
+  _blank23: number = 0;
+  Base: number = 0;
+
+  get protocolClassId(): number {
+    return ClassID_CodeGenTest;
+  }
+
+  serialize(w: ProtocolWriter): void {
+    const start = w.beginStruct(this.protocolClassId);
+    w.writeInt8(this.A);
+    w.writeUint8(this.B);
+    w.writeInt16(this.C);
+    w.writeUint64(this.D);
+    w.writeBool(this.E);
+    w.writeFloat32(this.F);
+    w.writeFloat64(this.G);
+    w.writeInt64(this.H);
+    w.writeString(this.S);
+    (this.Slot ?? new TSlotData()).serialize(w);
+    w.writeMessage(this.P);
+    w.writeMessage(this.Nil);
+    w.writeMessage(this.Any);
+    {
+      const a0 = this.List ?? [];
+      w.writeArrayLen(a0.length);
+      for (const e0 of a0) {
+        w.writeString(e0);
+      }
+    }
+    {
+      const a0 = this.Slots ?? [];
+      w.writeArrayLen(a0.length);
+      for (const e0 of a0) {
+        w.writeMessage(e0);
+      }
+    }
+    {
+      const a0 = this.Arr ?? [];
+      w.writeArrayLen(3);
+      for (let i0 = 0; i0 < 3; i0++) {
+        const e0 = a0[i0] ?? 0;
+        w.writeInt16(e0);
+      }
+    }
+    {
+      const m0 = this.M ?? new Map();
+      w.writeArrayLen(m0.size);
+      for (const k0 of sortedKeys(m0)) {
+        w.writeString(k0);
+        w.writeInt32((m0.get(k0) as number));
+      }
+    }
+    {
+      const m0 = this.MI ?? new Map();
+      w.writeArrayLen(m0.size);
+      for (const k0 of sortedKeys(m0)) {
+        w.writeInt32(k0);
+        w.writeMessage((m0.get(k0) as TSlotData | null));
+      }
+    }
+    {
+      const m0 = this.Empty ?? new Map();
+      w.writeArrayLen(m0.size);
+      for (const k0 of sortedKeys(m0)) {
+        w.writeFloat64(k0);
+        w.writeBool((m0.get(k0) as boolean));
+      }
+    }
+    w.writeLongString(this.Log);
+    {
+      const a0 = this.Grid ?? [];
+      w.writeArrayLen(a0.length);
+      for (const e0 of a0) {
+        {
+          const a1 = e0 ?? [];
+          w.writeArrayLen(a1.length);
+          for (const e1 of a1) {
+            w.writeInt32(e1);
+          }
+        }
+      }
+    }
+    {
+      const a0 = this.Mat ?? [];
+      w.writeArrayLen(2);
+      for (let i0 = 0; i0 < 2; i0++) {
+        const e0 = a0[i0] ?? Array.from({ length: 2 }, () => 0);
+        {
+          const a1 = e0 ?? [];
+          w.writeArrayLen(2);
+          for (let i1 = 0; i1 < 2; i1++) {
+            const e1 = a1[i1] ?? 0;
+            w.writeFloat32(e1);
+          }
+        }
+      }
+    }
+    {
+      const m0 = this.Rows ?? new Map();
+      w.writeArrayLen(m0.size);
+      for (const k0 of sortedKeys(m0)) {
+        w.writeInt32(k0);
+        {
+          const a1 = (m0.get(k0) as Array<string>) ?? [];
+          w.writeArrayLen(a1.length);
+          for (const e1 of a1) {
+            w.writeString(e1);
+          }
+        }
+      }
+    }
+    w.writeInt32(this._blank23);
+    w.writeUint16(this.Base);
+    w.endStruct(start);
+  }
+
+  deserialize(r: ProtocolReader): void {
+    this.A = 0;
+    this.B = 0;
+    this.C = 0;
+    this.D = 0n;
+    this.E = false;
+    this.F = 0;
+    this.G = 0;
+    this.H = 0n;
+    this.S = "";
+    this.Slot = new TSlotData();
+    this.P = null;
+    this.Nil = null;
+    this.Any = null;
+    this.List = [];
+    this.Slots = [];
+    this.Arr = Array.from({ length: 3 }, () => 0);
+    this.M = new Map();
+    this.MI = new Map();
+    this.Empty = new Map();
+    this.Log = "";
+    this.Grid = [];
+    this.Mat = Array.from({ length: 2 }, () => Array.from({ length: 2 }, () => 0));
+    this.Rows = new Map();
+    this._blank23 = 0;
+    this.Base = 0;
+    const h = r.beginStruct(this.protocolClassId);
+    if (r.more(h)) {
+      this.A = r.readInt8();
+    }
+    if (r.more(h)) {
+      this.B = r.readUint8();
+    }
+    if (r.more(h)) {
+      this.C = r.readInt16();
+    }
+    if (r.more(h)) {
+      this.D = r.readUint64();
+    }
+    if (r.more(h)) {
+      this.E = r.readBool();
+    }
+    if (r.more(h)) {
+      this.F = r.readFloat32();
+    }
+    if (r.more(h)) {
+      this.G = r.readFloat64();
+    }
+    if (r.more(h)) {
+      this.H = r.readInt64();
+    }
+    if (r.more(h)) {
+      this.S = r.readString();
+    }
+    if (r.more(h)) {
+      this.Slot = r.readStruct(new TSlotData());
+    }
+    if (r.more(h)) {
+      this.P = r.readNil() ? null : r.readStruct(new TSlotData());
+    }
+    if (r.more(h)) {
+      this.Nil = r.readNil() ? null : r.readStruct(new TSlotData());
+    }
+    if (r.more(h)) {
+      this.Any = readAny(r);
+    }
+    if (r.more(h)) {
+      const n0 = r.readArrayLen();
+      for (let i0 = 0; i0 < n0; i0++) {
+        this.List.push(r.readString());
+      }
+    }
+    if (r.more(h)) {
+      const n0 = r.readArrayLen();
+      for (let i0 = 0; i0 < n0; i0++) {
+        this.Slots.push(r.readNil() ? null : r.readStruct(new TSlotData()));
+      }
+    }
+    if (r.more(h)) {
+      const n0 = r.readArrayLen();
+      for (let i0 = 0; i0 < n0; i0++) {
+        const e0 = r.readInt16();
+        if (i0 < 3) {
+          this.Arr[i0] = e0;
+        }
+      }
+    }
+    if (r.more(h)) {
+      const n0 = r.readArrayLen();
+      for (let i0 = 0; i0 < n0; i0++) {
+        const k0 = r.readString();
+        this.M.set(k0, r.readInt32());
+      }
+    }
+    if (r.more(h)) {
+      const n0 = r.readArrayLen();
+      for (let i0 = 0; i0 < n0; i0++) {
+        const k0 = r.readInt32();
+        this.MI.set(k0, r.readNil() ? null : r.readStruct(new TSlotData()));
+      }
+    }
+    if (r.more(h)) {
+      const n0 = r.readArrayLen();
+      for (let i0 = 0; i0 < n0; i0++) {
+        const k0 = r.readFloat64();
+        this.Empty.set(k0, r.readBool());
+      }
+    }
+    if (r.more(h)) {
+      this.Log = r.readLongString();
+    }
+    if (r.more(h)) {
+      const n0 = r.readArrayLen();
+      for (let i0 = 0; i0 < n0; i0++) {
+        const e0: Array<number> = [];
+        const n1 = r.readArrayLen();
+        for (let i1 = 0; i1 < n1; i1++) {
+          e0.push(r.readInt32());
+        }
+        this.Grid.push(e0);
+      }
+    }
+    if (r.more(h)) {
+      const n0 = r.readArrayLen();
+      for (let i0 = 0; i0 < n0; i0++) {
+        const e0: Array<number> = Array.from({ length: 2 }, () => 0);
+        const n1 = r.readArrayLen();
+        for (let i1 = 0; i1 < n1; i1++) {
+          const e1 = r.readFloat32();
+          if (i1 < 2) {
+            e0[i1] = e1;
+          }
+        }
+        if (i0 < 2) {
+          this.Mat[i0] = e0;
+        }
+      }
+    }
+    if (r.more(h)) {
+      const n0 = r.readArrayLen();
+      for (let i0 = 0; i0 < n0; i0++) {
+        const k0 = r.readInt32();
+        const v0: Array<string> = [];
+        const n1 = r.readArrayLen();
+        for (let i1 = 0; i1 < n1; i1++) {
+          v0.push(r.readString());
+        }
+        this.Rows.set(k0, v0);
+      }
+    }
+    if (r.more(h)) {
+      this._blank23 = r.readInt32();
+    }
+    if (r.more(h)) {
+      this.Base = r.readUint16();
+    }
+    r.endStruct(h);
+  }
+}
+
+export class TSlotData implements IProtocolMessage {
+  Idx: number = 0;
+  SlotType: number = 0;
+  BoSit: boolean = false;
+  PlaceItemId: number = 0;
+  SitPersonId: number = 0;
+
+  get protocolClassId(): number {
+    return ClassID_SlotData;
+  }
+
+  serialize(w: ProtocolWriter): void {
+    const start = w.beginStruct(this.protocolClassId);
+    w.writeInt32(this.Idx);
+    w.writeUint16(this.SlotType);
+    w.writeBool(this.BoSit);
+    w.writeInt32(this.PlaceItemId);
+    w.writeInt32(this.SitPersonId);
+    w.endStruct(start);
+  }
+
+  deserialize(r: ProtocolReader): void {
+    this.Idx = 0;
+    this.SlotType = 0;
+    this.BoSit = false;
+    this.PlaceItemId = 0;
+    this.SitPersonId = 0;
+    const h = r.beginStruct(this.protocolClassId);
+    if (r.more(h)) {
+      this.Idx = r.readInt32();
+    }
+    if (r.more(h)) {
+      this.SlotType = r.readUint16();
+    }
+    if (r.more(h)) {
+      this.BoSit = r.readBool();
+    }
+    if (r.more(h)) {
+      this.PlaceItemId = r.readInt32();
+    }
+    if (r.more(h)) {
+      this.SitPersonId = r.readInt32();
+    }
+    r.endStruct(h);
+  }
+}
+
+export class TMapInfo implements IProtocolMessage {
+  Idx: number = 0;
+  Name: string = "";
+  RefreshPoint: number = 0;
+  SlotList: Array<TSlotData> = [];
+  MaxCount: number = 0;
+  MaxLineUpCount: number = 0;
+  MaxClerk: number = 0;
+  MaxCook: number = 0;
+  CookExp: number = 0;
+  OrderExp: number = 0;
+  DeliveryExp: number = 0;
+  PointRefreshTime: number = 0;
+
+  get protocolClassId(): number {
+    return ClassID_MapInfo;
+  }
+
+  serialize(w: ProtocolWriter): void {
+    const start = w.beginStruct(this.protocolClassId);
+    w.writeInt32(this.Idx);
+    w.writeString(this.Name);
+    w.writeUint16(this.RefreshPoint);
+    {
+      const a0 = this.SlotList ?? [];
+      w.writeArrayLen(a0.length);
+      for (const e0 of a0) {
+        (e0 ?? new TSlotData()).serialize(w);
+      }
+    }
+    w.writeInt32(this.MaxCount);
+    w.writeInt32(this.MaxLineUpCount);
+    w.writeInt32(this.MaxClerk);
+    w.writeInt32(this.MaxCook);
+    w.writeInt32(this.CookExp);
+    w.writeInt32(this.OrderExp);
+    w.writeInt32(this.DeliveryExp);
+    w.writeInt32(this.PointRefreshTime);
+    w.endStruct(start);
+  }
+
+  deserialize(r: ProtocolReader): void {
+    this.Idx = 0;
+    this.Name = "";
+    this.RefreshPoint = 0;
+    this.SlotList = [];
+    this.MaxCount = 0;
+    this.MaxLineUpCount = 0;
+    this.MaxClerk = 0;
+    this.MaxCook = 0;
+    this.CookExp = 0;
+    this.OrderExp = 0;
+    this.DeliveryExp = 0;
+    this.PointRefreshTime = 0;
+    const h = r.beginStruct(this.protocolClassId);
+    if (r.more(h)) {
+      this.Idx = r.readInt32();
+    }
+    if (r.more(h)) {
+      this.Name = r.readString();
+    }
+    if (r.more(h)) {
+      this.RefreshPoint = r.readUint16();
+    }
+    if (r.more(h)) {
+      const n0 = r.readArrayLen();
+      for (let i0 = 0; i0 < n0; i0++) {
+        this.SlotList.push(r.readStruct(new TSlotData()));
+      }
+    }
+    if (r.more(h)) {
+      this.MaxCount = r.readInt32();
+    }
+    if (r.more(h)) {
+      this.MaxLineUpCount = r.readInt32();
+    }
+    if (r.more(h)) {
+      this.MaxClerk = r.readInt32();
+    }
+    if (r.more(h)) {
+      this.MaxCook = r.readInt32();
+    }
+    if (r.more(h)) {
+      this.CookExp = r.readInt32();
+    }
+    if (r.more(h)) {
+      this.OrderExp = r.readInt32();
+    }
+    if (r.more(h)) {
+      this.DeliveryExp = r.readInt32();
+    }
+    if (r.more(h)) {
+      this.PointRefreshTime = r.readInt32();
+    }
+    r.endStruct(h);
+  }
+}